@@ -0,0 +1,180 @@
+// Package bench holds reusable benchmark harnesses for the rings of this
+// module: lookup throughput, membership change latency, memory per member
+// and the share of keys relocated by a membership change. Each harness takes
+// a Factory, so the same measurements can be run against every
+// implementation in Implementations, or against a custom configuration, to
+// pick an algorithm for a given number of members and weights.
+package bench
+
+import (
+	"runtime"
+	"sort"
+	"strconv"
+	"testing"
+
+	consistent "github.com/kingreatwill/weighted-consistent-hashing"
+)
+
+// Ring is the lookup side shared by all rings of the module.
+type Ring interface {
+	Get(key string) (string, error)
+}
+
+// Factory builds a ring of members, keyed by name with their weight.
+type Factory func(members map[string]float64) Ring
+
+// Implementation is a named Factory.
+type Implementation struct {
+	Name string
+	New  Factory
+}
+
+// Implementations are the rings of the module with their default settings.
+var Implementations = []Implementation{
+	{"consistent", func(m map[string]float64) Ring {
+		c := consistent.New(20)
+		c.Set(m)
+		return c
+	}},
+	{"ketama", func(m map[string]float64) Ring {
+		c := consistent.New(40, consistent.WithKetamaMode())
+		c.Set(m)
+		return c
+	}},
+	{"frozen", func(m map[string]float64) Ring {
+		b := consistent.NewBuilder(20)
+		for elt, wgt := range m {
+			b.Add(elt, wgt)
+		}
+		return b.Build()
+	}},
+	{"multiprobe", func(m map[string]float64) Ring {
+		return consistent.NewMultiProbeRing(memberList(m), 0)
+	}},
+	{"weighted", func(m map[string]float64) Ring {
+		return consistent.NewWeightedConsistent("bench", 0, memberList(m))
+	}},
+}
+
+// memberList returns m as a slice in name order.
+func memberList(m map[string]float64) []consistent.Member {
+	members := make([]consistent.Member, 0, len(m))
+	for elt, wgt := range m {
+		members = append(members, consistent.Member{Name: elt, Weight: wgt})
+	}
+	sort.Slice(members, func(i, j int) bool { return members[i].Name < members[j].Name })
+	return members
+}
+
+// Members returns n members named "node-0" to "node-<n-1>" with weights
+// cycling through 1, 2 and 3.
+func Members(n int) map[string]float64 {
+	m := make(map[string]float64, n)
+	for i := 0; i < n; i++ {
+		m["node-"+strconv.Itoa(i)] = float64(1 + i%3)
+	}
+	return m
+}
+
+// Keys returns n distinct keys.
+func Keys(n int) []string {
+	keys := make([]string, n)
+	for i := range keys {
+		keys[i] = "key-" + strconv.Itoa(i)
+	}
+	return keys
+}
+
+// Lookup measures the throughput of Get on a ring of members built by f.
+func Lookup(b *testing.B, f Factory, members int) {
+	r := f(Members(members))
+	keys := Keys(1 << 12)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r.Get(keys[i&(len(keys)-1)])
+	}
+}
+
+// Change measures the latency of adding a member to a ring of members and
+// removing it again. Rings with Add and Remove methods are changed in place;
+// other rings are rebuilt by f, as their users would have to.
+func Change(b *testing.B, f Factory, members int) {
+	m := Members(members)
+	r := f(m)
+	b.ReportAllocs()
+	b.ResetTimer()
+	switch c := r.(type) {
+	case *consistent.Consistent:
+		for i := 0; i < b.N; i++ {
+			c.Add("extra", 1)
+			c.Remove("extra")
+		}
+	case *consistent.WeightedConsistent:
+		for i := 0; i < b.N; i++ {
+			c.Add("extra", 1)
+			c.Remove("extra")
+		}
+	default:
+		for i := 0; i < b.N; i++ {
+			m["extra"] = 1
+			f(m)
+			delete(m, "extra")
+			f(m)
+		}
+	}
+}
+
+// MemoryPerMember returns the heap bytes retained per member by a ring of
+// members built by f.
+func MemoryPerMember(f Factory, members int) float64 {
+	m := Members(members)
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	r := f(m)
+	runtime.GC()
+	runtime.ReadMemStats(&after)
+	runtime.KeepAlive(r)
+	return float64(int64(after.HeapAlloc)-int64(before.HeapAlloc)) / float64(members)
+}
+
+// Relocation returns the fraction of keys mapped to a different member after
+// a member of weight 1 is added to a ring of members built by f, along with
+// the fraction an ideal ring would move: the new member's weight share.
+func Relocation(f Factory, members, keys int) (moved, ideal float64) {
+	m := Members(members)
+	before := f(m)
+	var total float64
+	for _, wgt := range m {
+		total += wgt
+	}
+	m["extra"] = 1
+	after := f(m)
+	n := 0
+	for _, key := range Keys(keys) {
+		x, _ := before.Get(key)
+		y, _ := after.Get(key)
+		if x != y {
+			n++
+		}
+	}
+	return float64(n) / float64(keys), 1 / (total + 1)
+}
+
+// Run runs Lookup and Change for every implementation as sub-benchmarks, and
+// reports memory per member and relocation as metrics of the Lookup ones.
+func Run(b *testing.B, members int) {
+	for _, impl := range Implementations {
+		b.Run(impl.Name+"/lookup", func(b *testing.B) {
+			Lookup(b, impl.New, members)
+			b.StopTimer()
+			moved, _ := Relocation(impl.New, members, 10000)
+			b.ReportMetric(MemoryPerMember(impl.New, members), "B/member")
+			b.ReportMetric(moved*100, "%relocated")
+		})
+		b.Run(impl.Name+"/change", func(b *testing.B) {
+			Change(b, impl.New, members)
+		})
+	}
+}
@@ -0,0 +1,21 @@
+package bench
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestRelocation(t *testing.T) {
+	for _, impl := range Implementations {
+		moved, ideal := Relocation(impl.New, 30, 20000)
+		if moved <= 0 || moved > ideal*2 {
+			t.Errorf("%s relocates %.3f of keys, ideal %.3f", impl.Name, moved, ideal)
+		}
+	}
+}
+
+func BenchmarkRings(b *testing.B) {
+	for _, n := range []int{10, 100, 1000} {
+		b.Run(strconv.Itoa(n), func(b *testing.B) { Run(b, n) })
+	}
+}
@@ -0,0 +1,204 @@
+// Command whashctl simulates and inspects weighted consistent hash rings.
+//
+// Usage:
+//
+//	whashctl dist     -f members.txt [-replicas 200] [-keys 100000]
+//	whashctl relocate -f members.txt [-add name=weight] [-remove name] [-set name=weight] [-keys 100000]
+//	whashctl dump     -f members.txt [-replicas 200]
+//
+// The members file holds one "name weight" pair per line; blank lines and
+// lines starting with # are ignored. A file starting with "{" is read as the
+// JSON produced by Consistent.MarshalJSON instead.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	consistent "github.com/kingreatwill/weighted-consistent-hashing"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	var err error
+	switch cmd, args := os.Args[1], os.Args[2:]; cmd {
+	case "dist":
+		err = dist(os.Stdout, args)
+	case "relocate":
+		err = relocate(os.Stdout, args)
+	case "dump":
+		err = dump(os.Stdout, args)
+	default:
+		usage()
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "whashctl:", err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: whashctl dist|relocate|dump -f members.txt [flags]")
+	os.Exit(2)
+}
+
+// listFlag collects repeated string flags.
+type listFlag []string
+
+func (l *listFlag) String() string     { return strings.Join(*l, ",") }
+func (l *listFlag) Set(v string) error { *l = append(*l, v); return nil }
+
+func dist(w io.Writer, args []string) error {
+	fs := flag.NewFlagSet("dist", flag.ExitOnError)
+	file := fs.String("f", "", "members file")
+	replicas := fs.Int("replicas", 200, "virtual nodes per unit of weight")
+	keys := fs.Int("keys", 100000, "number of sampled keys")
+	fs.Parse(args)
+	c, err := load(*file, *replicas)
+	if err != nil {
+		return err
+	}
+	report := c.Distribution(*keys)
+	ownership := c.Stats().Ownership
+	fmt.Fprintf(w, "%-30s %10s %10s\n", "MEMBER", "KEYS", "HASHSPACE")
+	for _, m := range sortedMembers(c) {
+		fmt.Fprintf(w, "%-30s %9.3f%% %9.3f%%\n", m, 100*report.Ratios[m], 100*ownership[m])
+	}
+	fmt.Fprintf(w, "peak-to-mean %.3f, stddev %.3f\n", report.PeakToMean, report.StdDev)
+	return nil
+}
+
+func relocate(w io.Writer, args []string) error {
+	fs := flag.NewFlagSet("relocate", flag.ExitOnError)
+	file := fs.String("f", "", "members file")
+	replicas := fs.Int("replicas", 200, "virtual nodes per unit of weight")
+	keys := fs.Int("keys", 100000, "number of sampled keys")
+	var adds, removes, sets listFlag
+	fs.Var(&adds, "add", "add a member, as name=weight (repeatable)")
+	fs.Var(&removes, "remove", "remove a member (repeatable)")
+	fs.Var(&sets, "set", "change a member weight, as name=weight (repeatable)")
+	fs.Parse(args)
+	old, err := load(*file, *replicas)
+	if err != nil {
+		return err
+	}
+	cur, err := load(*file, *replicas)
+	if err != nil {
+		return err
+	}
+	for _, a := range adds {
+		name, wgt, err := parsePair(a)
+		if err != nil {
+			return err
+		}
+		if err := cur.Add(name, wgt); err != nil {
+			return fmt.Errorf("add %s: %w", name, err)
+		}
+	}
+	for _, name := range removes {
+		if !cur.Remove(name) {
+			return fmt.Errorf("remove %s: %w", name, consistent.ErrMemberNotFound)
+		}
+	}
+	for _, s := range sets {
+		name, wgt, err := parsePair(s)
+		if err != nil {
+			return err
+		}
+		if err := cur.UpdateWeight(name, wgt); err != nil {
+			return fmt.Errorf("set %s: %w", name, err)
+		}
+	}
+	sample := make([][]byte, *keys)
+	for i := range sample {
+		sample[i] = []byte(strconv.Itoa(i))
+	}
+	report := consistent.Diff(old, cur, sample)
+	fmt.Fprintf(w, "relocated %d of %d keys (%.3f%%)\n", len(report.Moved), report.Total, 100*report.Fraction())
+	flows := make([]consistent.Flow, 0, len(report.Flows))
+	for f := range report.Flows {
+		flows = append(flows, f)
+	}
+	sort.Slice(flows, func(i, j int) bool { return report.Flows[flows[i]] > report.Flows[flows[j]] })
+	for _, f := range flows {
+		fmt.Fprintf(w, "  %-25s -> %-25s %9.3f%%\n", f.From, f.To, 100*float64(report.Flows[f])/float64(report.Total))
+	}
+	return nil
+}
+
+func dump(out io.Writer, args []string) error {
+	fs := flag.NewFlagSet("dump", flag.ExitOnError)
+	file := fs.String("f", "", "members file")
+	replicas := fs.Int("replicas", 200, "virtual nodes per unit of weight")
+	fs.Parse(args)
+	c, err := load(*file, *replicas)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(out)
+	defer w.Flush()
+	for _, n := range c.DumpRing() {
+		fmt.Fprintf(w, "%10d %s#%d\n", n.Hash, n.Member, n.Replica)
+	}
+	return nil
+}
+
+// load builds a ring from the members file at path.
+func load(path string, replicas int) (*consistent.Consistent, error) {
+	if path == "" {
+		return nil, fmt.Errorf("missing -f members file")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c := consistent.New(replicas)
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		return c, json.Unmarshal(trimmed, c)
+	}
+	members := make(map[string]float64)
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%s:%d: want \"name weight\"", path, i+1)
+		}
+		w, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", path, i+1, err)
+		}
+		members[fields[0]] = w
+	}
+	return c, c.Set(members)
+}
+
+func parsePair(s string) (string, float64, error) {
+	name, weight, ok := strings.Cut(s, "=")
+	if !ok {
+		return "", 0, fmt.Errorf("%q: want name=weight", s)
+	}
+	w, err := strconv.ParseFloat(weight, 64)
+	if err != nil {
+		return "", 0, fmt.Errorf("%q: %v", s, err)
+	}
+	return name, w, nil
+}
+
+func sortedMembers(c *consistent.Consistent) []string {
+	m := c.Members()
+	sort.Strings(m)
+	return m
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	consistent "github.com/kingreatwill/weighted-consistent-hashing"
+)
+
+const members = "testdata/members.txt"
+
+func sample() *consistent.Consistent {
+	c := consistent.New(10)
+	c.Set(map[string]float64{"cache-a": 1, "cache-b": 1, "cache-c": 2})
+	return c
+}
+
+func run(t *testing.T, cmd func(*bytes.Buffer, []string) error, args ...string) []string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := cmd(&buf, args); err != nil {
+		t.Fatal(err)
+	}
+	return strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+}
+
+func TestDist(t *testing.T) {
+	lines := run(t, func(b *bytes.Buffer, a []string) error { return dist(b, a) },
+		"-f", members, "-replicas", "10", "-keys", "1000")
+	if len(lines) != 5 || !strings.HasPrefix(lines[0], "MEMBER") || !strings.HasPrefix(lines[4], "peak-to-mean ") {
+		t.Fatalf("output:\n%s", strings.Join(lines, "\n"))
+	}
+	c := sample()
+	ownership := c.Stats().Ownership
+	report := c.Distribution(1000)
+	for i, m := range []string{"cache-a", "cache-b", "cache-c"} {
+		want := fmt.Sprintf("%-30s %9.3f%% %9.3f%%", m, 100*report.Ratios[m], 100*ownership[m])
+		if lines[i+1] != want {
+			t.Fatalf("line %d = %q, want %q", i+1, lines[i+1], want)
+		}
+	}
+}
+
+func TestRelocate(t *testing.T) {
+	lines := run(t, func(b *bytes.Buffer, a []string) error { return relocate(b, a) },
+		"-f", members, "-replicas", "10", "-keys", "1000", "-add", "cache-d=1", "-remove", "cache-a")
+	old, cur := sample(), sample()
+	cur.Add("cache-d", 1)
+	cur.Remove("cache-a")
+	keys := make([][]byte, 1000)
+	for i := range keys {
+		keys[i] = []byte(strconv.Itoa(i))
+	}
+	report := consistent.Diff(old, cur, keys)
+	want := fmt.Sprintf("relocated %d of 1000 keys (%.3f%%)", len(report.Moved), 100*report.Fraction())
+	if lines[0] != want || len(lines) != 1+len(report.Flows) {
+		t.Fatalf("output:\n%s\nwant first line %q and %d flows", strings.Join(lines, "\n"), want, len(report.Flows))
+	}
+	for _, l := range lines[1:] {
+		if f := strings.Fields(l); len(f) != 4 || f[1] != "->" || f[2] != "cache-d" && f[0] != "cache-a" {
+			t.Fatalf("flow %q neither leaves cache-a nor reaches cache-d", l)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := relocate(&buf, []string{"-f", members, "-remove", "missing"}); err == nil {
+		t.Fatal("removing a missing member succeeded")
+	}
+}
+
+func TestDump(t *testing.T) {
+	lines := run(t, func(b *bytes.Buffer, a []string) error { return dump(b, a) }, "-f", members, "-replicas", "10")
+	if len(lines) != 40 {
+		t.Fatalf("%d virtual nodes dumped, want 40", len(lines))
+	}
+	hashes := make([]int, len(lines))
+	for i, l := range lines {
+		f := strings.Fields(l)
+		if len(f) != 2 || !strings.HasPrefix(f[1], "cache-") || !strings.Contains(f[1], "#") {
+			t.Fatalf("line %q", l)
+		}
+		hashes[i], _ = strconv.Atoi(f[0])
+	}
+	if !sort.IntsAreSorted(hashes) {
+		t.Fatal("virtual nodes not in hash order")
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	if _, err := load("", 10); err == nil {
+		t.Fatal("load without a file succeeded")
+	}
+	if _, err := load("testdata/missing.txt", 10); err == nil {
+		t.Fatal("load of a missing file succeeded")
+	}
+}
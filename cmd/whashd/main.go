@@ -0,0 +1,223 @@
+// Command whashd serves a weighted consistent hash ring over HTTP, so
+// services in any language share the exact placement decisions of Go
+// services using the library.
+//
+// Usage:
+//
+//	whashd [-addr :8080] [-f members.txt] [-replicas 200] [-save state.json]
+//
+// The members file has the format read by whashctl. With -save, the ring is
+// written to the given file as JSON after every change, and read from it
+// at startup if it exists.
+//
+// Endpoints:
+//
+//	GET  /locate?key=K[&n=N]    {"key": K, "members": [...]}, the N owners of K (default 1)
+//	GET  /members               [{"name", "weight", "virtual_nodes"}, ...]
+//	POST /add?name=M&weight=W   adds M, or sets its weight if it exists
+//	POST /remove?name=M         removes M
+//	GET  /stats                 ring statistics
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	consistent "github.com/kingreatwill/weighted-consistent-hashing"
+)
+
+func main() {
+	addr := flag.String("addr", ":8080", "listen address")
+	file := flag.String("f", "", "initial members file")
+	replicas := flag.Int("replicas", 200, "virtual nodes per unit of weight")
+	save := flag.String("save", "", "file the ring is saved to after every change")
+	flag.Parse()
+
+	path := *file
+	if *save != "" {
+		if _, err := os.Stat(*save); err == nil {
+			path = *save
+		}
+	}
+	c := consistent.New(*replicas)
+	if path != "" {
+		if err := load(c, path); err != nil {
+			log.Fatalf("whashd: %v", err)
+		}
+	}
+	log.Printf("whashd: serving %d members on %s", len(c.Members()), *addr)
+	log.Fatal(http.ListenAndServe(*addr, newHandler(c, *save)))
+}
+
+func load(c *consistent.Consistent, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		return json.Unmarshal(trimmed, c)
+	}
+	members := make(map[string]float64)
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return fmt.Errorf("%s:%d: want \"name weight\"", path, i+1)
+		}
+		w, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil {
+			return fmt.Errorf("%s:%d: %v", path, i+1, err)
+		}
+		members[fields[0]] = w
+	}
+	return c.Set(members)
+}
+
+type server struct {
+	c    *consistent.Consistent
+	save string
+	// mu orders changes with their saves, so the saved file is never older
+	// than the ring.
+	mu sync.Mutex
+}
+
+func newHandler(c *consistent.Consistent, save string) http.Handler {
+	s := &server{c: c, save: save}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /locate", s.locate)
+	mux.HandleFunc("GET /members", s.members)
+	mux.HandleFunc("POST /add", s.add)
+	mux.HandleFunc("POST /remove", s.remove)
+	mux.HandleFunc("GET /stats", s.stats)
+	return mux
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func (s *server) locate(w http.ResponseWriter, r *http.Request) {
+	key := r.URL.Query().Get("key")
+	n := 1
+	if v := r.URL.Query().Get("n"); v != "" {
+		var err error
+		if n, err = strconv.Atoi(v); err != nil || n < 1 {
+			http.Error(w, "n must be a positive integer", http.StatusBadRequest)
+			return
+		}
+	}
+	members, err := s.c.GetN(key, n)
+	if len(members) == 0 {
+		if err == nil {
+			err = consistent.ErrEmptyCircle
+		}
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	writeJSON(w, struct {
+		Key     string   `json:"key"`
+		Members []string `json:"members"`
+	}{key, members})
+}
+
+type memberJSON struct {
+	Name         string  `json:"name"`
+	Weight       float64 `json:"weight"`
+	VirtualNodes int     `json:"virtual_nodes"`
+}
+
+func (s *server) members(w http.ResponseWriter, r *http.Request) {
+	infos := s.c.MemberInfos()
+	res := make([]memberJSON, len(infos))
+	for i, m := range infos {
+		res[i] = memberJSON{m.Name, m.Weight, m.VirtualNodes}
+	}
+	writeJSON(w, res)
+}
+
+func (s *server) add(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+	weight, err := strconv.ParseFloat(r.URL.Query().Get("weight"), 64)
+	if name == "" || err != nil {
+		http.Error(w, "want name and a numeric weight", http.StatusBadRequest)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err = s.c.Add(name, weight)
+	if errors.Is(err, consistent.ErrMemberExists) {
+		err = s.c.UpdateWeight(name, weight)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.changed(w)
+}
+
+func (s *server) remove(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.c.Remove(r.URL.Query().Get("name")) {
+		http.Error(w, consistent.ErrMemberNotFound.Error(), http.StatusNotFound)
+		return
+	}
+	s.changed(w)
+}
+
+// changed saves the ring if requested and acknowledges a change.
+func (s *server) changed(w http.ResponseWriter) {
+	if s.save != "" {
+		if err := saveRing(s.c, s.save); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// saveRing writes c to path as JSON, replacing the file atomically.
+func saveRing(c *consistent.Consistent, path string) error {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), ".whashd-*")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+func (s *server) stats(w http.ResponseWriter, r *http.Request) {
+	st := s.c.Stats()
+	writeJSON(w, struct {
+		Members      int                `json:"members"`
+		VirtualNodes int                `json:"virtual_nodes"`
+		Lookups      uint64             `json:"lookups"`
+		Ownership    map[string]float64 `json:"ownership"`
+	}{st.Members, st.VirtualNodes, st.Lookups, st.Ownership})
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	consistent "github.com/kingreatwill/weighted-consistent-hashing"
+)
+
+func TestHandler(t *testing.T) {
+	save := filepath.Join(t.TempDir(), "state.json")
+	c := consistent.New(50)
+	h := newHandler(c, save)
+	do := func(method, url string, want int) *httptest.ResponseRecorder {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(method, url, nil))
+		if rec.Code != want {
+			t.Fatalf("%s %s = %d %s, want %d", method, url, rec.Code, rec.Body, want)
+		}
+		return rec
+	}
+	do("GET", "/locate?key=a", http.StatusServiceUnavailable)
+	do("POST", "/add?name=A&weight=1", http.StatusNoContent)
+	do("POST", "/add?name=B&weight=2", http.StatusNoContent)
+	do("POST", "/add?name=B&weight=3", http.StatusNoContent)
+	do("POST", "/add?name=C&weight=x", http.StatusBadRequest)
+	do("GET", "/locate", http.StatusOK)
+
+	var loc struct {
+		Key     string
+		Members []string
+	}
+	json.Unmarshal(do("GET", "/locate?key=user-1&n=2", http.StatusOK).Body.Bytes(), &loc)
+	want, _ := c.GetN("user-1", 2)
+	if loc.Key != "user-1" || len(loc.Members) != 2 || loc.Members[0] != want[0] {
+		t.Fatalf("locate = %+v, want %v", loc, want)
+	}
+
+	var members []memberJSON
+	json.Unmarshal(do("GET", "/members", http.StatusOK).Body.Bytes(), &members)
+	if len(members) != 2 || members[1] != (memberJSON{"B", 3, 150}) {
+		t.Fatalf("members = %+v", members)
+	}
+	do("POST", "/remove?name=A", http.StatusNoContent)
+	do("POST", "/remove?name=A", http.StatusNotFound)
+	do("GET", "/stats", http.StatusOK)
+
+	restored := consistent.New(50)
+	if err := load(restored, save); err != nil {
+		t.Fatal(err)
+	}
+	if m := restored.Members(); len(m) != 1 || m[0] != "B" {
+		t.Fatalf("saved members %v", m)
+	}
+}
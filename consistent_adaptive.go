@@ -0,0 +1,141 @@
+package consistent
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// LoadReport is a load sample of one element sent to an AdaptiveController.
+type LoadReport struct {
+	CPU        float64
+	Latency    time.Duration
+	QueueDepth float64
+}
+
+// AdaptiveOptions configures an AdaptiveController. The zero value uses the
+// defaults.
+type AdaptiveOptions struct {
+	// Load turns a report into the scalar load the controller equalizes.
+	// Default: the CPU usage.
+	Load func(LoadReport) float64
+	// Alpha is the smoothing factor of the moving average of the load of
+	// each element, in (0, 1]. Default 0.3.
+	Alpha float64
+	// MinWeight and MaxWeight bound the weights set by the controller.
+	// Default 0.1 and no upper bound.
+	MinWeight, MaxWeight float64
+	// MaxStep is the largest relative weight change of an element in one
+	// cycle, which bounds the keys moved per cycle. Default 0.1.
+	MaxStep float64
+	// Tolerance is the relative deviation from the mean load below which an
+	// element is left alone. Default 0.05.
+	Tolerance float64
+}
+
+// AdaptiveController nudges the weights of the elements of a Consistent to
+// equalize the load they report: elements loaded above the mean lose weight
+// and elements below it gain weight.
+type AdaptiveController struct {
+	c    *Consistent
+	opts AdaptiveOptions
+
+	mu   sync.Mutex
+	load map[string]float64 // moving average of the reported load
+}
+
+// NewAdaptiveController returns an AdaptiveController adjusting the weights
+// of c.
+func NewAdaptiveController(c *Consistent, opts AdaptiveOptions) *AdaptiveController {
+	if opts.Load == nil {
+		opts.Load = func(r LoadReport) float64 { return r.CPU }
+	}
+	if opts.Alpha <= 0 || opts.Alpha > 1 {
+		opts.Alpha = 0.3
+	}
+	if opts.MinWeight <= 0 {
+		opts.MinWeight = 0.1
+	}
+	if opts.MaxWeight <= 0 {
+		opts.MaxWeight = math.Inf(1)
+	}
+	if opts.MaxStep <= 0 {
+		opts.MaxStep = 0.1
+	}
+	if opts.Tolerance <= 0 {
+		opts.Tolerance = 0.05
+	}
+	return &AdaptiveController{c: c, opts: opts, load: make(map[string]float64)}
+}
+
+// Report records a load sample of member.
+func (a *AdaptiveController) Report(member string, r LoadReport) {
+	l := a.opts.Load(r)
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if prev, ok := a.load[member]; ok {
+		l = a.opts.Alpha*l + (1-a.opts.Alpha)*prev
+	}
+	a.load[member] = l
+}
+
+// Adjust runs one cycle, updating the weights of the elements that reported
+// a load with a single ring rebuild. It returns the weights it changed.
+func (a *AdaptiveController) Adjust() map[string]float64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	c := a.c
+	c.lock()
+	defer c.Unlock()
+
+	var sum float64
+	n := 0
+	for elt, l := range a.load {
+		if _, ok := c.members[elt]; !ok {
+			delete(a.load, elt)
+			continue
+		}
+		sum += l
+		n++
+	}
+	changed := make(map[string]float64)
+	if n == 0 || sum <= 0 {
+		return changed
+	}
+	mean := sum / float64(n)
+	for elt, l := range a.load {
+		if math.Abs(l-mean) <= a.opts.Tolerance*mean {
+			continue
+		}
+		wgt := c.members[elt]
+		ratio := a.opts.MaxStep + 1
+		if l > 0 {
+			ratio = min(max(mean/l, 1-a.opts.MaxStep), 1+a.opts.MaxStep)
+		}
+		next := min(max(wgt*ratio, a.opts.MinWeight), a.opts.MaxWeight)
+		if next != wgt {
+			c.cancelRamp(elt)
+			c.updateWeight(elt, next)
+			changed[elt] = next
+		}
+	}
+	if len(changed) > 0 {
+		c.updateSortedHashes()
+	}
+	return changed
+}
+
+// Run calls Adjust every interval until ctx is done.
+func (a *AdaptiveController) Run(ctx context.Context, interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			a.Adjust()
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package consistent
+
+import "testing"
+
+func TestAdaptiveController(t *testing.T) {
+	c := New(20)
+	c.Set(map[string]float64{"A": 1, "B": 1, "C": 1})
+	a := NewAdaptiveController(c, AdaptiveOptions{MaxStep: 0.2})
+	a.Report("A", LoadReport{CPU: 0.9})
+	a.Report("B", LoadReport{CPU: 0.3})
+	a.Report("C", LoadReport{CPU: 0.6})
+	changed := a.Adjust()
+	if len(changed) != 2 || changed["A"] != 0.8 || changed["B"] != 1.2 {
+		t.Fatalf("Adjust = %v, want A down and B up by MaxStep", changed)
+	}
+	weights := map[string]float64{}
+	for _, m := range c.MemberInfos() {
+		weights[m.Name] = m.Weight
+	}
+	if weights["A"] != 0.8 || weights["B"] != 1.2 || weights["C"] != 1 {
+		t.Fatalf("weights = %v", weights)
+	}
+}
@@ -0,0 +1,226 @@
+package consistent
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math"
+	"sort"
+)
+
+// binaryVersion is the first byte of the binary encoding written by WriteTo.
+const binaryVersion = 1
+
+const (
+	binaryFlagFnv = 1 << iota
+	binaryFlagKetama
+	binaryFlagSeed
+	binaryFlagBudget
+	binaryFlagReplicas
+)
+
+// ErrUnsupportedVersion is the error returned when decoding a binary ring
+// state written by an incompatible version.
+var ErrUnsupportedVersion = errors.New("unsupported encoding version")
+
+// WriteTo writes a compact binary encoding of the members, their weights and
+// the hashing settings to w. Members are written in name order, so equal
+// rings produce identical bytes.
+//
+// Layout: version byte, flags byte, uvarint replicas, uvarint seed if the
+// seed flag is set, uvarint virtual node budget if the budget flag is set,
+// uvarint member count, then per member a uvarint name length, the name and
+// the weight as a little-endian float64. If the replicas flag is set, a
+// uvarint count of AddWithReplicas overrides follows, then per override in
+// name order a uvarint name length, the name and a uvarint replica count.
+func (c *Consistent) WriteTo(w io.Writer) (int64, error) {
+	c.RLock()
+	buf := c.appendBinary(nil)
+	c.RUnlock()
+	n, err := w.Write(buf)
+	return int64(n), err
+}
+
+// appendBinary appends the encoding written by WriteTo to buf.
+//
+// need c.RLock() before calling
+func (c *Consistent) appendBinary(buf []byte) []byte {
+	cfg := c.conf()
+	start := len(buf)
+	buf = append(buf, binaryVersion, 0)
+	if cfg.fnv {
+		buf[start+1] |= binaryFlagFnv
+	}
+	if cfg.ketama {
+		buf[start+1] |= binaryFlagKetama
+	}
+	buf = binary.AppendUvarint(buf, uint64(cfg.replicas))
+	if cfg.seed != 0 {
+		buf[start+1] |= binaryFlagSeed
+		buf = binary.AppendUvarint(buf, cfg.seed)
+	}
+	if cfg.budget > 0 {
+		buf[start+1] |= binaryFlagBudget
+		buf = binary.AppendUvarint(buf, uint64(cfg.budget))
+	}
+	buf = binary.AppendUvarint(buf, uint64(len(c.members)))
+	names := make([]string, 0, len(c.members))
+	for elt := range c.members {
+		names = append(names, elt)
+	}
+	sort.Strings(names)
+	for _, elt := range names {
+		buf = binary.AppendUvarint(buf, uint64(len(elt)))
+		buf = append(buf, elt...)
+		buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(c.members[elt]))
+	}
+	if len(c.replicas) > 0 {
+		buf[start+1] |= binaryFlagReplicas
+		buf = binary.AppendUvarint(buf, uint64(len(c.replicas)))
+		names = names[:0]
+		for elt := range c.replicas {
+			names = append(names, elt)
+		}
+		sort.Strings(names)
+		for _, elt := range names {
+			buf = binary.AppendUvarint(buf, uint64(len(elt)))
+			buf = append(buf, elt...)
+			buf = binary.AppendUvarint(buf, uint64(c.replicas[elt]))
+		}
+	}
+	return buf
+}
+
+// ReadFrom reads a ring state written by WriteTo from r, replaces the
+// settings and members of c with it and rebuilds the ring. It reads exactly
+// one encoded state and nothing past it.
+func (c *Consistent) ReadFrom(r io.Reader) (int64, error) {
+	br := &byteCounter{r: r}
+	var head [2]byte
+	if _, err := io.ReadFull(br, head[:]); err != nil {
+		return br.n, err
+	}
+	if head[0] != binaryVersion {
+		return br.n, fmt.Errorf("%w: %d", ErrUnsupportedVersion, head[0])
+	}
+	v := consistentJSON{
+		UseFnv:     head[1]&binaryFlagFnv != 0,
+		KetamaMode: head[1]&binaryFlagKetama != 0,
+	}
+	replicas, err := binary.ReadUvarint(br)
+	if err != nil {
+		return br.n, err
+	}
+	if replicas == 0 || replicas > math.MaxInt32 {
+		return br.n, fmt.Errorf("invalid number of replicas %d", replicas)
+	}
+	if head[1]&binaryFlagSeed != 0 {
+		if v.Seed, err = binary.ReadUvarint(br); err != nil {
+			return br.n, err
+		}
+	}
+	if head[1]&binaryFlagBudget != 0 {
+		budget, err := binary.ReadUvarint(br)
+		if err != nil {
+			return br.n, err
+		}
+		if budget > math.MaxInt32 {
+			return br.n, fmt.Errorf("virtual node budget %d is too large", budget)
+		}
+		v.Budget = int(budget)
+	}
+	count, err := binary.ReadUvarint(br)
+	if err != nil {
+		return br.n, err
+	}
+	v.NumberOfReplicas = int(replicas)
+	v.Members = make(map[string]float64)
+	for ; count > 0; count-- {
+		size, err := binary.ReadUvarint(br)
+		if err != nil {
+			return br.n, err
+		}
+		if size > math.MaxUint16 {
+			return br.n, fmt.Errorf("member name of %d bytes is too long", size)
+		}
+		name := make([]byte, size+8)
+		if _, err := io.ReadFull(br, name); err != nil {
+			return br.n, err
+		}
+		v.Members[string(name[:size])] = math.Float64frombits(binary.LittleEndian.Uint64(name[size:]))
+	}
+	if head[1]&binaryFlagReplicas != 0 {
+		if count, err = binary.ReadUvarint(br); err != nil {
+			return br.n, err
+		}
+		v.Replicas = make(map[string]int)
+		for ; count > 0; count-- {
+			size, err := binary.ReadUvarint(br)
+			if err != nil {
+				return br.n, err
+			}
+			if size > math.MaxUint16 {
+				return br.n, fmt.Errorf("member name of %d bytes is too long", size)
+			}
+			name := make([]byte, size)
+			if _, err := io.ReadFull(br, name); err != nil {
+				return br.n, err
+			}
+			n, err := binary.ReadUvarint(br)
+			if err != nil {
+				return br.n, err
+			}
+			if n > math.MaxInt32 {
+				return br.n, fmt.Errorf("replica count %d is too large", n)
+			}
+			v.Replicas[string(name)] = int(n)
+		}
+	}
+	return br.n, c.restore(v)
+}
+
+// byteCounter counts the bytes read from r and reads single bytes without
+// buffering ahead, so ReadFrom never consumes input past the encoded state.
+type byteCounter struct {
+	r io.Reader
+	n int64
+}
+
+func (b *byteCounter) Read(p []byte) (int, error) {
+	n, err := b.r.Read(p)
+	b.n += int64(n)
+	return n, err
+}
+
+func (b *byteCounter) ReadByte() (byte, error) {
+	var p [1]byte
+	if _, err := io.ReadFull(b, p[:]); err != nil {
+		return 0, err
+	}
+	return p[0], nil
+}
+
+// Checksum returns a stable digest of the members, their weights and the
+// hashing settings. Peers holding identical rings get identical checksums.
+// Whether a custom Hasher or Uint64Hasher is set is part of the digest, but
+// functions cannot be compared, so peers using different custom hashers get
+// the same checksum. Everything is read under one lock, so a concurrent
+// change is either fully included or not at all.
+func (c *Consistent) Checksum() uint64 {
+	c.RLock()
+	buf := c.appendBinary(nil)
+	cfg := c.conf()
+	c.RUnlock()
+	var custom byte
+	if cfg.hasher != nil {
+		custom |= 1
+	}
+	if cfg.uint64Hasher != nil {
+		custom |= 2
+	}
+	h := fnv.New64a()
+	h.Write(append(buf, custom))
+	return h.Sum64()
+}
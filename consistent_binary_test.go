@@ -0,0 +1,64 @@
+package consistent
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"math"
+	"testing"
+)
+
+func TestBinaryRoundTrip(t *testing.T) {
+	c := New(30)
+	c.Set(map[string]float64{"Host1": 1, "Host2": 2.5})
+	var buf bytes.Buffer
+	n, err := c.WriteTo(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf.WriteString("trailing")
+	d := New(0)
+	m, err := d.ReadFrom(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m != n || buf.String() != "trailing" {
+		t.Fatalf("ReadFrom read %d bytes, WriteTo wrote %d", m, n)
+	}
+	if d.NumberOfReplicas != 30 || len(d.Members()) != 2 {
+		t.Fatalf("state not restored")
+	}
+	if _, err := d.ReadFrom(bytes.NewReader([]byte{9, 0})); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Fatalf("err = %v, want ErrUnsupportedVersion", err)
+	}
+	for _, replicas := range []uint64{0, math.MaxInt32 + 1, math.MaxUint64} {
+		crafted := binary.AppendUvarint([]byte{binaryVersion, 0}, replicas)
+		crafted = binary.AppendUvarint(crafted, 0)
+		if _, err := d.ReadFrom(bytes.NewReader(crafted)); err == nil {
+			t.Fatalf("ReadFrom accepted %d replicas", replicas)
+		}
+	}
+	if d.NumberOfReplicas != 30 || len(d.Members()) != 2 {
+		t.Fatal("rejected state was restored")
+	}
+}
+
+func TestChecksum(t *testing.T) {
+	a, b := New(20), New(20)
+	a.Set(map[string]float64{"Host1": 1, "Host2": 2})
+	b.Add("Host2", 2)
+	b.Add("Host1", 1)
+	if a.Checksum() != b.Checksum() {
+		t.Fatal("identical rings have different checksums")
+	}
+	h := New(20, WithHasher(func(key string) uint32 { return crc32.ChecksumIEEE([]byte(key)) }))
+	h.Set(map[string]float64{"Host1": 1, "Host2": 2})
+	if a.Checksum() == h.Checksum() {
+		t.Fatal("a custom Hasher does not change the checksum")
+	}
+	b.UpdateWeight("Host1", 3)
+	if a.Checksum() == b.Checksum() {
+		t.Fatal("different rings have the same checksum")
+	}
+}
@@ -0,0 +1,73 @@
+package consistent
+
+import (
+	"math"
+	"sort"
+)
+
+// budgeted reports whether virtual nodes are allocated from
+// VirtualNodeBudget rather than per unit of weight.
+func (c *Consistent) budgeted() bool {
+	cfg := c.conf()
+	return cfg.budget > 0 && !cfg.ketama
+}
+
+// allocate splits VirtualNodeBudget across the elements in proportion to
+// their weight with largest-remainder rounding, so the counts add up to the
+// budget exactly and each is within one of its exact share.
+//
+// need c.Lock() before calling
+func (c *Consistent) allocate() map[string]int {
+	var total float64
+	for _, wgt := range c.members {
+		total += wgt
+	}
+	alloc := make(map[string]int, len(c.members))
+	if total <= 0 {
+		return alloc
+	}
+	type share struct {
+		elt  string
+		frac float64
+	}
+	shares := make([]share, 0, len(c.members))
+	left := c.cfg.budget
+	for elt, wgt := range c.members {
+		q := float64(c.cfg.budget) * wgt / total
+		n := int(math.Floor(q))
+		alloc[elt] = n
+		left -= n
+		shares = append(shares, share{elt, q - float64(n)})
+	}
+	sort.Slice(shares, func(i, j int) bool {
+		if shares[i].frac != shares[j].frac {
+			return shares[i].frac > shares[j].frac
+		}
+		return shares[i].elt < shares[j].elt
+	})
+	for i := 0; i < left && i < len(shares); i++ {
+		alloc[shares[i].elt]++
+	}
+	return alloc
+}
+
+// reallocate moves the virtual nodes of every element to its new allocation,
+// adding or removing nodes at the end of its sequence so that only the keys
+// of changed nodes move.
+//
+// need c.Lock() before calling
+func (c *Consistent) reallocate() {
+	alloc := c.allocate()
+	for elt, old := range c.alloc {
+		for i := alloc[elt]; i < old; i++ {
+			c.deleteNode(c.hashKey(c.eltKey(elt, i)), elt)
+		}
+	}
+	for elt, n := range alloc {
+		for i := c.alloc[elt]; i < n; i++ {
+			c.setNode(c.hashKey(c.eltKey(elt, i)), elt)
+		}
+	}
+	c.alloc = alloc
+	c.realloc = false
+}
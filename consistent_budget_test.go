@@ -0,0 +1,65 @@
+package consistent
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+func TestVirtualNodeBudget(t *testing.T) {
+	c := New(20)
+	c.VirtualNodeBudget = 10000
+	c.Add("big", 1e6)
+	c.Add("mid", 3)
+	c.Add("small", 1)
+	count := func() map[string]int {
+		n := make(map[string]int)
+		for _, o := range c.snapshot().owners {
+			n[o]++
+		}
+		return n
+	}
+	total := func(n map[string]int) (t int) {
+		for _, v := range n {
+			t += v
+		}
+		return t
+	}
+	if n := count(); total(n) > 10000 || total(n) < 9990 || n["big"] < 9980 {
+		t.Fatalf("budget split as %v", n)
+	}
+
+	c.Set(map[string]float64{"A": 1, "B": 2, "C": 7})
+	want := map[string]int{"A": 1000, "B": 2000, "C": 7000}
+	for m, n := range count() {
+		if n < want[m]-5 || n > want[m] {
+			t.Fatalf("%s has %d virtual nodes, want %d", m, n, want[m])
+		}
+	}
+	c.Add("D", 10)
+	c.Remove("A")
+	c.UpdateWeight("B", 3)
+	want = map[string]int{"B": 1500, "C": 3500, "D": 5000}
+	n := count()
+	if len(n) != 3 {
+		t.Fatalf("owners %v", n)
+	}
+	for m, v := range n {
+		if v < want[m]-5 || v > want[m] {
+			t.Fatalf("%s has %d virtual nodes, want %d", m, v, want[m])
+		}
+	}
+
+	d := New(20)
+	data, _ := json.Marshal(c)
+	if err := json.Unmarshal(data, d); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 1000; i++ {
+		x, _ := c.Get(fmt.Sprint(i))
+		y, _ := d.Get(fmt.Sprint(i))
+		if x != y {
+			t.Fatalf("key %d maps to %s after decoding, want %s", i, y, x)
+		}
+	}
+}
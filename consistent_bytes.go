@@ -0,0 +1,53 @@
+package consistent
+
+import "unsafe"
+
+// bytesKey returns key as a string without copying it. The string must not
+// be retained beyond the lookup, since the caller may reuse key.
+func bytesKey(key []byte) string {
+	return unsafe.String(unsafe.SliceData(key), len(key))
+}
+
+// stringBytes returns the bytes of s without copying them. They must not be
+// modified.
+func stringBytes(s string) []byte {
+	return unsafe.Slice(unsafe.StringData(s), len(s))
+}
+
+// GetBytes is like Get for a key held in a byte slice, without converting it
+// to a string. A custom Hasher must not retain the key it is passed.
+func (c *Consistent) GetBytes(key []byte) (string, error) {
+	r := c.snapshot()
+	start := r.lookupStart()
+	c.countLookup()
+	m, err := r.getOne(bytesKey(key))
+	if r.observers != nil {
+		r.observeLookup("GetBytes", string(key), r.primary(string(key)), start, err, m)
+	}
+	return m, err
+}
+
+// GetTwoBytes is like GetTwo for a key held in a byte slice.
+func (c *Consistent) GetTwoBytes(key []byte) (string, string, error) {
+	r := c.snapshot()
+	start := r.lookupStart()
+	c.countLookup()
+	a, b, err := r.getTwo(bytesKey(key))
+	if r.observers != nil {
+		r.observeLookup("GetTwoBytes", string(key), r.primary(string(key)), start, err, a, b)
+	}
+	return a, b, err
+}
+
+// GetNBytes is like GetN for a key held in a byte slice.
+func (c *Consistent) GetNBytes(key []byte, n int) ([]string, error) {
+	r := c.snapshot()
+	start := r.lookupStart()
+	c.countLookup()
+	res, err := r.getNExcluding(bytesKey(key), n, nil)
+	err = atLeast(res, n, err)
+	if r.observers != nil {
+		r.observeLookup("GetNBytes", string(key), r.primary(string(key)), start, err, res...)
+	}
+	return res, err
+}
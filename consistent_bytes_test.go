@@ -0,0 +1,26 @@
+package consistent
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestGetBytes(t *testing.T) {
+	c := New(20)
+	c.Set(map[string]float64{"A": 1, "B": 2, "C": 1})
+	for i := 0; i < 50; i++ {
+		key := fmt.Sprint(i)
+		want, _ := c.Get(key)
+		if got, _ := c.GetBytes([]byte(key)); got != want {
+			t.Fatalf("GetBytes(%q) = %q, want %q", key, got, want)
+		}
+		wantN, _ := c.GetN(key, 2)
+		if gotN, _ := c.GetNBytes([]byte(key), 2); fmt.Sprint(gotN) != fmt.Sprint(wantN) {
+			t.Fatalf("GetNBytes(%q) = %v, want %v", key, gotN, wantN)
+		}
+	}
+	key := []byte("key")
+	if n := testing.AllocsPerRun(100, func() { c.GetBytes(key) }); n != 0 {
+		t.Fatalf("GetBytes allocates %v times", n)
+	}
+}
@@ -0,0 +1,146 @@
+package consistent
+
+import (
+	"hash/maphash"
+	"sync"
+	"sync/atomic"
+)
+
+// keyCacheShards is the number of independently locked shards of a KeyCache.
+const keyCacheShards = 16
+
+// KeyCache is a sharded cache of recently looked up keys and their owners in
+// front of a Consistent, for skewed workloads where a small set of keys makes
+// up most lookups. Each shard evicts with the CLOCK approximation of LRU, so
+// a hit only takes a read lock and sets a flag, and hits on different keys
+// do not serialize. Every shard remembers the ring it was filled from and is
+// emptied as soon as a different ring is published, so any change that can
+// move keys (members, weights, health, drains, pins) invalidates it without
+// notifying the cache. Errors are not cached.
+type KeyCache struct {
+	c      *Consistent
+	seed   maphash.Seed
+	shards [keyCacheShards]keyCacheShard
+}
+
+type keyCacheShard struct {
+	mu   sync.RWMutex
+	r    *ring
+	size int
+	// clock holds the entries in insertion order; hand is the next
+	// candidate for eviction.
+	clock []*keyCacheEntry
+	hand  int
+	items map[string]*keyCacheEntry
+}
+
+type keyCacheEntry struct {
+	key, owner string
+	// used is set by every hit and cleared as the clock hand passes, which
+	// spares the entry once.
+	used atomic.Bool
+}
+
+// NewKeyCache returns a KeyCache for c holding up to size keys, 1024 if
+// size is 0 or less.
+func NewKeyCache(c *Consistent, size int) *KeyCache {
+	if size <= 0 {
+		size = 1024
+	}
+	k := &KeyCache{c: c, seed: maphash.MakeSeed()}
+	for i := range k.shards {
+		k.shards[i].size = max(size/keyCacheShards, 1)
+		k.shards[i].items = make(map[string]*keyCacheEntry)
+	}
+	return k
+}
+
+// Get returns the owner of key like c.Get, from the cache when possible.
+func (k *KeyCache) Get(key string) (string, error) {
+	r := k.c.snapshot()
+	start := r.lookupStart()
+	k.c.countLookup()
+	s := &k.shards[maphash.String(k.seed, key)%keyCacheShards]
+	s.mu.RLock()
+	var e *keyCacheEntry
+	if s.r == r {
+		e = s.items[key]
+	}
+	s.mu.RUnlock()
+	if e != nil {
+		e.used.Store(true)
+		if r.observers != nil {
+			r.observeLookup("KeyCache.Get", key, r.primary(key), start, nil, e.owner)
+		}
+		return e.owner, nil
+	}
+
+	m, err := r.getOne(key)
+	if err == nil {
+		s.mu.Lock()
+		s.add(r, key, m)
+		s.mu.Unlock()
+	}
+	if r.observers != nil {
+		r.observeLookup("KeyCache.Get", key, r.primary(key), start, err, m)
+	}
+	return m, err
+}
+
+// add caches the owner of key on r, emptying the shard first if it was
+// filled from another ring.
+//
+// need s.mu.Lock() before calling
+func (s *keyCacheShard) add(r *ring, key, owner string) {
+	if s.r != r {
+		s.reset()
+		s.r = r
+	}
+	if _, ok := s.items[key]; ok {
+		return
+	}
+	e := &keyCacheEntry{key: key, owner: owner}
+	s.items[key] = e
+	if len(s.clock) < s.size {
+		s.clock = append(s.clock, e)
+		return
+	}
+	for s.clock[s.hand].used.Swap(false) {
+		s.hand = (s.hand + 1) % len(s.clock)
+	}
+	delete(s.items, s.clock[s.hand].key)
+	s.clock[s.hand] = e
+	s.hand = (s.hand + 1) % len(s.clock)
+}
+
+// need s.mu.Lock() before calling
+func (s *keyCacheShard) reset() {
+	clear(s.clock)
+	s.clock = s.clock[:0]
+	s.hand = 0
+	clear(s.items)
+}
+
+// Len returns the number of cached keys.
+func (k *KeyCache) Len() int {
+	n := 0
+	for i := range k.shards {
+		s := &k.shards[i]
+		s.mu.RLock()
+		if s.r == k.c.snapshot() {
+			n += len(s.clock)
+		}
+		s.mu.RUnlock()
+	}
+	return n
+}
+
+// Purge empties the cache.
+func (k *KeyCache) Purge() {
+	for i := range k.shards {
+		s := &k.shards[i]
+		s.mu.Lock()
+		s.reset()
+		s.mu.Unlock()
+	}
+}
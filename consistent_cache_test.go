@@ -0,0 +1,69 @@
+package consistent
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestKeyCache(t *testing.T) {
+	c := New(20)
+	c.Set(map[string]float64{"A": 1, "B": 1, "C": 1})
+	k := NewKeyCache(c, 64)
+	for i := 0; i < 200; i++ {
+		key := fmt.Sprint(i % 50)
+		got, err := k.Get(key)
+		want, _ := c.Get(key)
+		if err != nil || got != want {
+			t.Fatalf("Get(%q) = %q, %v, want %q", key, got, err, want)
+		}
+	}
+	if n := k.Len(); n == 0 || n > 64 {
+		t.Fatalf("cache holds %d keys", n)
+	}
+	c.Remove("A")
+	if n := k.Len(); n != 0 {
+		t.Fatalf("cache holds %d keys after a change", n)
+	}
+	for i := 0; i < 50; i++ {
+		if got, _ := k.Get(fmt.Sprint(i)); got == "A" {
+			t.Fatalf("key %d still maps to the removed member", i)
+		}
+	}
+	c.SetHealthy("B", false)
+	for i := 0; i < 50; i++ {
+		if got, _ := k.Get(fmt.Sprint(i)); got != "C" {
+			t.Fatalf("key %d maps to %q with B down", i, got)
+		}
+	}
+	if _, err := NewKeyCache(New(20), 0).Get("x"); err != ErrEmptyCircle {
+		t.Fatalf("empty ring: %v", err)
+	}
+}
+
+// benchmarkHotKeys runs get in parallel over a few long keys on a large
+// ring, the skewed workload KeyCache is for.
+func benchmarkHotKeys(b *testing.B, get func(c *Consistent) func(string) (string, error)) {
+	c := New(160)
+	for i := 0; i < 100; i++ {
+		c.Add(fmt.Sprintf("10.0.%d.%d:11211", i/256, i%256), 1)
+	}
+	keys := make([]string, 64)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("session:%04d:user-profile-and-preferences-blob", i)
+	}
+	lookup := get(c)
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for i := 0; pb.Next(); i++ {
+			lookup(keys[i%len(keys)])
+		}
+	})
+}
+
+func BenchmarkHotKeysGet(b *testing.B) {
+	benchmarkHotKeys(b, func(c *Consistent) func(string) (string, error) { return c.Get })
+}
+
+func BenchmarkHotKeysKeyCache(b *testing.B) {
+	benchmarkHotKeys(b, func(c *Consistent) func(string) (string, error) { return NewKeyCache(c, 1024).Get })
+}
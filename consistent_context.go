@@ -0,0 +1,172 @@
+package consistent
+
+import (
+	"context"
+	"errors"
+)
+
+// changed returns a channel that is closed when the next ring is published.
+// Taking it before reading the snapshot guarantees that a change published
+// in between is not missed.
+func (c *Consistent) changed() <-chan struct{} {
+	c.waitMu.Lock()
+	defer c.waitMu.Unlock()
+	if c.waitCh == nil {
+		c.waitCh = make(chan struct{})
+	}
+	return c.waitCh
+}
+
+// wakeWaiters wakes the goroutines waiting for a change.
+func (c *Consistent) wakeWaiters() {
+	c.waitMu.Lock()
+	if c.waitCh != nil {
+		close(c.waitCh)
+		c.waitCh = nil
+	}
+	c.waitMu.Unlock()
+}
+
+// waitFor calls lookup until it returns an error other than ErrEmptyCircle
+// or ErrNoAvailableMember, waiting for the ring to change between calls. If
+// ctx is done first it returns the last error of lookup joined with
+// ctx.Err().
+func (c *Consistent) waitFor(ctx context.Context, lookup func() error) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		ch := c.changed()
+		err := lookup()
+		if err != ErrEmptyCircle && err != ErrNoAvailableMember {
+			return err
+		}
+		select {
+		case <-ch:
+		case <-ctx.Done():
+			return errors.Join(err, ctx.Err())
+		}
+	}
+}
+
+// GetContext is like Get but, while the ring is empty or all its elements
+// are unavailable, blocks until an element becomes available or ctx is done.
+// Observers see each attempt as a "Get" lookup carrying ctx.
+func (c *Consistent) GetContext(ctx context.Context, name string) (string, error) {
+	var m string
+	err := c.waitFor(ctx, func() (err error) {
+		r := c.snapshot()
+		start := r.lookupStart()
+		c.countLookup()
+		m, err = r.getOne(name)
+		if r.observers != nil {
+			l := r.lookup("Get", name, r.primary(name), start, err, m)
+			l.Context = ctx
+			r.notifyLookup(l)
+		}
+		return err
+	})
+	return m, err
+}
+
+// GetNContext is like GetN but, while the ring is empty or all its elements
+// are unavailable, blocks until an element becomes available or ctx is done.
+// Observers see each attempt as a "GetN" lookup carrying ctx.
+func (c *Consistent) GetNContext(ctx context.Context, name string, n int) ([]string, error) {
+	var res []string
+	err := c.waitFor(ctx, func() (err error) {
+		r := c.snapshot()
+		start := r.lookupStart()
+		c.countLookup()
+		res, err = r.getNExcluding(name, n, nil)
+		err = atLeast(res, n, err)
+		if r.observers != nil {
+			l := r.lookup("GetN", name, r.primary(name), start, err, res...)
+			l.Context = ctx
+			r.notifyLookup(l)
+		}
+		return err
+	})
+	return res, err
+}
+
+// GetCheckedContext returns the first available element at or after where
+// name hashes to in the circle for which check returns nil, checking one
+// element at a time, for example with a health probe. It waits like
+// GetContext for an available element, stops early when ctx is done, and
+// returns the errors of check joined if every element fails.
+func (c *Consistent) GetCheckedContext(ctx context.Context, name string, check func(ctx context.Context, member string) error) (string, error) {
+	var candidates []string
+	err := c.waitFor(ctx, func() (err error) {
+		r := c.snapshot()
+		candidates, err = r.getNExcluding(name, len(r.members), nil)
+		return atLeast(candidates, 1, err)
+	})
+	if len(candidates) == 0 {
+		return "", err
+	}
+	var errs []error
+	for _, m := range candidates {
+		if err := ctx.Err(); err != nil {
+			return "", errors.Join(append(errs, err)...)
+		}
+		err := check(ctx, m)
+		if err == nil {
+			return m, nil
+		}
+		errs = append(errs, err)
+	}
+	return "", errors.Join(errs...)
+}
+
+// GetLeastLoadedContext is like GetLeastLoadedFunc but asks load for the
+// load of each candidate with ctx, waits like GetContext for an available
+// element, and skips the candidates load fails for. It returns the errors
+// of load joined if it fails for every candidate.
+func (c *Consistent) GetLeastLoadedContext(ctx context.Context, name string, n int, load func(ctx context.Context, member string) (float64, error)) (string, error) {
+	var candidates []string
+	err := c.waitFor(ctx, func() (err error) {
+		candidates, err = c.snapshot().getNExcluding(name, max(n, 1), nil)
+		return atLeast(candidates, 1, err)
+	})
+	if len(candidates) == 0 {
+		return "", err
+	}
+	var errs []error
+	best, bestLoad := "", 0.0
+	for _, m := range candidates {
+		if err := ctx.Err(); err != nil {
+			return "", errors.Join(append(errs, err)...)
+		}
+		l, err := load(ctx, m)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if best == "" || l < bestLoad {
+			best, bestLoad = m, l
+		}
+	}
+	if best == "" {
+		return "", errors.Join(errs...)
+	}
+	return best, nil
+}
+
+// WaitForMembers blocks until the ring has at least minCount elements, for
+// example at startup while a discovery watcher fills it, or until ctx is
+// done, in which case it returns ctx.Err(). Unhealthy and drained elements
+// count as members.
+func (c *Consistent) WaitForMembers(ctx context.Context, minCount int) error {
+	for {
+		ch := c.changed()
+		if len(c.snapshot().members) >= minCount {
+			return nil
+		}
+		select {
+		case <-ch:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
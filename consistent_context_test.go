@@ -0,0 +1,115 @@
+package consistent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestGetContext(t *testing.T) {
+	c := New(20)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := c.GetContext(ctx, "k"); !errors.Is(err, ErrEmptyCircle) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("GetContext on an empty ring = %v", err)
+	}
+
+	done := make(chan string)
+	go func() {
+		m, _ := c.GetContext(context.Background(), "k")
+		done <- m
+	}()
+	time.Sleep(10 * time.Millisecond)
+	c.Add("A", 1)
+	if m := <-done; m != "A" {
+		t.Fatalf("GetContext = %q after A was added", m)
+	}
+
+	c.SetHealthy("A", false)
+	go func() {
+		res, _ := c.GetNContext(context.Background(), "k", 2)
+		done <- fmt.Sprint(res)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	c.SetHealthy("A", true)
+	if m := <-done; m != "[A]" {
+		t.Fatalf("GetNContext = %s after A recovered", m)
+	}
+
+	c.Add("B", 1)
+	c.Add("C", 1)
+	order, _ := c.GetAll("k")
+	probed := []string{}
+	m, err := c.GetCheckedContext(context.Background(), "k", func(_ context.Context, m string) error {
+		probed = append(probed, m)
+		if m == order[0] {
+			return errors.New("down")
+		}
+		return nil
+	})
+	if err != nil || m != order[1] || len(probed) != 2 {
+		t.Fatalf("GetCheckedContext = %q, %v after probing %v", m, err, probed)
+	}
+	fail := errors.New("fail")
+	if _, err := c.GetCheckedContext(context.Background(), "k", func(context.Context, string) error { return fail }); !errors.Is(err, fail) {
+		t.Fatalf("GetCheckedContext with every probe failing = %v", err)
+	}
+
+	loads := map[string]float64{order[0]: 3, order[1]: 1}
+	m, err = c.GetLeastLoadedContext(context.Background(), "k", 3, func(_ context.Context, m string) (float64, error) {
+		if l, ok := loads[m]; ok {
+			return l, nil
+		}
+		return 0, fail
+	})
+	if err != nil || m != order[1] {
+		t.Fatalf("GetLeastLoadedContext = %q, %v, want %q", m, err, order[1])
+	}
+}
+
+func TestWaitForMembers(t *testing.T) {
+	c := New(20)
+	if err := c.WaitForMembers(context.Background(), 0); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := c.WaitForMembers(ctx, 1); err != context.DeadlineExceeded {
+		t.Fatalf("WaitForMembers on an empty ring = %v", err)
+	}
+
+	done := make(chan error)
+	go func() { done <- c.WaitForMembers(context.Background(), 2) }()
+	c.Add("A", 1)
+	select {
+	case err := <-done:
+		t.Fatalf("WaitForMembers returned %v with one member", err)
+	case <-time.After(10 * time.Millisecond):
+	}
+	c.Add("B", 1)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLookupContext(t *testing.T) {
+	c := New(20)
+	c.Add("A", 1)
+	o := new(recordingObserver)
+	c.AddObserver(o)
+	type key struct{}
+	ctx := context.WithValue(context.Background(), key{}, 1)
+	c.Get("k")
+	c.GetContext(ctx, "k")
+	c.GetNContext(ctx, "k", 1)
+	if len(o.lookups) != 3 || o.lookups[0].Context != nil {
+		t.Fatalf("lookups = %+v", o.lookups)
+	}
+	for _, l := range o.lookups[1:] {
+		if l.Context != ctx || l.Members[0] != "A" {
+			t.Fatalf("%s lookup = %+v", l.Op, l)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package consistent
+
+import (
+	"errors"
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrNotStaged is the error returned by CutoverRing when no ring is staged.
+var ErrNotStaged = errors.New("no staged ring")
+
+// CutoverRing holds an active ring and optionally a staged one, for
+// blue/green topology changes: stage the new topology, route a canary
+// fraction of keys to it, then promote it or roll it back. Lookups take no
+// locks.
+type CutoverRing struct {
+	state atomic.Pointer[cutoverState]
+	// mu serializes Stage, SetCanary, Promote and Rollback.
+	mu sync.Mutex
+}
+
+type cutoverState struct {
+	active, staged *Consistent
+	canary         float64
+}
+
+// NewCutoverRing returns a CutoverRing serving lookups from active.
+func NewCutoverRing(active *Consistent) *CutoverRing {
+	r := new(CutoverRing)
+	r.state.Store(&cutoverState{active: active})
+	return r
+}
+
+// Stage builds a ring with the settings of the active ring and members, and
+// stages it, replacing any staged ring. No keys are routed to it until
+// SetCanary is called. The staged ring has no Logger and no observers, so
+// that its changes are not reported as if they were live, until it is
+// promoted.
+func (r *CutoverRing) Stage(members []Member) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s := r.state.Load()
+	staged := s.active.Clone()
+	staged.setLogger(nil)
+	if err := staged.SetMembers(members); err != nil {
+		return err
+	}
+	r.state.Store(&cutoverState{active: s.active, staged: staged})
+	return nil
+}
+
+// SetCanary routes fraction, between 0 and 1, of the keys to the staged
+// ring. The same keys stay in the canary as the fraction grows.
+func (r *CutoverRing) SetCanary(fraction float64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s := r.state.Load()
+	if s.staged == nil {
+		return ErrNotStaged
+	}
+	r.state.Store(&cutoverState{active: s.active, staged: s.staged, canary: min(max(fraction, 0), 1)})
+	return nil
+}
+
+// Promote makes the staged ring the active one and routes every key to it.
+// The staged ring takes over the Logger, observers and History of the active
+// ring.
+func (r *CutoverRing) Promote() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s := r.state.Load()
+	if s.staged == nil {
+		return ErrNotStaged
+	}
+	s.staged.inherit(s.active)
+	r.state.Store(&cutoverState{active: s.staged})
+	return nil
+}
+
+// inherit gives c the Logger and observers of active, and puts the History
+// of active before its own.
+func (c *Consistent) inherit(active *Consistent) {
+	active.RLock()
+	l := active.conf().logger
+	observers := active.observers
+	history := slices.Clone(active.history)
+	active.RUnlock()
+
+	c.setLogger(l)
+	c.lock()
+	defer c.Unlock()
+	c.observers = observers
+	c.history = append(history, c.history...)
+	if extra := len(c.history) - c.cfg.historySize; extra > 0 {
+		c.history = slices.Delete(c.history, 0, extra)
+	}
+	r := *c.snapshot()
+	r.observers = c.observers
+	c.ring.Store(&r)
+}
+
+// Rollback discards the staged ring and routes every key to the active one.
+func (r *CutoverRing) Rollback() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s := r.state.Load()
+	if s.staged == nil {
+		return ErrNotStaged
+	}
+	r.state.Store(&cutoverState{active: s.active})
+	return nil
+}
+
+// Active returns the active ring.
+func (r *CutoverRing) Active() *Consistent {
+	return r.state.Load().active
+}
+
+// Staged returns the staged ring, or nil.
+func (r *CutoverRing) Staged() *Consistent {
+	return r.state.Load().staged
+}
+
+// ring returns the ring serving key.
+func (s *cutoverState) ring(key string) *Consistent {
+	if s.canary > 0 && float64(mix64(xxhash64(key))>>11)/(1<<53) < s.canary {
+		return s.staged
+	}
+	return s.active
+}
+
+// InCanary reports whether key is routed to the staged ring.
+func (r *CutoverRing) InCanary(key string) bool {
+	s := r.state.Load()
+	return s.staged != nil && s.ring(key) == s.staged
+}
+
+// Get returns the owner of key on the ring serving it.
+func (r *CutoverRing) Get(key string) (string, error) {
+	return r.state.Load().ring(key).Get(key)
+}
+
+// GetN returns the n closest distinct owners of key on the ring serving it.
+func (r *CutoverRing) GetN(key string, n int) ([]string, error) {
+	return r.state.Load().ring(key).GetN(key, n)
+}
@@ -0,0 +1,101 @@
+package consistent
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestCutoverRing(t *testing.T) {
+	active := New(50)
+	active.Set(map[string]float64{"A": 1, "B": 1})
+	r := NewCutoverRing(active)
+	if err := r.Promote(); err != ErrNotStaged {
+		t.Fatalf("Promote with nothing staged: %v", err)
+	}
+	if err := r.Stage([]Member{{Name: "C", Weight: 1}}); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 100; i++ {
+		if m, _ := r.Get(fmt.Sprint(i)); m == "C" {
+			t.Fatalf("key %d routed to the staged ring without a canary", i)
+		}
+	}
+	r.SetCanary(0.25)
+	var canary []string
+	for i := 0; i < 4000; i++ {
+		key := fmt.Sprint(i)
+		m, _ := r.Get(key)
+		if r.InCanary(key) != (m == "C") {
+			t.Fatalf("key %s routed to %s, in canary %v", key, m, r.InCanary(key))
+		}
+		if m == "C" {
+			canary = append(canary, key)
+		}
+	}
+	if len(canary) < 800 || len(canary) > 1200 {
+		t.Fatalf("%d of 4000 keys in a 25%% canary", len(canary))
+	}
+	r.SetCanary(0.5)
+	for _, key := range canary {
+		if m, _ := r.Get(key); m != "C" {
+			t.Fatalf("key %s left the canary as it grew", key)
+		}
+	}
+	if err := r.Rollback(); err != nil || r.Staged() != nil || r.Active() != active {
+		t.Fatalf("Rollback: %v", err)
+	}
+	r.Stage([]Member{{Name: "C", Weight: 1}})
+	if err := r.Promote(); err != nil {
+		t.Fatal(err)
+	}
+	if m, _ := r.Get("x"); m != "C" || active.Members()[0] == "C" {
+		t.Fatalf("after Promote Get = %s, old ring %v", m, active.Members())
+	}
+}
+
+func TestCutoverStageLogsNothing(t *testing.T) {
+	var buf bytes.Buffer
+	active := New(50, WithLogger(slog.New(slog.NewTextHandler(&buf, nil))))
+	active.Set(map[string]float64{"A": 1, "B": 1})
+	buf.Reset()
+	r := NewCutoverRing(active)
+	if err := r.Stage([]Member{{Name: "C", Weight: 1}}); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != 0 {
+		t.Fatalf("staging logged %q", buf.String())
+	}
+	r.Promote()
+	r.Active().Add("D", 1)
+	if !strings.Contains(buf.String(), "member=D") {
+		t.Fatalf("promoted ring does not log: %q", buf.String())
+	}
+}
+
+func TestCutoverPromoteKeepsObservers(t *testing.T) {
+	active := New(50, WithHistorySize(10))
+	o := new(recordingObserver)
+	active.AddObserver(o)
+	active.Set(map[string]float64{"A": 1, "B": 1})
+	r := NewCutoverRing(active)
+	r.Stage([]Member{{Name: "C", Weight: 1}})
+	r.Promote()
+	o.lookups = nil
+	if m, _ := r.Get("x"); m != "C" {
+		t.Fatalf("Get = %s after Promote", m)
+	}
+	if len(o.lookups) != 1 || o.lookups[0].Members[0] != "C" {
+		t.Fatalf("lookups after Promote = %+v", o.lookups)
+	}
+	r.Active().Add("D", 1)
+	if last := o.changes[len(o.changes)-1]; last.Member != "D" {
+		t.Fatalf("last change = %+v", last)
+	}
+	h := r.Active().History()
+	if len(h) != 6 || h[0].Change.Kind != MemberAdded || h[5].Change.Member != "D" {
+		t.Fatalf("history after Promote = %+v", h)
+	}
+}
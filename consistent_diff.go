@@ -0,0 +1,130 @@
+package consistent
+
+import (
+	"maps"
+	"slices"
+)
+
+// Relocation records a key whose owner differs between two rings.
+type Relocation struct {
+	Key  []byte
+	From string
+	To   string
+}
+
+// Flow identifies keys moving from one member to another.
+type Flow struct {
+	From string
+	To   string
+}
+
+// RelocationReport describes how keys move between two ring states.
+type RelocationReport struct {
+	// Total is the number of keys examined.
+	Total int
+	// Moved lists every key whose owner changed, in input order.
+	Moved []Relocation
+	// Flows counts the moved keys per (from, to) member pair.
+	Flows map[Flow]int
+}
+
+// Fraction returns the fraction of examined keys that moved.
+func (r RelocationReport) Fraction() float64 {
+	if r.Total == 0 {
+		return 0
+	}
+	return float64(len(r.Moved)) / float64(r.Total)
+}
+
+// Diff reports which of keys are owned by different members in old and new.
+// A key is reported with an empty From or To when the corresponding ring is
+// empty.
+func Diff(old, new *Consistent, keys [][]byte) RelocationReport {
+	or, nr := old.snapshot(), new.snapshot()
+	report := RelocationReport{Total: len(keys), Flows: make(map[Flow]int)}
+	for _, k := range keys {
+		from, to := or.get(string(k)), nr.get(string(k))
+		if from == to {
+			continue
+		}
+		report.Moved = append(report.Moved, Relocation{Key: k, From: from, To: to})
+		report.Flows[Flow{From: from, To: to}]++
+	}
+	return report
+}
+
+// Transition answers ownership questions across the rings before and after
+// a topology change, so storage layers can read from both owners of a key
+// and write to both while data moves.
+type Transition struct {
+	old, new *ring
+}
+
+// NewTransition returns a Transition from old to new. It works on the rings
+// as they are when it is called; take old as a Clone before changing the
+// ring.
+func NewTransition(old, new *Consistent) *Transition {
+	return &Transition{old: old.snapshot(), new: new.snapshot()}
+}
+
+// Owners returns the owner of key on the old and on the new ring, as Get
+// would, or "" for a ring without an available owner.
+func (t *Transition) Owners(key string) (oldOwner, newOwner string) {
+	oldOwner, _ = t.old.getOne(key)
+	newOwner, _ = t.new.getOne(key)
+	return oldOwner, newOwner
+}
+
+// Moved reports whether key has a different owner on the new ring.
+func (t *Transition) Moved(key string) bool {
+	o, n := t.Owners(key)
+	return o != n
+}
+
+// OwnedByEither returns the distinct owners of key on either ring, the new
+// owner first: read from them in order and write to all of them until the
+// transition completes.
+func (t *Transition) OwnedByEither(key string) []string {
+	o, n := t.Owners(key)
+	var owners []string
+	if n != "" {
+		owners = append(owners, n)
+	}
+	if o != "" && o != n {
+		owners = append(owners, o)
+	}
+	return owners
+}
+
+// Equal reports whether c and other have the same elements with the same
+// weights and the same virtual nodes, so that they map every key alike while
+// all elements are available. Health, pins and metadata are not compared.
+func (c *Consistent) Equal(other *Consistent) bool {
+	r, o := c.snapshot(), other.snapshot()
+	return maps.Equal(r.members, o.members) &&
+		slices.Equal(r.hashes, o.hashes) &&
+		slices.Equal(r.owners, o.owners)
+}
+
+// DiffMembers compares the elements of c with those of other, such as a
+// desired topology against the live ring. It returns, sorted, the elements
+// only other has, those only c has, and those whose weight differs.
+func (c *Consistent) DiffMembers(other *Consistent) (added, removed, reweighted []string) {
+	r, o := c.snapshot(), other.snapshot()
+	for elt, w := range o.members {
+		if cw, ok := r.members[elt]; !ok {
+			added = append(added, elt)
+		} else if cw != w {
+			reweighted = append(reweighted, elt)
+		}
+	}
+	for elt := range r.members {
+		if _, ok := o.members[elt]; !ok {
+			removed = append(removed, elt)
+		}
+	}
+	slices.Sort(added)
+	slices.Sort(removed)
+	slices.Sort(reweighted)
+	return added, removed, reweighted
+}
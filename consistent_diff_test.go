@@ -0,0 +1,88 @@
+package consistent
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	old := New(100)
+	old.Set(map[string]float64{"Host1": 1, "Host2": 1, "Host3": 1})
+	cur := New(100)
+	cur.Set(map[string]float64{"Host1": 1, "Host2": 1, "Host3": 1, "Host4": 1})
+	keys := make([][]byte, 1000)
+	for i := range keys {
+		keys[i] = []byte(fmt.Sprintf("key%d", i))
+	}
+	report := Diff(old, cur, keys)
+	for _, m := range report.Moved {
+		if m.To != "Host4" {
+			t.Fatalf("key %s moved %s -> %s, want moves to Host4 only", m.Key, m.From, m.To)
+		}
+	}
+	if f := report.Fraction(); f < 0.1 || f > 0.4 {
+		t.Fatalf("relocated fraction = %.2f, want about 0.25", f)
+	}
+}
+
+func TestTransition(t *testing.T) {
+	c := New(50)
+	c.Set(map[string]float64{"A": 1, "B": 1, "C": 1})
+	old := c.Clone()
+	c.Add("D", 1)
+	tr := NewTransition(old, c)
+	moved := 0
+	for i := 0; i < 1000; i++ {
+		key := fmt.Sprint(i)
+		o, n := tr.Owners(key)
+		owners := tr.OwnedByEither(key)
+		if tr.Moved(key) {
+			moved++
+			if n != "D" || len(owners) != 2 || owners[0] != n || owners[1] != o {
+				t.Fatalf("key %s moved from %s to %s, owners %v", key, o, n, owners)
+			}
+		} else if len(owners) != 1 || owners[0] != o {
+			t.Fatalf("key %s stayed on %s, owners %v", key, o, owners)
+		}
+	}
+	if moved < 150 || moved > 350 {
+		t.Fatalf("%d of 1000 keys moved", moved)
+	}
+	c.Remove("A")
+	if o, n := tr.Owners("x"); o == "" || n == "" {
+		t.Fatalf("owners %q, %q", o, n)
+	}
+	if owners := NewTransition(New(20), New(20)).OwnedByEither("x"); owners != nil {
+		t.Fatalf("owners on empty rings %v", owners)
+	}
+}
+
+func TestEqualDiffMembers(t *testing.T) {
+	c := New(20)
+	c.Set(map[string]float64{"A": 1, "B": 2, "C": 1})
+	d := c.Clone()
+	if !c.Equal(d) || !New(20).Equal(New(20)) {
+		t.Fatal("identical rings are not Equal")
+	}
+	d.SetHealthy("A", false)
+	if !c.Equal(d) {
+		t.Fatal("health makes rings unequal")
+	}
+	e := New(30)
+	e.Set(map[string]float64{"A": 1, "B": 2, "C": 1})
+	if c.Equal(e) {
+		t.Fatal("rings with different virtual nodes are Equal")
+	}
+
+	d.Remove("C")
+	d.UpdateWeight("B", 3)
+	d.Add("E", 1)
+	d.Add("D", 1)
+	if c.Equal(d) {
+		t.Fatal("different rings are Equal")
+	}
+	added, removed, reweighted := c.DiffMembers(d)
+	if fmt.Sprint(added, removed, reweighted) != "[D E] [C] [B]" {
+		t.Fatalf("DiffMembers = %v %v %v", added, removed, reweighted)
+	}
+}
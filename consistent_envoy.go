@@ -0,0 +1,170 @@
+package consistent
+
+import (
+	"encoding/binary"
+	"math"
+	"math/bits"
+	"sort"
+	"strconv"
+)
+
+// Default ring bounds of Envoy's RING_HASH load balancer.
+const (
+	EnvoyDefaultMinRingSize = 1024
+	EnvoyDefaultMaxRingSize = 8 * 1024 * 1024
+)
+
+// EnvoyRing is a ring built exactly like Envoy's RING_HASH load balancer with
+// the default XX_HASH function, so that for the same hash key it selects the
+// same backend as an Envoy sidecar configured with the same hosts.
+//
+// An EnvoyRing is immutable; build a new one when the host set changes, as
+// Envoy does.
+type EnvoyRing struct {
+	hashes []uint64
+	owners []string
+}
+
+// NewEnvoyRing builds a ring from members in the order Envoy lists the hosts.
+// Member names must be the host addresses Envoy hashes ("ip:port"). A zero
+// ring size selects the Envoy default.
+func NewEnvoyRing(members []Member, minRingSize, maxRingSize uint64) *EnvoyRing {
+	if minRingSize == 0 {
+		minRingSize = EnvoyDefaultMinRingSize
+	}
+	if maxRingSize == 0 {
+		maxRingSize = EnvoyDefaultMaxRingSize
+	}
+	r := new(EnvoyRing)
+	var total float64
+	for _, m := range members {
+		if m.Weight > 0 {
+			total += m.Weight
+		}
+	}
+	if total == 0 {
+		return r
+	}
+	minWeight := 1.0
+	for _, m := range members {
+		if m.Weight > 0 {
+			minWeight = math.Min(minWeight, m.Weight/total)
+		}
+	}
+	scale := math.Min(math.Ceil(minWeight*float64(minRingSize))/minWeight, float64(maxRingSize))
+	ringSize := uint64(math.Ceil(scale))
+
+	type entry struct {
+		hash  uint64
+		owner string
+	}
+	entries := make([]entry, 0, ringSize)
+	var current, target float64
+	for _, m := range members {
+		if m.Weight <= 0 {
+			continue
+		}
+		target += scale * m.Weight / total
+		for i := 0; current < target; i++ {
+			entries = append(entries, entry{xxhash64(m.Name + "_" + strconv.Itoa(i)), m.Name})
+			current++
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].hash < entries[j].hash })
+	r.hashes = make([]uint64, len(entries))
+	r.owners = make([]string, len(entries))
+	for i, e := range entries {
+		r.hashes[i], r.owners[i] = e.hash, e.owner
+	}
+	return r
+}
+
+// Get returns the member Envoy would pick for a request whose hash policy
+// produced key.
+func (r *EnvoyRing) Get(key string) (string, error) {
+	return r.GetHash(xxhash64(key))
+}
+
+// GetHash returns the member owning the already computed 64-bit hash h.
+func (r *EnvoyRing) GetHash(h uint64) (string, error) {
+	if len(r.hashes) == 0 {
+		return "", ErrEmptyCircle
+	}
+	i := sort.Search(len(r.hashes), func(x int) bool { return r.hashes[x] >= h })
+	if i == len(r.hashes) {
+		i = 0
+	}
+	return r.owners[i], nil
+}
+
+// Len returns the number of entries on the ring.
+func (r *EnvoyRing) Len() int {
+	return len(r.hashes)
+}
+
+const (
+	xxPrime1 uint64 = 11400714785074694791
+	xxPrime2 uint64 = 14029467366897019727
+	xxPrime3 uint64 = 1609587929392839161
+	xxPrime4 uint64 = 9650029242287828579
+	xxPrime5 uint64 = 2870177450012600261
+)
+
+// xxhash64 is XXH64 with a zero seed, the hash Envoy uses for ring placement.
+func xxhash64(s string) uint64 {
+	b := []byte(s)
+	n := len(b)
+	var h uint64
+	if n >= 32 {
+		p1, p2 := xxPrime1, xxPrime2
+		v1 := p1 + p2
+		v2 := p2
+		v3 := uint64(0)
+		v4 := -p1
+		for ; len(b) >= 32; b = b[32:] {
+			v1 = xxRound(v1, binary.LittleEndian.Uint64(b[0:]))
+			v2 = xxRound(v2, binary.LittleEndian.Uint64(b[8:]))
+			v3 = xxRound(v3, binary.LittleEndian.Uint64(b[16:]))
+			v4 = xxRound(v4, binary.LittleEndian.Uint64(b[24:]))
+		}
+		h = bits.RotateLeft64(v1, 1) + bits.RotateLeft64(v2, 7) + bits.RotateLeft64(v3, 12) + bits.RotateLeft64(v4, 18)
+		h = xxMergeRound(h, v1)
+		h = xxMergeRound(h, v2)
+		h = xxMergeRound(h, v3)
+		h = xxMergeRound(h, v4)
+	} else {
+		h = xxPrime5
+	}
+	h += uint64(n)
+	for ; len(b) >= 8; b = b[8:] {
+		h ^= xxRound(0, binary.LittleEndian.Uint64(b))
+		h = bits.RotateLeft64(h, 27)*xxPrime1 + xxPrime4
+	}
+	if len(b) >= 4 {
+		h ^= uint64(binary.LittleEndian.Uint32(b)) * xxPrime1
+		h = bits.RotateLeft64(h, 23)*xxPrime2 + xxPrime3
+		b = b[4:]
+	}
+	for _, c := range b {
+		h ^= uint64(c) * xxPrime5
+		h = bits.RotateLeft64(h, 11) * xxPrime1
+	}
+	h ^= h >> 33
+	h *= xxPrime2
+	h ^= h >> 29
+	h *= xxPrime3
+	h ^= h >> 32
+	return h
+}
+
+func xxRound(acc, input uint64) uint64 {
+	acc += input * xxPrime2
+	acc = bits.RotateLeft64(acc, 31)
+	return acc * xxPrime1
+}
+
+func xxMergeRound(acc, val uint64) uint64 {
+	val = xxRound(0, val)
+	acc ^= val
+	return acc*xxPrime1 + xxPrime4
+}
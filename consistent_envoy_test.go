@@ -0,0 +1,23 @@
+package consistent
+
+import "testing"
+
+func TestEnvoyRing(t *testing.T) {
+	for s, want := range map[string]uint64{
+		"":              0xef46db3751d8e999,
+		"abc":           0x44bc2cf5ad770999,
+		"10.0.0.1:80_0": 0x75041381e7371a08,
+		"0123456789abcdefghijklmnopqrstuvwxyz0123456789ABCDEF": 0xebaa4c2c8844553c,
+	} {
+		if got := xxhash64(s); got != want {
+			t.Fatalf("xxhash64(%q) = %#x, want %#x", s, got, want)
+		}
+	}
+	r := NewEnvoyRing([]Member{{Name: "10.0.0.1:80", Weight: 1}, {Name: "10.0.0.2:80", Weight: 1}, {Name: "10.0.0.3:80", Weight: 2}}, 0, 0)
+	if r.Len() != 1024 {
+		t.Fatalf("ring size = %d, want 1024", r.Len())
+	}
+	if _, err := r.Get("user-1"); err != nil {
+		t.Fatal(err)
+	}
+}
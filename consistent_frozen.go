@@ -0,0 +1,97 @@
+package consistent
+
+// Builder collects the elements of a FrozenRing.
+type Builder struct {
+	// NumberOfReplicas, UseFnv, Hasher, KetamaMode, Seed and VirtualNodeBudget
+	// have the same meaning as on Consistent; a FrozenRing maps keys exactly
+	// like a Consistent with the same settings and elements.
+	NumberOfReplicas  int
+	UseFnv            bool
+	Hasher            func(key string) uint32
+	KetamaMode        bool
+	Seed              uint64
+	VirtualNodeBudget int
+	members           map[string]float64
+}
+
+// NewBuilder returns a Builder placing numberOfReplicas virtual nodes per
+// unit of weight, 20 if numberOfReplicas is 0 or less.
+func NewBuilder(numberOfReplicas int) *Builder {
+	return &Builder{NumberOfReplicas: numberOfReplicas, members: make(map[string]float64)}
+}
+
+// Add adds an element to the ring being built. It returns ErrMemberExists if
+// elt was already added.
+func (b *Builder) Add(elt string, wgt float64) error {
+	if err := validateWeight(wgt); err != nil {
+		return err
+	}
+	if _, ok := b.members[elt]; ok {
+		return ErrMemberExists
+	}
+	b.members[elt] = wgt
+	return nil
+}
+
+// Build returns a FrozenRing of the elements added so far. The Builder can be
+// reused to build further rings.
+func (b *Builder) Build() *FrozenRing {
+	c := New(b.NumberOfReplicas)
+	c.UseFnv = b.UseFnv
+	c.Hasher = b.Hasher
+	c.KetamaMode = b.KetamaMode
+	c.Seed = b.Seed
+	c.VirtualNodeBudget = b.VirtualNodeBudget
+	c.Set(b.members)
+	return &FrozenRing{r: c.snapshot()}
+}
+
+// FrozenRing is an immutable ring built by a Builder. Its lookups take no
+// locks and do no atomic operations, for topologies fixed at startup where
+// lookup throughput is all that matters.
+type FrozenRing struct {
+	r *ring
+}
+
+// Freeze returns the current ring of c as a FrozenRing, for many lookups that
+// must see the same ring. Later changes to c do not affect it, and lookups on
+// it are neither counted in Stats nor reported to observers.
+func (c *Consistent) Freeze() *FrozenRing {
+	return &FrozenRing{r: c.snapshot()}
+}
+
+// Get returns an element close to where name hashes to in the circle.
+func (f *FrozenRing) Get(name string) (string, error) {
+	return f.r.getOne(name)
+}
+
+// GetTwo returns the two closest distinct elements to the name input in the circle.
+func (f *FrozenRing) GetTwo(name string) (string, string, error) {
+	return f.r.getTwo(name)
+}
+
+// GetN returns the N closest distinct elements to the name input in the circle.
+func (f *FrozenRing) GetN(name string, n int) ([]string, error) {
+	res, err := f.r.getNExcluding(name, n, nil)
+	return res, atLeast(res, n, err)
+}
+
+// GetUint64 returns the element the integer key maps to, like
+// Consistent.GetUint64.
+func (f *FrozenRing) GetUint64(key uint64) (string, error) {
+	return f.r.getUint64(key)
+}
+
+// Members returns the names of the elements of the ring.
+func (f *FrozenRing) Members() []string {
+	m := make([]string, 0, len(f.r.members))
+	for elt := range f.r.members {
+		m = append(m, elt)
+	}
+	return m
+}
+
+// Len returns the number of virtual nodes on the ring.
+func (f *FrozenRing) Len() int {
+	return len(f.r.hashes)
+}
@@ -0,0 +1,41 @@
+package consistent
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestFrozenRing(t *testing.T) {
+	b := NewBuilder(20)
+	b.Add("A", 1)
+	b.Add("B", 2)
+	if err := b.Add("A", 1); !errors.Is(err, ErrMemberExists) {
+		t.Fatalf("Add of duplicate: %v", err)
+	}
+	f := b.Build()
+	c := New(20)
+	c.Set(map[string]float64{"A": 1, "B": 2})
+	for i := 0; i < 50; i++ {
+		want, _ := c.Get(fmt.Sprint(i))
+		if got, _ := f.Get(fmt.Sprint(i)); got != want {
+			t.Fatalf("FrozenRing maps %d to %q, Consistent to %q", i, got, want)
+		}
+	}
+	if res, _ := f.GetN("key", 5); len(res) != 2 {
+		t.Fatalf("GetN = %v", res)
+	}
+
+	frozen := c.Freeze()
+	lookups := c.Stats().Lookups
+	c.Add("C", 5)
+	for i := uint64(0); i < 50; i++ {
+		got, _ := frozen.GetUint64(i)
+		if want, _ := f.GetUint64(i); got != want {
+			t.Fatalf("Freeze maps %d to %q after a change, want %q", i, got, want)
+		}
+	}
+	if c.Stats().Lookups != lookups {
+		t.Fatal("lookups on a frozen ring were counted")
+	}
+}
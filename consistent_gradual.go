@@ -0,0 +1,156 @@
+package consistent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"time"
+)
+
+// Progress reports a step taken by ApplyGradually.
+type Progress struct {
+	// Step is the number of the step just applied, from 1 to Steps.
+	Step, Steps int
+	// Moved is the fraction of the hash space that changed owner in the step.
+	Moved float64
+	// Err is set if the step could not be applied; no further steps follow.
+	Err error
+}
+
+// ErrRingChanged is the error ApplyGradually reports when the members of the
+// ring were changed by something else while it was applying its steps.
+var ErrRingChanged = errors.New("ring changed during gradual apply")
+
+// minStep is the smallest fraction of the way to the target ApplyGradually
+// advances per step, even if that moves more than maxMovedFraction.
+const minStep = 1.0 / 1024
+
+// ApplyGradually moves c to the target members and weights in steps, waiting
+// interval between steps, so that each step moves at most maxMovedFraction of
+// the hash space to new owners. Weights move linearly from their current to
+// their target values; new members grow from nothing and removed members
+// shrink away. A step may exceed maxMovedFraction when a single virtual node
+// alone carries more.
+//
+// The steps are planned up front against the current members and applied
+// like Set in the background. Before each step the members are checked
+// against those the previous step left; if anything else changed them, the
+// step fails with ErrRingChanged rather than silently reverting the change.
+// A step also fails with the error of ctx once it is done. The returned
+// channel receives one Progress per step and is closed when the target is
+// reached or a step fails.
+func (c *Consistent) ApplyGradually(ctx context.Context, target map[string]float64, maxMovedFraction float64, interval time.Duration) (<-chan Progress, error) {
+	if err := validateWeights(target); err != nil {
+		return nil, err
+	}
+	if !(maxMovedFraction > 0) || math.IsInf(maxMovedFraction, 1) {
+		return nil, fmt.Errorf("invalid moved fraction %v", maxMovedFraction)
+	}
+	c.RLock()
+	current := make(map[string]float64, len(c.members))
+	for elt, wgt := range c.members {
+		current[elt] = wgt
+	}
+	c.RUnlock()
+
+	at := func(t float64) map[string]float64 {
+		m := make(map[string]float64, len(current)+len(target))
+		for elt, wgt := range current {
+			if w := wgt + t*(target[elt]-wgt); w > 0 {
+				m[elt] = w
+			}
+		}
+		for elt, wgt := range target {
+			if _, ok := current[elt]; !ok && t > 0 {
+				m[elt] = t * wgt
+			}
+		}
+		return m
+	}
+	moved := func(a, b map[string]float64) float64 {
+		plan, _ := c.PlanRebalance(a, b, nil)
+		var f float64
+		for _, m := range plan {
+			f += m.Size
+		}
+		return f
+	}
+
+	type step struct {
+		members map[string]float64
+		moved   float64
+	}
+	var steps []step
+	prev, t := current, 0.0
+	for t < 1 {
+		next, f := 1.0, moved(prev, target)
+		if f > maxMovedFraction {
+			// Binary search for the furthest point within the bound.
+			lo, hi := t, 1.0
+			for hi-lo > minStep/2 {
+				mid := (lo + hi) / 2
+				if moved(prev, at(mid)) <= maxMovedFraction {
+					lo = mid
+				} else {
+					hi = mid
+				}
+			}
+			next = max(lo, t+minStep)
+			if next > 1 {
+				next = 1
+			}
+			f = moved(prev, at(next))
+		}
+		state := at(next)
+		if next == 1 {
+			state = target
+		}
+		steps = append(steps, step{state, f})
+		prev, t = state, next
+	}
+
+	ch := make(chan Progress, len(steps))
+	go func() {
+		defer close(ch)
+		prev := current
+		for i, s := range steps {
+			if i > 0 {
+				t := time.NewTimer(interval)
+				select {
+				case <-t.C:
+				case <-ctx.Done():
+					t.Stop()
+				}
+			}
+			err := ctx.Err()
+			if err == nil {
+				err = c.setIf(prev, s.members)
+			}
+			if err != nil {
+				ch <- Progress{Step: i + 1, Steps: len(steps), Err: err}
+				return
+			}
+			ch <- Progress{Step: i + 1, Steps: len(steps), Moved: s.moved}
+			prev = s.members
+		}
+	}()
+	return ch, nil
+}
+
+// setIf is like Set but returns ErrRingChanged without changing anything if
+// the members of c are not expected.
+func (c *Consistent) setIf(expected, eltMap map[string]float64) error {
+	c.lock()
+	defer c.Unlock()
+	if !maps.Equal(c.members, expected) {
+		return ErrRingChanged
+	}
+	if err := c.checkMoved(c.setter(eltMap)); err != nil {
+		return err
+	}
+	c.set(eltMap)
+	c.updateSortedHashes()
+	return nil
+}
@@ -0,0 +1,87 @@
+package consistent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestApplyGradually(t *testing.T) {
+	c := New(20)
+	c.Set(map[string]float64{"A": 1, "B": 1, "C": 1})
+	target := map[string]float64{"A": 1, "B": 2, "D": 1}
+	ch, err := c.ApplyGradually(context.Background(), target, 0.1, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	steps := 0
+	for p := range ch {
+		if p.Err != nil {
+			t.Fatal(p.Err)
+		}
+		if p.Moved > 0.15 {
+			t.Fatalf("step %d/%d moved %.2f of the hash space", p.Step, p.Steps, p.Moved)
+		}
+		steps++
+	}
+	if steps < 3 {
+		t.Fatalf("applied in %d steps", steps)
+	}
+	got := map[string]float64{}
+	for _, m := range c.MemberInfos() {
+		got[m.Name] = m.Weight
+	}
+	if fmt.Sprint(got) != fmt.Sprint(target) {
+		t.Fatalf("members after ApplyGradually = %v, want %v", got, target)
+	}
+}
+
+func TestApplyGraduallyInvalidFraction(t *testing.T) {
+	c := New(20)
+	c.Set(map[string]float64{"A": 1})
+	for _, f := range []float64{0, -0.1, math.NaN()} {
+		if _, err := c.ApplyGradually(context.Background(), map[string]float64{"B": 1}, f, 0); err == nil {
+			t.Fatalf("ApplyGradually accepted a moved fraction of %v", f)
+		}
+	}
+}
+
+func TestApplyGraduallyConcurrentChange(t *testing.T) {
+	c := New(20)
+	c.Set(map[string]float64{"A": 1, "B": 1, "C": 1})
+	ch, err := c.ApplyGradually(context.Background(), map[string]float64{"A": 1, "B": 1, "D": 1}, 0.05, 20*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := <-ch; p.Err != nil {
+		t.Fatal(p.Err)
+	}
+	c.Add("E", 1)
+	var last Progress
+	for p := range ch {
+		last = p
+	}
+	if !errors.Is(last.Err, ErrRingChanged) {
+		t.Fatalf("last step = %+v, want ErrRingChanged", last)
+	}
+	if w, ok := c.Weight("E"); !ok || w != 1 {
+		t.Fatal("the concurrent change was reverted")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch, err = c.ApplyGradually(ctx, map[string]float64{"A": 1}, 0.05, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-ch
+	cancel()
+	for p := range ch {
+		last = p
+	}
+	if !errors.Is(last.Err, context.Canceled) {
+		t.Fatalf("last step after cancel = %+v", last)
+	}
+}
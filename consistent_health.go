@@ -0,0 +1,109 @@
+package consistent
+
+import "errors"
+
+// ErrNoAvailableMember is the error returned when every element in the circle is unhealthy or drained.
+var ErrNoAvailableMember = errors.New("no available member")
+
+// SetHealthy marks elt as healthy or unhealthy. An unhealthy element keeps its
+// place in the circle, so no keys move, but lookups skip it and its keys are
+// served by the next elements until it is healthy again.
+func (c *Consistent) SetHealthy(elt string, healthy bool) error {
+	c.lock()
+	defer c.Unlock()
+	if _, ok := c.members[elt]; !ok {
+		return ErrMemberNotFound
+	}
+	if c.unhealthy[elt] != healthy {
+		return nil
+	}
+	setEntry(c, ownUnhealthy, &c.unhealthy, elt, true, healthy)
+	c.republish()
+	return nil
+}
+
+// Healthy reports whether elt is present, not marked unhealthy and not
+// holding an expired lease.
+func (c *Consistent) Healthy(elt string) bool {
+	c.RLock()
+	defer c.RUnlock()
+	_, ok := c.members[elt]
+	return ok && !c.unhealthy[elt] && !c.expired[elt]
+}
+
+// Drain takes elt out of service, for example for a restart. Like an
+// unhealthy element it keeps its place in the circle and its keys are served
+// by the next elements, so draining and undraining move each key at most once
+// each way. Drain is independent of health: an element is only used again
+// once it is both undrained and healthy.
+func (c *Consistent) Drain(elt string) error {
+	return c.setDrained(elt, true)
+}
+
+// Undrain puts an element drained with Drain back into service.
+func (c *Consistent) Undrain(elt string) error {
+	return c.setDrained(elt, false)
+}
+
+// Drained reports whether elt is present and drained.
+func (c *Consistent) Drained(elt string) bool {
+	c.RLock()
+	defer c.RUnlock()
+	return c.drained[elt]
+}
+
+func (c *Consistent) setDrained(elt string, drained bool) error {
+	c.lock()
+	defer c.Unlock()
+	if _, ok := c.members[elt]; !ok {
+		return ErrMemberNotFound
+	}
+	if c.drained[elt] == drained {
+		return nil
+	}
+	setEntry(c, ownDrained, &c.drained, elt, true, !drained)
+	c.republish()
+	return nil
+}
+
+// down returns the elements lookups skip.
+//
+// need c.Lock() before calling
+func (c *Consistent) down() map[string]bool {
+	var only map[string]bool
+	n := 0
+	for _, m := range [...]map[string]bool{c.unhealthy, c.drained, c.expired} {
+		if len(m) > 0 {
+			only = m
+			n++
+		}
+	}
+	if n <= 1 {
+		return only
+	}
+	down := make(map[string]bool, len(c.unhealthy)+len(c.drained)+len(c.expired))
+	for _, m := range [...]map[string]bool{c.unhealthy, c.drained, c.expired} {
+		for elt := range m {
+			down[elt] = true
+		}
+	}
+	return down
+}
+
+// republish publishes the current circle again after a change that moves no
+// virtual nodes.
+//
+// need c.Lock() before calling
+func (c *Consistent) republish() {
+	if c.stale {
+		c.regenerate()
+		return
+	}
+	r := c.snapshot()
+	c.publish(r.hashes, r.owners)
+}
+
+// skip reports whether lookups must pass over elt.
+func (r *ring) skip(elt string) bool {
+	return r.down[elt]
+}
@@ -0,0 +1,72 @@
+package consistent
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestSetHealthy(t *testing.T) {
+	c := New(20)
+	c.Set(map[string]float64{"A": 1, "B": 1, "C": 1})
+	before := map[string]string{}
+	for i := 0; i < 100; i++ {
+		before[fmt.Sprint(i)], _ = c.Get(fmt.Sprint(i))
+	}
+	if err := c.SetHealthy("B", false); err != nil {
+		t.Fatal(err)
+	}
+	if c.Healthy("B") {
+		t.Fatal("B should be unhealthy")
+	}
+	for key, owner := range before {
+		got, err := c.Get(key)
+		if err != nil || got == "B" || (owner != "B" && got != owner) {
+			t.Fatalf("Get(%q) = %q, %v; was %q", key, got, err, owner)
+		}
+		if res, _ := c.GetN(key, 3); len(res) != 2 {
+			t.Fatalf("GetN(%q) = %v, want the 2 healthy members", key, res)
+		}
+	}
+	c.SetHealthy("B", true)
+	for key, owner := range before {
+		if got, _ := c.Get(key); got != owner {
+			t.Fatalf("Get(%q) = %q after recovery, want %q", key, got, owner)
+		}
+	}
+	c.SetHealthy("A", false)
+	c.SetHealthy("B", false)
+	c.SetHealthy("C", false)
+	if _, err := c.Get("key"); !errors.Is(err, ErrNoAvailableMember) {
+		t.Fatalf("Get with no healthy member: %v", err)
+	}
+	if err := c.SetHealthy("D", false); !errors.Is(err, ErrMemberNotFound) {
+		t.Fatalf("SetHealthy of unknown member: %v", err)
+	}
+}
+
+func TestDrain(t *testing.T) {
+	c := New(20)
+	c.Set(map[string]float64{"A": 1, "B": 1, "C": 1})
+	before := map[string]string{}
+	for i := 0; i < 100; i++ {
+		before[fmt.Sprint(i)], _ = c.Get(fmt.Sprint(i))
+	}
+	c.Drain("A")
+	c.SetHealthy("A", false)
+	c.Undrain("A")
+	if c.Drained("A") {
+		t.Fatal("A still drained")
+	}
+	for key := range before {
+		if got, _ := c.Get(key); got == "A" {
+			t.Fatalf("Get(%q) = A while A is unhealthy", key)
+		}
+	}
+	c.SetHealthy("A", true)
+	for key, owner := range before {
+		if got, _ := c.Get(key); got != owner {
+			t.Fatalf("Get(%q) = %q after undrain, want %q", key, got, owner)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package consistent
+
+import (
+	"slices"
+	"time"
+)
+
+// HistoryEntry records a membership change kept by History.
+type HistoryEntry struct {
+	Time time.Time
+	Change
+	// Moved is the fraction of the hash space that changed owner in the
+	// operation the change was part of. Changes made together, as by Set,
+	// share the same Moved.
+	Moved float64
+}
+
+// recordHistory appends the pending changes to the history, dropping the
+// oldest entries beyond HistorySize.
+//
+// need c.Lock() before calling
+func (c *Consistent) recordHistory(old, r *ring) {
+	if c.cfg.historySize <= 0 || len(c.changes) == 0 {
+		return
+	}
+	var moved float64
+	diffArcs(old, r, func(h HashRange, _, _ string) {
+		moved += float64(h.Size()) / (1 << 32)
+	})
+	now := time.Now()
+	for _, ch := range c.changes {
+		c.history = append(c.history, HistoryEntry{Time: now, Change: ch, Moved: moved})
+	}
+	if extra := len(c.history) - c.cfg.historySize; extra > 0 {
+		c.history = slices.Delete(c.history, 0, extra)
+	}
+}
+
+// History returns the last HistorySize membership changes, oldest first.
+func (c *Consistent) History() []HistoryEntry {
+	c.RLock()
+	defer c.RUnlock()
+	return slices.Clone(c.history)
+}
@@ -0,0 +1,38 @@
+package consistent
+
+import (
+	"math"
+	"testing"
+)
+
+func TestHistory(t *testing.T) {
+	c := New(20)
+	c.HistorySize = 3
+	c.Add("A", 1)
+	c.Add("B", 1)
+	c.UpdateWeight("B", 3)
+	c.Remove("A")
+	h := c.History()
+	if len(h) != 3 {
+		t.Fatalf("History has %d entries, want 3", len(h))
+	}
+	if h[0].Kind != MemberAdded || h[0].Member != "B" ||
+		h[1].Kind != WeightChanged || h[1].OldWeight != 1 || h[1].NewWeight != 3 ||
+		h[2].Kind != MemberRemoved || h[2].Member != "A" {
+		t.Fatalf("History = %+v", h)
+	}
+	for _, e := range h {
+		if e.Moved <= 0 || e.Moved > 1 || e.Time.IsZero() {
+			t.Fatalf("entry %+v", e)
+		}
+	}
+	if math.Abs(h[2].Moved-0.25) > 0.15 {
+		t.Fatalf("removing A moved %v of the hash space", h[2].Moved)
+	}
+
+	d := New(20)
+	d.Add("A", 1)
+	if len(d.History()) != 0 {
+		t.Fatal("History recorded without HistorySize")
+	}
+}
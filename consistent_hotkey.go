@@ -0,0 +1,122 @@
+package consistent
+
+import (
+	"hash/maphash"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// HotKeyOptions configures a HotKeyRouter.
+type HotKeyOptions struct {
+	// Threshold is the lookup rate, in lookups per second, above which a key
+	// is considered hot.
+	Threshold float64
+	// Window is the period over which lookup rates are measured; 0 means one
+	// second. Hot keys are re-detected in every window.
+	Window time.Duration
+	// Spread is the number of ring owners a hot key is spread across; 0 means 3.
+	Spread int
+	// OnHot, if set, is called once per window for every key that becomes hot.
+	// It is called from the lookup that made the key hot, without any lock
+	// held, so it may use the router.
+	OnHot func(key string, rate float64)
+	// Width and Depth size the count-min sketch; 0 means 2048 and 4.
+	Width, Depth int
+}
+
+// HotKeyRouter routes lookups through a Consistent while estimating the rate
+// of every key with a count-min sketch. Keys whose rate exceeds the threshold
+// are fanned out at random across their first Spread owners instead of
+// always hitting a single owner. The sketch uses atomic counters, so lookups
+// do not serialize on a lock; counts racing with the start of a new window
+// may land in either window.
+type HotKeyRouter struct {
+	c    *Consistent
+	opts HotKeyOptions
+	seed maphash.Seed
+
+	counts []atomic.Uint32
+	window atomic.Int64 // start of the current window, in Unix nanoseconds
+	hot    sync.Map     // keys hot in the current window
+}
+
+// NewHotKeyRouter returns a HotKeyRouter for c.
+func NewHotKeyRouter(c *Consistent, opts HotKeyOptions) *HotKeyRouter {
+	if opts.Window <= 0 {
+		opts.Window = time.Second
+	}
+	if opts.Spread <= 0 {
+		opts.Spread = 3
+	}
+	if opts.Width <= 0 {
+		opts.Width = 2048
+	}
+	if opts.Depth <= 0 {
+		opts.Depth = 4
+	}
+	h := &HotKeyRouter{
+		c:      c,
+		opts:   opts,
+		seed:   maphash.MakeSeed(),
+		counts: make([]atomic.Uint32, opts.Width*opts.Depth),
+	}
+	h.window.Store(time.Now().UnixNano())
+	return h
+}
+
+// Get returns the owner of key, or for a hot key one of its first Spread
+// owners chosen at random.
+func (h *HotKeyRouter) Get(key string) (string, error) {
+	if !h.record(key) {
+		return h.c.Get(key)
+	}
+	owners, err := h.c.GetN(key, h.opts.Spread)
+	if len(owners) == 0 {
+		if err == nil {
+			err = ErrEmptyCircle
+		}
+		return "", err
+	}
+	return owners[rand.IntN(len(owners))], nil
+}
+
+// IsHot reports whether key is hot in the current window.
+func (h *HotKeyRouter) IsHot(key string) bool {
+	_, ok := h.hot.Load(key)
+	return ok
+}
+
+// record counts a lookup of key and reports whether key is hot.
+func (h *HotKeyRouter) record(key string) bool {
+	sum := maphash.String(h.seed, key)
+	h1, h2 := uint32(sum), uint32(sum>>32)|1
+
+	now := time.Now().UnixNano()
+	if start := h.window.Load(); now-start >= int64(h.opts.Window) && h.window.CompareAndSwap(start, now) {
+		for i := range h.counts {
+			h.counts[i].Store(0)
+		}
+		h.hot.Clear()
+	}
+	if _, ok := h.hot.Load(key); ok {
+		return true
+	}
+	// Count-min: increment one counter per row and estimate with the minimum.
+	est := uint32(0)
+	for i := 0; i < h.opts.Depth; i++ {
+		idx := i*h.opts.Width + int((h1+uint32(i)*h2)%uint32(h.opts.Width))
+		if n := h.counts[idx].Add(1); i == 0 || n < est {
+			est = n
+		}
+	}
+	rate := float64(est) / h.opts.Window.Seconds()
+	if rate <= h.opts.Threshold {
+		return false
+	}
+	if _, loaded := h.hot.LoadOrStore(key, struct{}{}); !loaded && h.opts.OnHot != nil {
+		h.opts.OnHot(key, rate)
+	}
+	return true
+}
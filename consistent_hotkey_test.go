@@ -0,0 +1,65 @@
+package consistent
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHotKeyRouter(t *testing.T) {
+	c := New(20)
+	c.Set(map[string]float64{"Host1": 1, "Host2": 1, "Host3": 1, "Host4": 1})
+	var hot []string
+	h := NewHotKeyRouter(c, HotKeyOptions{Threshold: 50, Window: time.Minute, OnHot: func(k string, _ float64) {
+		hot = append(hot, k)
+	}})
+	seen := make(map[string]bool)
+	for i := 0; i < 5000; i++ {
+		m, err := h.Get("celebrity")
+		if err != nil {
+			t.Fatal(err)
+		}
+		seen[m] = true
+	}
+	if len(hot) != 1 || hot[0] != "celebrity" {
+		t.Fatalf("hot keys = %v", hot)
+	}
+	if len(seen) != 3 {
+		t.Fatalf("hot key served by %d owners, want 3", len(seen))
+	}
+	owner, _ := c.Get("quiet")
+	if m, _ := h.Get("quiet"); m != owner {
+		t.Fatalf("cold key routed to %q, want %q", m, owner)
+	}
+}
+
+func TestHotKeyRouterOnHotReenters(t *testing.T) {
+	c := New(20)
+	c.Set(map[string]float64{"Host1": 1, "Host2": 1, "Host3": 1})
+	var h *HotKeyRouter
+	var calls atomic.Int32
+	h = NewHotKeyRouter(c, HotKeyOptions{Threshold: 5, Window: time.Minute, OnHot: func(k string, _ float64) {
+		calls.Add(1)
+		if !h.IsHot(k) {
+			t.Errorf("%s not hot inside OnHot", k)
+		}
+		if _, err := h.Get(k); err != nil {
+			t.Error(err)
+		}
+	}})
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				h.Get("celebrity")
+			}
+		}()
+	}
+	wg.Wait()
+	if n := calls.Load(); n != 1 {
+		t.Fatalf("OnHot called %d times, want 1", n)
+	}
+}
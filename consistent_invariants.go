@@ -0,0 +1,78 @@
+package consistent
+
+import (
+	"fmt"
+	"slices"
+)
+
+// CheckInvariants verifies the internal consistency of c and returns an
+// error describing the first violation found, or nil. It checks that the
+// published hashes are strictly ascending and match the circle, that every
+// virtual node is owned by a member, and that each member holds exactly the
+// virtual nodes its weight calls for. It is meant for tests and for
+// embedders who want to detect corruption after a sequence of mutations;
+// it takes the read lock and costs as much as a full rebuild.
+func (c *Consistent) CheckInvariants() error {
+	c.RLock()
+	defer c.RUnlock()
+	r := c.snapshot()
+	if len(r.hashes) != len(r.owners) {
+		return fmt.Errorf("%d hashes but %d owners", len(r.hashes), len(r.owners))
+	}
+	if len(r.hashes) != len(c.circle) {
+		return fmt.Errorf("%d hashes but %d virtual nodes in the circle", len(r.hashes), len(c.circle))
+	}
+	for i, h := range r.hashes {
+		if i > 0 && r.hashes[i-1] >= h {
+			return fmt.Errorf("hashes not strictly ascending at index %d", i)
+		}
+		if owner, ok := c.circle[h]; !ok || owner != r.owners[i] {
+			return fmt.Errorf("virtual node %d owned by %q, circle has %q", h, r.owners[i], owner)
+		}
+	}
+	if len(r.members) != len(c.members) {
+		return fmt.Errorf("%d members published, %d held", len(r.members), len(c.members))
+	}
+	for elt, wgt := range c.members {
+		if pw, ok := r.members[elt]; !ok || pw != wgt {
+			return fmt.Errorf("member %q published with weight %v, held with %v", elt, pw, wgt)
+		}
+	}
+
+	claims := make(map[uint32][]string, len(c.circle))
+	for elt, wgt := range c.members {
+		for _, h := range c.nodeHashes(elt, wgt) {
+			claims[h] = append(claims[h], elt)
+		}
+	}
+	if len(claims) != len(c.circle) {
+		return fmt.Errorf("members claim %d virtual nodes, circle has %d", len(claims), len(c.circle))
+	}
+	for h, want := range claims {
+		slices.Sort(want)
+		owner, ok := c.circle[h]
+		if !ok || owner != want[0] {
+			return fmt.Errorf("virtual node %d owned by %q, want %q", h, owner, want[0])
+		}
+		got := c.collisions[h]
+		if len(want) == 1 {
+			if got != nil {
+				return fmt.Errorf("virtual node %d has claimants %v, want none", h, got)
+			}
+			continue
+		}
+		if !slices.Equal(got, want) {
+			return fmt.Errorf("virtual node %d has claimants %v, want %v", h, got, want)
+		}
+	}
+	if c.budgeted() && len(c.members) > 0 {
+		total := 0
+		for _, n := range c.alloc {
+			total += n
+		}
+		if budget := c.conf().budget; total != budget {
+			return fmt.Errorf("%d virtual nodes allocated, budget is %d", total, budget)
+		}
+	}
+	return nil
+}
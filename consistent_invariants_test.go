@@ -0,0 +1,46 @@
+package consistent
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+)
+
+// FuzzRing applies the mutations encoded in ops to a ring, two bytes per
+// mutation, and checks the invariants after each of them.
+func FuzzRing(f *testing.F) {
+	f.Add(byte(0), []byte{0, 1, 0, 2, 1, 1, 2, 3})
+	f.Add(byte(1), []byte{0, 1, 0, 2, 0, 3, 3, 0})
+	f.Add(byte(2), []byte{0, 9, 2, 9, 1, 9, 0, 4})
+	f.Fuzz(func(t *testing.T, mode byte, ops []byte) {
+		c := New(int(mode%4) * 5)
+		c.KetamaMode = mode&4 != 0
+		if mode&8 != 0 {
+			c.VirtualNodeBudget = 100
+		}
+		for i := 0; i+1 < len(ops) && i < 64; i += 2 {
+			elt := fmt.Sprint("m", ops[i+1]%8)
+			wgt := float64(ops[i+1]%5 + 1)
+			switch ops[i] % 4 {
+			case 0:
+				c.Add(elt, wgt)
+			case 1:
+				c.Remove(elt)
+			case 2:
+				c.UpdateWeight(elt, wgt)
+			case 3:
+				m := make(map[string]float64)
+				for j := byte(0); j < ops[i+1]%8; j++ {
+					m[fmt.Sprint("m", j)] = float64(j%3 + 1)
+				}
+				c.Set(m)
+			}
+			if err := c.CheckInvariants(); err != nil {
+				t.Fatalf("after op %d: %v", i/2, err)
+			}
+			if m, err := c.Get("key"); err == nil && !slices.Contains(c.Members(), m) {
+				t.Fatalf("Get returned %q, not a member", m)
+			}
+		}
+	})
+}
@@ -0,0 +1,49 @@
+package consistent
+
+import "iter"
+
+// Iter returns the distinct elements in the order GetAll would, from where
+// name hashes to in the circle, without building the whole list. This lets
+// callers try one element after another and stop at the first that works.
+// The sequence walks the circle as it was when Iter was called.
+func (c *Consistent) Iter(name string) iter.Seq[string] {
+	r := c.snapshot()
+	c.countLookup()
+	return func(yield func(string) bool) {
+		if len(r.hashes) == 0 {
+			return
+		}
+		seen := make(map[string]struct{})
+		if elt, ok := r.pinned(name); ok {
+			seen[elt] = struct{}{}
+			if !yield(elt) {
+				return
+			}
+		}
+		start := r.search(r.hash(name))
+		for k := 0; k < len(r.owners) && len(seen) < len(r.members); k++ {
+			elt := r.owners[(start+k)%len(r.owners)]
+			if _, ok := seen[elt]; ok || r.skip(elt) {
+				continue
+			}
+			seen[elt] = struct{}{}
+			if !yield(elt) {
+				return
+			}
+		}
+	}
+}
+
+// AllMembers returns the elements of the hash with their weights, in no
+// particular order, without copying them. The sequence ranges over the
+// elements as they were when AllMembers was called.
+func (c *Consistent) AllMembers() iter.Seq2[string, float64] {
+	r := c.snapshot()
+	return func(yield func(string, float64) bool) {
+		for elt, w := range r.members {
+			if !yield(elt, w) {
+				return
+			}
+		}
+	}
+}
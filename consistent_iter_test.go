@@ -0,0 +1,50 @@
+package consistent
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestIter(t *testing.T) {
+	c := New(20)
+	c.Set(map[string]float64{"A": 1, "B": 2, "C": 1, "D": 1})
+	for i := 0; i < 20; i++ {
+		key := fmt.Sprint(i)
+		all, _ := c.GetAll(key)
+		var got []string
+		for m := range c.Iter(key) {
+			got = append(got, m)
+		}
+		if fmt.Sprint(got) != fmt.Sprint(all) {
+			t.Fatalf("Iter(%q) = %v, want %v", key, got, all)
+		}
+		for m := range c.Iter(key) {
+			if m != all[0] {
+				t.Fatalf("first of Iter(%q) = %q, want %q", key, m, all[0])
+			}
+			break
+		}
+	}
+}
+
+func TestAllMembers(t *testing.T) {
+	c := New(20)
+	c.Set(map[string]float64{"A": 1, "B": 2, "C": 3})
+	seq := c.AllMembers()
+	c.Add("D", 4)
+	got := map[string]float64{}
+	for elt, w := range seq {
+		got[elt] = w
+	}
+	if fmt.Sprint(got) != "map[A:1 B:2 C:3]" {
+		t.Fatalf("AllMembers = %v", got)
+	}
+	n := 0
+	for range c.AllMembers() {
+		n++
+		break
+	}
+	if n != 1 {
+		t.Fatalf("AllMembers kept going after break")
+	}
+}
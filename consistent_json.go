@@ -0,0 +1,93 @@
+package consistent
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// consistentJSON is the persisted form of a Consistent: everything needed to
+// rebuild the exact same ring.
+type consistentJSON struct {
+	NumberOfReplicas int                `json:"number_of_replicas"`
+	UseFnv           bool               `json:"use_fnv,omitempty"`
+	KetamaMode       bool               `json:"ketama_mode,omitempty"`
+	Seed             uint64             `json:"seed,omitempty"`
+	Budget           int                `json:"virtual_node_budget,omitempty"`
+	Members          map[string]float64 `json:"members"`
+	Replicas         map[string]int     `json:"replicas,omitempty"`
+}
+
+// MarshalJSON encodes the members, their weights and the hashing settings.
+// A custom Hasher cannot be encoded; see UnmarshalJSON for restoring it.
+func (c *Consistent) MarshalJSON() ([]byte, error) {
+	c.RLock()
+	defer c.RUnlock()
+	cfg := c.conf()
+	return json.Marshal(consistentJSON{
+		NumberOfReplicas: cfg.replicas,
+		UseFnv:           cfg.fnv,
+		KetamaMode:       cfg.ketama,
+		Seed:             cfg.seed,
+		Budget:           cfg.budget,
+		Members:          c.members,
+		Replicas:         c.replicas,
+	})
+}
+
+// UnmarshalJSON replaces the settings and members of c with the encoded ones
+// and rebuilds the ring. Settings that cannot be encoded, such as a custom
+// Hasher, are kept from c, so decode into a hash created with WithHasher to
+// restore one. Setting the Hasher field after decoding has no effect until
+// Rebuild is called.
+func (c *Consistent) UnmarshalJSON(data []byte) error {
+	var v consistentJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	return c.restore(v)
+}
+
+// restore replaces the settings and members of c with v and rebuilds the ring.
+// The members are applied as a diff like Set does, so departed members lose
+// their health, zone, tags, pins and leases, and every change is recorded.
+// Slow starts are cancelled so that the restored weights hold.
+func (c *Consistent) restore(v consistentJSON) error {
+	if err := validateWeights(v.Members); err != nil {
+		return err
+	}
+	for elt, n := range v.Replicas {
+		if n < 0 {
+			return fmt.Errorf("invalid replica count %d for %q", n, elt)
+		}
+	}
+	if v.NumberOfReplicas <= 0 {
+		v.NumberOfReplicas = 20
+	}
+	c.lock()
+	defer c.Unlock()
+	cfg := *c.cfg
+	cfg.replicas, cfg.fnv, cfg.ketama, cfg.seed, cfg.budget = v.NumberOfReplicas, v.UseFnv, v.KetamaMode, v.Seed, v.Budget
+	c.cfg = &cfg
+	c.NumberOfReplicas = v.NumberOfReplicas
+	c.UseFnv = v.UseFnv
+	c.KetamaMode = v.KetamaMode
+	c.Seed = v.Seed
+	c.VirtualNodeBudget = v.Budget
+	c.alloc = nil
+	c.replicas = nil
+	for elt, n := range v.Replicas {
+		if _, ok := v.Members[elt]; ok {
+			if c.replicas == nil {
+				c.replicas = make(map[string]int)
+			}
+			c.replicas[elt] = n
+		}
+	}
+	for elt := range c.ramps {
+		c.cancelRamp(elt)
+	}
+	c.set(v.Members)
+	c.stale = true
+	c.updateSortedHashes()
+	return nil
+}
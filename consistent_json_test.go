@@ -0,0 +1,103 @@
+package consistent
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestJSONRoundTrip(t *testing.T) {
+	c := New(50)
+	c.UseFnv = true
+	c.Set(map[string]float64{"Host1": 1, "Host2": 3, "Host3": 0.5})
+	data, err := json.Marshal(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var d Consistent
+	if err := json.Unmarshal(data, &d); err != nil {
+		t.Fatal(err)
+	}
+	if d.NumberOfReplicas != 50 || !d.UseFnv {
+		t.Fatalf("settings not restored: %+v", d.NumberOfReplicas)
+	}
+	for i := 0; i < 100; i++ {
+		a, _ := c.Get(fmt.Sprint(i))
+		b, _ := d.Get(fmt.Sprint(i))
+		if a != b {
+			t.Fatalf("key %d: original -> %q, restored -> %q", i, a, b)
+		}
+	}
+}
+
+func TestRestoreOverLiveRing(t *testing.T) {
+	saved := New(20)
+	saved.Set(map[string]float64{"A": 1, "B": 1, "C": 1, "Z": 1})
+	data, _ := json.Marshal(saved)
+
+	c := New(20)
+	c.HistorySize = 10
+	c.Set(map[string]float64{"A": 1, "X": 1, "Y": 1})
+	c.SetHealthy("X", false)
+	c.Drain("Y")
+	c.AddSlowStart("Z", 4, SlowStart{Duration: time.Hour, Steps: 4})
+	if err := json.Unmarshal(data, c); err != nil {
+		t.Fatal(err)
+	}
+	res, err := c.GetN("k", 4)
+	if err != nil || len(res) != 4 {
+		t.Fatalf("GetN after restore = %v, %v", res, err)
+	}
+	if down := c.snapshot().down; len(down) != 0 {
+		t.Fatalf("departed members still down: %v", down)
+	}
+	c.RLock()
+	ramping := len(c.ramps)
+	c.RUnlock()
+	if w, _ := c.Weight("Z"); w != 1 || ramping != 0 {
+		t.Fatalf("Z has weight %v with %d ramps after the restore", w, ramping)
+	}
+	var removed []string
+	for _, e := range c.History() {
+		if e.Kind == MemberRemoved {
+			removed = append(removed, e.Member)
+		}
+	}
+	slices.Sort(removed)
+	if fmt.Sprint(removed) != "[X Y]" {
+		t.Fatalf("History records removals %v", removed)
+	}
+	if !c.Equal(saved) {
+		t.Fatal("restored ring differs from the saved one")
+	}
+}
+
+func TestJSONKeepsHasher(t *testing.T) {
+	hasher := func(key string) uint32 { return crc32.ChecksumIEEE([]byte(key)) }
+	c := New(20, WithHasher(hasher))
+	c.Set(map[string]float64{"Host1": 1, "Host2": 3, "Host3": 0.5})
+	data, _ := json.Marshal(c)
+
+	d := New(20, WithHasher(hasher))
+	if err := json.Unmarshal(data, d); err != nil {
+		t.Fatal(err)
+	}
+	var late Consistent
+	if err := json.Unmarshal(data, &late); err != nil {
+		t.Fatal(err)
+	}
+	late.Hasher = hasher
+	late.Rebuild()
+	for i := 0; i < 100; i++ {
+		want, _ := c.Get(fmt.Sprint(i))
+		if got, _ := d.Get(fmt.Sprint(i)); got != want {
+			t.Fatalf("key %d: original -> %q, decoded with WithHasher -> %q", i, want, got)
+		}
+		if got, _ := late.Get(fmt.Sprint(i)); got != want {
+			t.Fatalf("key %d: original -> %q, rebuilt after decoding -> %q", i, want, got)
+		}
+	}
+}
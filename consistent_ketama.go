@@ -0,0 +1,52 @@
+package consistent
+
+import (
+	"crypto/md5"
+	"math"
+	"strconv"
+)
+
+// ketamaPointsPerServer is the number of points libketama gives a server
+// holding an average share of the total weight.
+const ketamaPointsPerServer = 40
+
+// ketamaHash hashes key the way libketama's ketama_hashi does: the first four
+// bytes of its MD5 digest read as a little-endian uint32.
+func ketamaHash(key string) uint32 {
+	d := md5.Sum([]byte(key))
+	return uint32(d[3])<<24 | uint32(d[2])<<16 | uint32(d[1])<<8 | uint32(d[0])
+}
+
+// ketamaNodeHashes returns the points of elt following libketama's
+// ketama_create_continuum. For servers of equal weight the key→server
+// mapping is checked against the ketama output of libcouchbase, which
+// places the same 160 points per server as libketama; mappings with unequal
+// weights follow the libketama source but are not checked against its
+// output. Members are expected to be named "host:port" like in those
+// libraries. NumberOfReplicas is ignored in this mode.
+//
+// need c.RLock() before calling
+func (c *Consistent) ketamaNodeHashes(elt string, wgt float64) []uint32 {
+	var total float64
+	for _, w := range c.members {
+		total += w
+	}
+	if total <= 0 {
+		return nil
+	}
+	// libketama computes the share in single precision.
+	pct := float32(wgt) / float32(total)
+	ks := int(math.Floor(float64(float32(float64(pct) * ketamaPointsPerServer * float64(len(c.members))))))
+	points := make([]uint32, 0, 4*ks)
+	for k := 0; k < ks; k++ {
+		d := md5.Sum([]byte(elt + "-" + strconv.Itoa(k)))
+		for h := 0; h < 4; h++ {
+			p := uint32(d[3+h*4])<<24 | uint32(d[2+h*4])<<16 | uint32(d[1+h*4])<<8 | uint32(d[h*4])
+			if seed := c.conf().seed; seed != 0 {
+				p = seedHash(p, seed)
+			}
+			points = append(points, p)
+		}
+	}
+	return points
+}
@@ -0,0 +1,76 @@
+package consistent
+
+import "testing"
+
+func TestKetamaMode(t *testing.T) {
+	c := New(0)
+	c.KetamaMode = true
+	c.Set(map[string]float64{"10.0.0.1:11211": 1, "10.0.0.2:11211": 1, "10.0.0.3:11211": 2})
+	counts := make(map[string]int)
+	for _, o := range c.snapshot().owners {
+		counts[o]++
+	}
+	// libketama: floor(share * 40 * servers) digests of four points each.
+	want := map[string]int{"10.0.0.1:11211": 120, "10.0.0.2:11211": 120, "10.0.0.3:11211": 240}
+	for m, n := range want {
+		if counts[m] != n {
+			t.Fatalf("%s has %d points, want %d", m, counts[m], n)
+		}
+	}
+	r := c.snapshot()
+	h := r.hashes[7]
+	if got := r.owners[r.search(h)]; got != r.owners[7] {
+		t.Fatalf("key on a point maps to %q, want %q", got, r.owners[7])
+	}
+}
+
+// TestKetamaKnownAnswers checks keys against the ketama mapping of
+// libcouchbase for four servers of equal weight, as recorded in gocbcore's
+// testdata/memd_4node.exp.json.
+func TestKetamaKnownAnswers(t *testing.T) {
+	c := New(0, WithKetamaMode())
+	for _, s := range []string{"10.0.0.195:12000", "localhost:12002", "localhost:12004", "localhost:12006"} {
+		c.Add(s, 1)
+	}
+	for _, tt := range []struct {
+		key    string
+		hash   uint32
+		server string
+	}{
+		{"Key_0", 1026020100, "10.0.0.195:12000"},
+		{"Key_37", 526392438, "10.0.0.195:12000"},
+		{"Key_74", 1418627485, "localhost:12002"},
+		{"Key_111", 1095175750, "localhost:12004"},
+		{"Key_148", 291863995, "localhost:12006"},
+		{"Key_185", 829597441, "10.0.0.195:12000"},
+		{"Key_222", 1590110205, "localhost:12002"},
+		{"Key_259", 1839374504, "localhost:12006"},
+		{"Key_296", 3096265330, "localhost:12002"},
+		{"Key_333", 1860903057, "localhost:12002"},
+		{"Key_370", 3042614878, "10.0.0.195:12000"},
+		{"Key_407", 4197228698, "10.0.0.195:12000"},
+		{"Key_444", 1697892553, "localhost:12006"},
+		{"Key_481", 1379174178, "localhost:12004"},
+		{"Key_518", 1967193684, "localhost:12004"},
+		{"Key_555", 3185190707, "10.0.0.195:12000"},
+		{"Key_592", 3331939891, "localhost:12002"},
+		{"Key_629", 59688558, "localhost:12006"},
+		{"Key_666", 2548681138, "localhost:12006"},
+		{"Key_703", 2246282685, "localhost:12004"},
+		{"Key_740", 1103600217, "localhost:12004"},
+		{"Key_777", 1844316238, "localhost:12006"},
+		{"Key_814", 4005871170, "localhost:12002"},
+		{"Key_851", 466868845, "localhost:12006"},
+		{"Key_888", 150960849, "localhost:12004"},
+		{"Key_925", 2933603849, "localhost:12002"},
+		{"Key_962", 3119131628, "localhost:12006"},
+		{"Key_999", 4132581213, "10.0.0.195:12000"},
+	} {
+		if h := ketamaHash(tt.key); h != tt.hash {
+			t.Fatalf("hash of %s = %d, want %d", tt.key, h, tt.hash)
+		}
+		if m, _ := c.Get(tt.key); m != tt.server {
+			t.Fatalf("%s maps to %s, want %s", tt.key, m, tt.server)
+		}
+	}
+}
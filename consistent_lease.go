@@ -0,0 +1,90 @@
+package consistent
+
+import "time"
+
+// leaseAfterFunc starts lease timers; tests replace it to expire leases
+// without waiting.
+var leaseAfterFunc = time.AfterFunc
+
+// lease is the time-to-live of an element added with AddWithTTL.
+type lease struct {
+	ttl   time.Duration
+	timer *time.Timer
+}
+
+// AddWithTTL inserts a string element in the consistent hash for ttl. Unless
+// Heartbeat renews it in time, the element is removed when ttl runs out, or
+// marked unhealthy if ExpireToUnhealthy is set. It returns ErrMemberExists if
+// elt is already present.
+func (c *Consistent) AddWithTTL(elt string, wgt float64, ttl time.Duration) error {
+	if err := validateWeight(wgt); err != nil {
+		return err
+	}
+	c.lock()
+	defer c.Unlock()
+	if _, ok := c.members[elt]; ok {
+		return ErrMemberExists
+	}
+	if err := c.checkMoved(adder(elt, wgt)); err != nil {
+		return err
+	}
+	c.add(elt, wgt)
+	l := &lease{ttl: ttl}
+	l.timer = leaseAfterFunc(ttl, func() { c.expire(elt, l) })
+	if c.leases == nil {
+		c.leases = make(map[string]*lease)
+	}
+	c.leases[elt] = l
+	c.updateSortedHashes()
+	return nil
+}
+
+// Heartbeat renews the lease of elt for another ttl, and puts it back into
+// service if its lease had expired with ExpireToUnhealthy set. It does not
+// undo SetHealthy(elt, false). It returns ErrMemberNotFound if elt is not
+// present. Elements added without a TTL are left untouched.
+func (c *Consistent) Heartbeat(elt string) error {
+	c.lock()
+	defer c.Unlock()
+	if _, ok := c.members[elt]; !ok {
+		return ErrMemberNotFound
+	}
+	l, ok := c.leases[elt]
+	if !ok {
+		return nil
+	}
+	l.timer.Reset(l.ttl)
+	if c.expired[elt] {
+		setEntry(c, ownExpired, &c.expired, elt, false, true)
+		c.republish()
+	}
+	return nil
+}
+
+func (c *Consistent) expire(elt string, l *lease) {
+	c.lock()
+	defer c.Unlock()
+	if c.leases[elt] != l {
+		return
+	}
+	if c.cfg.logger != nil {
+		c.cfg.logger.Info("consistent: lease expired", "member", elt, "to_unhealthy", c.cfg.expireToUnhealthy)
+	}
+	if c.cfg.expireToUnhealthy {
+		if !c.expired[elt] {
+			setEntry(c, ownExpired, &c.expired, elt, true, false)
+			c.republish()
+		}
+		return
+	}
+	c.remove(elt)
+	c.updateSortedHashes()
+}
+
+// need c.Lock() before calling
+func (c *Consistent) cancelLease(elt string) {
+	if l, ok := c.leases[elt]; ok {
+		l.timer.Stop()
+		delete(c.leases, elt)
+	}
+}
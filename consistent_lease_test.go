@@ -0,0 +1,70 @@
+package consistent
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// manualLeases makes lease timers fire only when the returned function is
+// called with the element's TTL.
+func manualLeases(t *testing.T) func(ttl time.Duration) {
+	fired := map[time.Duration]func(){}
+	leaseAfterFunc = func(d time.Duration, f func()) *time.Timer {
+		fired[d] = f
+		return time.AfterFunc(time.Hour, func() {})
+	}
+	t.Cleanup(func() { leaseAfterFunc = time.AfterFunc })
+	return func(ttl time.Duration) { fired[ttl]() }
+}
+
+func TestAddWithTTL(t *testing.T) {
+	fire := manualLeases(t)
+	c := New(20)
+	c.Add("A", 1)
+	c.AddWithTTL("B", 1, time.Second)
+	if err := c.Heartbeat("B"); err != nil {
+		t.Fatalf("Heartbeat: %v", err)
+	}
+	fire(time.Second)
+	if members := c.Members(); len(members) != 1 || members[0] != "A" {
+		t.Fatalf("members after expiry = %v, want [A]", members)
+	}
+	if err := c.Heartbeat("B"); !errors.Is(err, ErrMemberNotFound) {
+		t.Fatalf("Heartbeat after expiry: %v", err)
+	}
+
+	c.ExpireToUnhealthy = true
+	c.Rebuild()
+	c.AddWithTTL("C", 1, 2*time.Second)
+	fire(2 * time.Second)
+	if c.Healthy("C") {
+		t.Fatal("C should be unhealthy after its lease expired")
+	}
+	c.Heartbeat("C")
+	if !c.Healthy("C") {
+		t.Fatal("C should be healthy after a heartbeat")
+	}
+}
+
+func TestHeartbeatKeepsUnhealthy(t *testing.T) {
+	fire := manualLeases(t)
+	c := New(20, WithExpireToUnhealthy())
+	c.Add("A", 1)
+	c.AddWithTTL("B", 1, time.Second)
+	c.SetHealthy("B", false)
+	c.Heartbeat("B")
+	if c.Healthy("B") {
+		t.Fatal("a heartbeat marked a failed member healthy")
+	}
+
+	fire(time.Second)
+	c.SetHealthy("B", true)
+	if c.Healthy("B") {
+		t.Fatal("SetHealthy cleared an expired lease")
+	}
+	c.Heartbeat("B")
+	if !c.Healthy("B") {
+		t.Fatal("B should be healthy after a heartbeat")
+	}
+}
@@ -0,0 +1,170 @@
+package consistent
+
+import "math"
+
+// DefaultLoadFactor is the load factor used by GetWithLoad when LoadFactor
+// is not set.
+const DefaultLoadFactor = 1.25
+
+// loadTracker counts the in-flight assignments handed out by GetWithLoad and
+// keeps the loads reported through ReportLoad.
+type loadTracker struct {
+	loads    map[string]int64
+	total    int64
+	reported map[string]float64
+}
+
+// GetWithLoad returns the first element at or after where name hashes to in
+// the circle whose load stays within its bound, and counts the assignment
+// against that element until Done is called for it.
+//
+// This is consistent hashing with bounded loads: an element of weight w may
+// hold at most ceil(LoadFactor * (total+1) * w / W) assignments, where total
+// is the number of assignments in flight and W the sum of all weights. Keys
+// of an overloaded element spill over to the next elements on the ring.
+func (c *Consistent) GetWithLoad(name string) (string, error) {
+	r := c.snapshot()
+	start := r.lookupStart()
+	c.countLookup()
+	m, err := c.getWithLoad(r, name)
+	if r.observers != nil {
+		r.observeLookup("GetWithLoad", name, r.primary(name), start, err, m)
+	}
+	return m, err
+}
+
+func (c *Consistent) getWithLoad(r *ring, name string) (string, error) {
+	if len(r.hashes) == 0 {
+		return "", ErrEmptyCircle
+	}
+	var weights float64
+	for elt, w := range r.members {
+		if !r.skip(elt) {
+			weights += w
+		}
+	}
+	if weights == 0 {
+		return "", ErrNoAvailableMember
+	}
+	c.loadMu.Lock()
+	defer c.loadMu.Unlock()
+	if c.load.loads == nil {
+		c.load.loads = make(map[string]int64)
+	}
+	factor := r.loadFactor
+	if factor <= 0 {
+		factor = DefaultLoadFactor
+	}
+	factor = max(factor, 1)
+	if elt, ok := r.pinned(name); ok {
+		c.load.loads[elt]++
+		c.load.total++
+		return elt, nil
+	}
+	start := r.search(r.hash(name))
+	for k := 0; k < len(r.owners); k++ {
+		elt := r.owners[(start+k)%len(r.owners)]
+		if r.skip(elt) {
+			continue
+		}
+		bound := math.Ceil(factor * float64(c.load.total+1) * r.members[elt] / weights)
+		if float64(c.load.loads[elt]+1) <= bound {
+			c.load.loads[elt]++
+			c.load.total++
+			return elt, nil
+		}
+	}
+	// Unreachable with a load factor of at least 1, but never fail a lookup
+	// because of load accounting.
+	elt, err := r.getOne(name)
+	if err != nil {
+		return "", err
+	}
+	c.load.loads[elt]++
+	c.load.total++
+	return elt, nil
+}
+
+// Done releases an assignment made by GetWithLoad for member.
+func (c *Consistent) Done(member string) {
+	c.loadMu.Lock()
+	defer c.loadMu.Unlock()
+	if c.load.loads[member] <= 0 {
+		return
+	}
+	c.load.loads[member]--
+	c.load.total--
+	if c.load.loads[member] == 0 {
+		delete(c.load.loads, member)
+	}
+}
+
+// Loads returns the number of in-flight GetWithLoad assignments per element.
+func (c *Consistent) Loads() map[string]int64 {
+	c.loadMu.Lock()
+	defer c.loadMu.Unlock()
+	m := make(map[string]int64, len(c.load.loads))
+	for k, v := range c.load.loads {
+		m[k] = v
+	}
+	return m
+}
+
+// ReportLoad records the latest load reported for member, as used by
+// GetLeastLoaded. The unit is up to the caller, e.g. queue depth or CPU usage.
+func (c *Consistent) ReportLoad(member string, load float64) {
+	c.loadMu.Lock()
+	defer c.loadMu.Unlock()
+	if c.load.reported == nil {
+		c.load.reported = make(map[string]float64)
+	}
+	c.load.reported[member] = load
+}
+
+// GetLeastLoaded returns, among the n closest distinct elements to where name
+// hashes to in the circle, the one with the lowest load recorded by
+// ReportLoad. Elements without a report count as unloaded, and ties go to the
+// element closest on the ring, so with equal loads the result matches Get.
+func (c *Consistent) GetLeastLoaded(name string, n int) (string, error) {
+	return c.getLeastLoaded("GetLeastLoaded", name, n, func(member string) float64 {
+		c.loadMu.Lock()
+		defer c.loadMu.Unlock()
+		return c.load.reported[member]
+	})
+}
+
+// GetLeastLoadedFunc is like GetLeastLoaded but asks load for the current
+// load of each candidate.
+func (c *Consistent) GetLeastLoadedFunc(name string, n int, load func(member string) float64) (string, error) {
+	return c.getLeastLoaded("GetLeastLoadedFunc", name, n, load)
+}
+
+func (c *Consistent) getLeastLoaded(op, name string, n int, load func(member string) float64) (string, error) {
+	r := c.snapshot()
+	start := r.lookupStart()
+	c.countLookup()
+	m, err := r.leastLoaded(name, n, load)
+	if r.observers != nil {
+		r.observeLookup(op, name, r.primary(name), start, err, m)
+	}
+	return m, err
+}
+
+// leastLoaded returns the element with the lowest load among the n closest
+// available elements to name.
+func (r *ring) leastLoaded(name string, n int, load func(member string) float64) (string, error) {
+	candidates, err := r.getNExcluding(name, max(n, 1), nil)
+	if len(candidates) == 0 {
+		if err == nil {
+			err = ErrNoAvailableMember
+		}
+		return "", err
+	}
+	best, bestLoad := "", 0.0
+	for i, m := range candidates {
+		if l := load(m); i == 0 || l < bestLoad {
+			best, bestLoad = m, l
+		}
+	}
+	return best, nil
+}
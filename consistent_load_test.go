@@ -0,0 +1,75 @@
+package consistent
+
+import "testing"
+
+func TestGetWithLoad(t *testing.T) {
+	c := New(50)
+	c.LoadFactor = 1.25
+	c.Set(map[string]float64{"Host1": 1, "Host2": 1, "Host3": 2})
+	for i := 0; i < 400; i++ {
+		// Every key hashes to the same point, so only the bound spreads them.
+		if _, err := c.GetWithLoad("hot"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	loads := c.Loads()
+	for m, bound := range map[string]int64{"Host1": 125, "Host2": 125, "Host3": 250} {
+		if loads[m] > bound {
+			t.Fatalf("%s has load %d, bound %d", m, loads[m], bound)
+		}
+	}
+	c.Done("Host1")
+	if c.Loads()["Host1"] != loads["Host1"]-1 {
+		t.Fatal("Done did not release the assignment")
+	}
+	o := new(recordingObserver)
+	c.AddObserver(o)
+	before := c.Stats().Lookups
+	m, _ := c.GetWithLoad("hot")
+	if c.Stats().Lookups != before+1 || len(o.lookups) != 1 || o.lookups[0].Op != "GetWithLoad" || o.lookups[0].Members[0] != m {
+		t.Fatalf("GetWithLoad not counted or observed: %v", o.lookups)
+	}
+}
+
+func TestGetWithLoadFactorBelowOne(t *testing.T) {
+	c := New(50, WithLoadFactor(0.5))
+	c.Set(map[string]float64{"Host1": 1, "Host2": 1, "Host3": 2})
+	for i := 0; i < 400; i++ {
+		if _, err := c.GetWithLoad("hot"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	loads := c.Loads()
+	for m, want := range map[string]int64{"Host1": 100, "Host2": 100, "Host3": 200} {
+		if loads[m] != want {
+			t.Fatalf("%s has load %d with a load factor raised to 1, want %d", m, loads[m], want)
+		}
+	}
+}
+
+func TestGetLeastLoaded(t *testing.T) {
+	c := New(20)
+	c.Set(map[string]float64{"Host1": 1, "Host2": 1, "Host3": 1})
+	cands, _ := c.GetN("k", 2)
+	if m, _ := c.GetLeastLoaded("k", 2); m != cands[0] {
+		t.Fatalf("without reports got %q, want %q", m, cands[0])
+	}
+	c.ReportLoad(cands[0], 10)
+	c.ReportLoad(cands[1], 3)
+	if m, _ := c.GetLeastLoaded("k", 2); m != cands[1] {
+		t.Fatalf("got %q, want less loaded %q", m, cands[1])
+	}
+
+	o := new(recordingObserver)
+	c.AddObserver(o)
+	before := c.Stats().Lookups
+	c.GetLeastLoaded("k", 2)
+	c.GetLeastLoadedFunc("k", 2, func(string) float64 { return 0 })
+	if n := c.Stats().Lookups - before; n != 2 {
+		t.Fatalf("%d lookups counted, want 2", n)
+	}
+	if len(o.lookups) != 2 || o.lookups[0].Op != "GetLeastLoaded" || o.lookups[0].Members[0] != cands[1] ||
+		!o.lookups[0].Fallback || o.lookups[1].Op != "GetLeastLoadedFunc" || o.lookups[1].Fallback {
+		t.Fatalf("lookups = %+v", o.lookups)
+	}
+}
@@ -0,0 +1,33 @@
+package consistent
+
+// Logger is the structured logger Consistent reports membership changes
+// to. *slog.Logger satisfies it.
+type Logger interface {
+	Info(msg string, args ...any)
+}
+
+var changeMessages = [...]string{
+	MemberAdded:   "consistent: member added",
+	MemberRemoved: "consistent: member removed",
+	WeightChanged: "consistent: weight changed",
+}
+
+// logChanges logs the pending changes with the percentage of the hash space
+// the member owned on old and owns on r.
+//
+// need c.Lock() before calling
+func (c *Consistent) logChanges(old, r *ring) {
+	if c.cfg.logger == nil || len(c.changes) == 0 {
+		return
+	}
+	before, after := old.ownership(), r.ownership()
+	for _, ch := range c.changes {
+		c.cfg.logger.Info(changeMessages[ch.Kind],
+			"member", ch.Member,
+			"old_weight", ch.OldWeight,
+			"new_weight", ch.NewWeight,
+			"ownership_before_pct", before[ch.Member]*100,
+			"ownership_after_pct", after[ch.Member]*100,
+		)
+	}
+}
@@ -0,0 +1,54 @@
+package consistent
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	c := New(50)
+	c.Logger = slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+	c.Add("A", 1)
+	c.Add("B", 1)
+	c.UpdateWeight("B", 3)
+	c.Remove("A")
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("logged %q", lines)
+	}
+	for i, want := range []string{
+		`msg="consistent: member added" member=A old_weight=0 new_weight=1 ownership_before_pct=0 ownership_after_pct=100`,
+		`msg="consistent: member added" member=B`,
+		`msg="consistent: weight changed" member=B old_weight=1 new_weight=3`,
+		`msg="consistent: member removed" member=A old_weight=1 new_weight=0`,
+	} {
+		if !strings.Contains(lines[i], want) {
+			t.Fatalf("line %d = %q, want %q", i, lines[i], want)
+		}
+	}
+	if !strings.HasSuffix(lines[3], "ownership_after_pct=0") {
+		t.Fatalf("removal logged %q", lines[3])
+	}
+
+	buf.Reset()
+	c.AddWithTTL("C", 1, time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	c.RLock() // the expiry logs from its timer goroutine under the lock
+	logged := buf.String()
+	c.RUnlock()
+	if !strings.Contains(logged, `msg="consistent: lease expired" member=C to_unhealthy=false`) ||
+		!strings.Contains(logged, `msg="consistent: member removed" member=C`) {
+		t.Fatalf("expiry logged %q", logged)
+	}
+}
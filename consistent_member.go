@@ -0,0 +1,280 @@
+package consistent
+
+import (
+	"fmt"
+	"slices"
+)
+
+// AddWithMeta inserts a string element in the consistent hash with metadata,
+// such as its address or capacity, that GetWithMeta returns along with it.
+// It returns ErrMemberExists if elt is already present.
+func (c *Consistent) AddWithMeta(elt string, wgt float64, meta map[string]string) error {
+	if err := validateWeight(wgt); err != nil {
+		return err
+	}
+	c.lock()
+	defer c.Unlock()
+	if _, ok := c.members[elt]; ok {
+		return ErrMemberExists
+	}
+	if err := c.checkMoved(adder(elt, wgt)); err != nil {
+		return err
+	}
+	c.add(elt, wgt)
+	c.setMeta(elt, meta)
+	c.updateSortedHashes()
+	return nil
+}
+
+// GetWithMeta returns an element close to where name hashes to in the circle
+// together with its metadata. The returned map must not be modified.
+func (c *Consistent) GetWithMeta(name string) (string, map[string]string, error) {
+	r := c.snapshot()
+	start := r.lookupStart()
+	c.countLookup()
+	elt, err := r.getOne(name)
+	if r.observers != nil {
+		r.observeLookup("GetWithMeta", name, r.primary(name), start, err, elt)
+	}
+	return elt, r.meta[elt], err
+}
+
+// Meta returns the metadata attached to elt, and whether elt is present.
+// The returned map must not be modified.
+func (c *Consistent) Meta(elt string) (map[string]string, bool) {
+	r := c.snapshot()
+	_, ok := r.members[elt]
+	return r.meta[elt], ok
+}
+
+// AddMember inserts m in the consistent hash, recording its zone for
+// zone-aware lookups and its tags. It returns ErrMemberExists if m.Name is
+// already present.
+func (c *Consistent) AddMember(m Member) error {
+	if err := validateWeight(m.Weight); err != nil {
+		return err
+	}
+	c.lock()
+	defer c.Unlock()
+	if _, ok := c.members[m.Name]; ok {
+		return ErrMemberExists
+	}
+	if err := c.checkMoved(adder(m.Name, m.Weight)); err != nil {
+		return err
+	}
+	c.add(m.Name, m.Weight)
+	c.setZone(m.Name, m.Zone)
+	c.setTags(m.Name, m.Tags)
+	c.updateSortedHashes()
+	return nil
+}
+
+// SetMembers is like Set, and also replaces the zones and tags of all the
+// elements with those of members, in a single rebuild. Nothing changes if a
+// weight is invalid or a name appears twice.
+func (c *Consistent) SetMembers(members []Member) error {
+	eltMap := make(map[string]float64, len(members))
+	zones := make(map[string]string)
+	tags := make(map[string][]string)
+	for _, m := range members {
+		if _, ok := eltMap[m.Name]; ok {
+			return fmt.Errorf("%w: %q listed twice", ErrMemberExists, m.Name)
+		}
+		eltMap[m.Name] = m.Weight
+		if m.Zone != "" {
+			zones[m.Name] = m.Zone
+		}
+		if len(m.Tags) > 0 {
+			tags[m.Name] = slices.Clone(m.Tags)
+		}
+	}
+	if err := validateWeights(eltMap); err != nil {
+		return err
+	}
+	c.lock()
+	defer c.Unlock()
+	if err := c.checkMoved(c.setter(eltMap)); err != nil {
+		return err
+	}
+	c.set(eltMap)
+	c.zones, c.tags = zones, tags
+	c.updateSortedHashes()
+	return nil
+}
+
+// Zone returns the zone of elt, and whether elt is present.
+func (c *Consistent) Zone(elt string) (string, bool) {
+	r := c.snapshot()
+	_, ok := r.members[elt]
+	return r.zones[elt], ok
+}
+
+// GetNDistinctZones returns n elements close to where name hashes to in the
+// circle, taking at most one element per zone, so that replicas placed on
+// them survive the loss of a zone. Elements without a zone share the empty
+// zone. If fewer than n zones have an available element it returns one
+// element per such zone with ErrInsufficientMembers.
+func (c *Consistent) GetNDistinctZones(name string, n int) ([]string, error) {
+	r := c.snapshot()
+	start := r.lookupStart()
+	c.countLookup()
+	res, err := r.getNDistinctZones(name, n)
+	err = atLeast(res, n, err)
+	if r.observers != nil {
+		r.observeLookup("GetNDistinctZones", name, r.primary(name), start, err, res...)
+	}
+	return res, err
+}
+
+func (r *ring) getNDistinctZones(name string, n int) ([]string, error) {
+	if len(r.hashes) == 0 {
+		return nil, ErrEmptyCircle
+	}
+	var res, zones []string
+	if elt, ok := r.pinned(name); ok && n > 0 {
+		res, zones = append(res, elt), append(zones, r.zones[elt])
+	}
+	start := r.search(r.hash(name))
+	for k := 0; k < len(r.owners) && len(res) < n; k++ {
+		elt := r.owners[(start+k)%len(r.owners)]
+		zone := r.zones[elt]
+		if r.skip(elt) || sliceContainsMember(zones, zone) {
+			continue
+		}
+		res = append(res, elt)
+		zones = append(zones, zone)
+	}
+	return res, nil
+}
+
+// ReplicaSet returns the owner of name as primary and up to n-1 backups, the
+// next distinct elements on the circle. The backups of a key only change when
+// an element joins, leaves or goes down between it and the last backup. With
+// ZoneSpread set, backups are taken from unused zones before any zone is
+// reused.
+func (c *Consistent) ReplicaSet(name string, n int) (primary string, backups []string, err error) {
+	r := c.snapshot()
+	start := r.lookupStart()
+	c.countLookup()
+	res, err := r.replicaSet(name, max(n, 1))
+	if r.observers != nil {
+		r.observeLookup("ReplicaSet", name, r.primary(name), start, err, res...)
+	}
+	if len(res) == 0 {
+		return "", nil, err
+	}
+	return res[0], res[1:], err
+}
+
+func (r *ring) replicaSet(name string, n int) ([]string, error) {
+	if len(r.hashes) == 0 {
+		return nil, ErrEmptyCircle
+	}
+	var res []string
+	if r.zoneSpread {
+		res, _ = r.getNDistinctZones(name, n)
+	}
+	var err error
+	if len(res) < n {
+		var more []string
+		more, err = r.candidates(name, n-len(res), res)
+		res = append(res, more...)
+	}
+	if len(res) == 0 && err == nil {
+		err = ErrNoAvailableMember
+	}
+	return res, err
+}
+
+// Tags returns a copy of the tags elt was added with.
+func (c *Consistent) Tags(elt string) []string {
+	return slices.Clone(c.snapshot().tags[elt])
+}
+
+// GetWithTags returns the first element at or after where name hashes to in
+// the circle that carries every tag in required. It returns
+// ErrNoMatchingMember if no element does.
+func (c *Consistent) GetWithTags(name string, required []string) (string, error) {
+	r := c.snapshot()
+	start := r.lookupStart()
+	c.countLookup()
+	m, err := r.getFiltered(name, func(member string) bool {
+		for _, tag := range required {
+			if !slices.Contains(r.tags[member], tag) {
+				return false
+			}
+		}
+		return true
+	})
+	if r.observers != nil {
+		r.observeLookup("GetWithTags", name, r.primary(name), start, err, m)
+	}
+	return m, err
+}
+
+// setMeta attaches meta to elt, or detaches it when meta is empty.
+//
+// need c.Lock() before calling
+func (c *Consistent) setMeta(elt string, meta map[string]string) {
+	if len(meta) == 0 {
+		setEntry(c, ownMeta, &c.meta, elt, nil, true)
+		return
+	}
+	copied := make(map[string]string, len(meta))
+	for k, v := range meta {
+		copied[k] = v
+	}
+	setEntry(c, ownMeta, &c.meta, elt, copied, false)
+}
+
+// setZone records the zone of elt, or forgets it when zone is empty.
+//
+// need c.Lock() before calling
+func (c *Consistent) setZone(elt, zone string) {
+	setEntry(c, ownZones, &c.zones, elt, zone, zone == "")
+}
+
+// setTags records the tags of elt, or forgets them when tags is empty.
+//
+// need c.Lock() before calling
+func (c *Consistent) setTags(elt string, tags []string) {
+	setEntry(c, ownTags, &c.tags, elt, slices.Clone(tags), len(tags) == 0)
+}
+
+// The copy-on-write maps of a Consistent, as bits of Consistent.owned.
+const (
+	ownMeta uint8 = 1 << iota
+	ownZones
+	ownTags
+	ownUnhealthy
+	ownDrained
+	ownExpired
+	ownPins
+	ownReplicas
+)
+
+// setEntry sets k to v in *m, or removes it when del is true. The maps are
+// shared with published rings and copies, so the first change to *m after
+// the lock is taken or a ring is published replaces it with a private copy,
+// recorded in c.owned under bit; later changes in the same batch reuse that
+// copy.
+//
+// need c.Lock() before calling
+func setEntry[V any](c *Consistent, bit uint8, m *map[string]V, k string, v V, del bool) {
+	if _, ok := (*m)[k]; !ok && del {
+		return
+	}
+	if c.owned&bit == 0 {
+		next := make(map[string]V, len(*m)+1)
+		for key, val := range *m {
+			next[key] = val
+		}
+		*m = next
+		c.owned |= bit
+	}
+	if del {
+		delete(*m, k)
+	} else {
+		(*m)[k] = v
+	}
+}
@@ -0,0 +1,136 @@
+package consistent
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func TestMemberMeta(t *testing.T) {
+	c := New(20)
+	c.AddWithMeta("Host1", 1, map[string]string{"addr": "10.0.0.1:80"})
+	m, meta, err := c.GetWithMeta("k")
+	if err != nil || m != "Host1" || meta["addr"] != "10.0.0.1:80" {
+		t.Fatalf("GetWithMeta = %q, %v, %v", m, meta, err)
+	}
+	c.Remove("Host1")
+	if meta, ok := c.Meta("Host1"); ok || meta != nil {
+		t.Fatalf("metadata kept after Remove: %v", meta)
+	}
+}
+
+func TestGetNDistinctZones(t *testing.T) {
+	c := New(20)
+	for i, zone := range []string{"a", "a", "b", "b", "c"} {
+		c.AddMember(Member{Name: fmt.Sprintf("Host%d", i), Weight: 1, Zone: zone})
+	}
+	for i := 0; i < 50; i++ {
+		res, err := c.GetNDistinctZones(fmt.Sprint(i), 3)
+		if err != nil || len(res) != 3 {
+			t.Fatalf("GetNDistinctZones = %v, %v", res, err)
+		}
+		zones := map[string]bool{}
+		for _, m := range res {
+			z, _ := c.Zone(m)
+			zones[z] = true
+		}
+		if len(zones) != 3 {
+			t.Fatalf("replicas %v share a zone", res)
+		}
+	}
+}
+
+func TestGetWithTags(t *testing.T) {
+	c := New(20)
+	c.Add("cpu1", 1)
+	c.Add("gpu1", 1, "gpu")
+	c.Add("gpu2", 1, "gpu", "ssd")
+	for i := 0; i < 50; i++ {
+		m, err := c.GetWithTags(fmt.Sprint(i), []string{"gpu", "ssd"})
+		if err != nil || m != "gpu2" {
+			t.Fatalf("GetWithTags = %q, %v, want gpu2", m, err)
+		}
+	}
+	if _, err := c.GetWithTags("key", []string{"tpu"}); !errors.Is(err, ErrNoMatchingMember) {
+		t.Fatalf("GetWithTags with unknown tag: %v", err)
+	}
+	c.Remove("gpu2")
+	if tags := c.Tags("gpu2"); tags != nil {
+		t.Fatalf("Tags after Remove = %v", tags)
+	}
+}
+
+func TestReplicaSet(t *testing.T) {
+	c := New(20)
+	c.ZoneSpread = true
+	for i, zone := range []string{"a", "a", "a", "b"} {
+		c.AddMember(Member{Name: fmt.Sprintf("Host%d", i), Weight: 1, Zone: zone})
+	}
+	for i := 0; i < 50; i++ {
+		key := fmt.Sprint(i)
+		primary, backups, err := c.ReplicaSet(key, 3)
+		if err != nil || len(backups) != 2 {
+			t.Fatalf("ReplicaSet(%q) = %q, %v, %v", key, primary, backups, err)
+		}
+		if owner, _ := c.Get(key); primary != owner {
+			t.Fatalf("primary of %q = %q, want %q", key, primary, owner)
+		}
+		if !sliceContainsMember(append(backups, primary), "Host3") {
+			t.Fatalf("replicas of %q = %q, %v, want one in zone b", key, primary, backups)
+		}
+	}
+}
+
+func TestSetMembers(t *testing.T) {
+	c := New(20)
+	c.AddMember(Member{Name: "A", Weight: 1, Zone: "z1", Tags: []string{"ssd"}})
+	if tags := c.Tags("A"); !slices.Equal(tags, []string{"ssd"}) {
+		t.Fatalf("tags of A = %v", tags)
+	}
+	err := c.SetMembers([]Member{{Name: "B", Weight: 1}, {Name: "B", Weight: 2}})
+	if !errors.Is(err, ErrMemberExists) {
+		t.Fatalf("duplicate names: %v", err)
+	}
+	if err := c.SetMembers([]Member{{Name: "A", Weight: 2}, {Name: "B", Weight: 1, Zone: "z2", Tags: []string{"hdd"}}}); err != nil {
+		t.Fatal(err)
+	}
+	if z, _ := c.Zone("A"); z != "" || len(c.Tags("A")) != 0 {
+		t.Fatalf("A kept zone %q and tags %v", z, c.Tags("A"))
+	}
+	if z, _ := c.Zone("B"); z != "z2" {
+		t.Fatalf("zone of B = %q", z)
+	}
+	if m, err := c.GetWithTags("k", []string{"hdd"}); err != nil || m != "B" {
+		t.Fatalf("GetWithTags = %q, %v", m, err)
+	}
+	if infos := c.MemberInfos(); infos[0].Weight != 2 {
+		t.Fatalf("members %+v", infos)
+	}
+}
+
+func TestBatchKeepsPublishedMaps(t *testing.T) {
+	c := New(5)
+	var members []Member
+	for i := 0; i < 50; i++ {
+		members = append(members, Member{Name: fmt.Sprint("node", i), Weight: 1, Zone: fmt.Sprint("z", i%3), Tags: []string{"t"}})
+	}
+	c.SetMembers(members)
+	r := c.snapshot()
+	clone := c.Clone()
+	var gone []string
+	for _, m := range members[:40] {
+		gone = append(gone, m.Name)
+	}
+	c.RemoveMany(gone)
+	if len(r.zones) != 50 || len(r.tags) != 50 || len(clone.snapshot().zones) != 50 {
+		t.Fatalf("earlier ring lost zones: %d, tags %d", len(r.zones), len(r.tags))
+	}
+	if z := c.snapshot().zones; len(z) != 10 || z["node45"] != "z0" {
+		t.Fatalf("zones after RemoveMany = %v", z)
+	}
+	c.SetMembers(members[:20])
+	if z := c.snapshot().zones; len(z) != 20 || z["node10"] != "z1" {
+		t.Fatalf("zones after SetMembers = %v", z)
+	}
+}
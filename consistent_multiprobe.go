@@ -0,0 +1,88 @@
+package consistent
+
+import (
+	"slices"
+	"sort"
+)
+
+// DefaultProbes is the number of probes per key used by NewMultiProbeRing
+// when none is given; 21 probes keep the peak-to-mean load near 1.05.
+const DefaultProbes = 21
+
+// MultiProbeRing is a ring using multi-probe consistent hashing: each member
+// has a single point on the ring, and a key is hashed several times and
+// mapped to the member whose point follows one of the probes most closely,
+// relative to the member's weight. Memory is proportional to the number of
+// members rather than members times replicas, which suits very large rings.
+//
+// A MultiProbeRing is immutable; build a new one when the members change.
+type MultiProbeRing struct {
+	points  []uint64
+	owners  []string
+	weights []float64
+	probes  int
+}
+
+// NewMultiProbeRing builds a ring of members, hashing keys probes times. A
+// probes of 0 or less selects DefaultProbes. Members with a weight of 0 or
+// less are left out.
+func NewMultiProbeRing(members []Member, probes int) *MultiProbeRing {
+	if probes <= 0 {
+		probes = DefaultProbes
+	}
+	type entry struct {
+		point  uint64
+		owner  string
+		weight float64
+	}
+	entries := make([]entry, 0, len(members))
+	for _, m := range members {
+		if m.Weight > 0 {
+			entries = append(entries, entry{xxhash64(m.Name), m.Name, m.Weight})
+		}
+	}
+	// Ties between equal points go to the smallest name, as on Consistent.
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].point != entries[j].point {
+			return entries[i].point < entries[j].point
+		}
+		return entries[i].owner < entries[j].owner
+	})
+	r := &MultiProbeRing{probes: probes}
+	for _, e := range entries {
+		if n := len(r.points); n > 0 && r.points[n-1] == e.point {
+			continue
+		}
+		r.points = append(r.points, e.point)
+		r.owners = append(r.owners, e.owner)
+		r.weights = append(r.weights, e.weight)
+	}
+	return r
+}
+
+// Get returns the member key maps to.
+func (r *MultiProbeRing) Get(key string) (string, error) {
+	if len(r.points) == 0 {
+		return "", ErrEmptyCircle
+	}
+	h := xxhash64(key)
+	step := mix64(h) | 1
+	best, bestScore := 0, 0.0
+	for i := 0; i < r.probes; i++ {
+		probe := h + uint64(i)*step
+		j, ok := slices.BinarySearch(r.points, probe)
+		if !ok && j == len(r.points) {
+			j = 0
+		}
+		score := float64(r.points[j]-probe) / r.weights[j]
+		if i == 0 || score < bestScore {
+			best, bestScore = j, score
+		}
+	}
+	return r.owners[best], nil
+}
+
+// Len returns the number of members on the ring.
+func (r *MultiProbeRing) Len() int {
+	return len(r.points)
+}
@@ -0,0 +1,31 @@
+package consistent
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestMultiProbeRing(t *testing.T) {
+	var members []Member
+	for i := 0; i < 10; i++ {
+		members = append(members, Member{Name: fmt.Sprintf("Host%d", i), Weight: 1})
+	}
+	members[0].Weight = 3
+	r := NewMultiProbeRing(members, 0)
+	if r.Len() != 10 {
+		t.Fatalf("Len = %d", r.Len())
+	}
+	counts := map[string]int{}
+	for i := 0; i < 12000; i++ {
+		m, _ := r.Get(fmt.Sprint(i))
+		counts[m]++
+	}
+	if counts["Host0"] < 2*counts["Host1"] {
+		t.Fatalf("weight 3 member got %d keys, weight 1 member %d", counts["Host0"], counts["Host1"])
+	}
+	for m, n := range counts {
+		if m != "Host0" && (n < 600 || n > 1500) {
+			t.Fatalf("%s got %d of 12000 keys, want about 1000", m, n)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package consistent
+
+import (
+	"context"
+	"time"
+)
+
+// Observer receives notifications about lookups and membership changes of a
+// Consistent. Observers are called synchronously: ObserveChange runs while
+// the ring is locked and must not mutate it.
+type Observer interface {
+	// ObserveLookup is called after every lookup.
+	ObserveLookup(l Lookup)
+	// ObserveChange is called after a membership change has been published.
+	ObserveChange(ch Change)
+}
+
+// Lookup describes a completed lookup.
+type Lookup struct {
+	// Op is the name of the lookup method, e.g. "Get" or "GetN".
+	Op  string
+	Key string
+	// Members are the selected elements, in order.
+	Members  []string
+	Err      error
+	Duration time.Duration
+	// RingSize is the number of virtual nodes on the ring that served the lookup.
+	RingSize int
+	// Fallback reports whether the first selected element is not the one
+	// the key maps to with every element available, because that element
+	// was down or filtered out.
+	Fallback bool
+	// Context is the context passed to GetContext or GetNContext, and nil
+	// for lookups without one.
+	Context context.Context
+}
+
+// ChangeKind is the kind of a membership change.
+type ChangeKind int
+
+const (
+	MemberAdded ChangeKind = iota
+	MemberRemoved
+	WeightChanged
+)
+
+// String returns the name of the change kind.
+func (k ChangeKind) String() string {
+	switch k {
+	case MemberAdded:
+		return "add"
+	case MemberRemoved:
+		return "remove"
+	case WeightChanged:
+		return "update_weight"
+	}
+	return "unknown"
+}
+
+// Change describes a membership change. OldWeight is 0 for additions and
+// NewWeight is 0 for removals.
+type Change struct {
+	Kind      ChangeKind
+	Member    string
+	OldWeight float64
+	NewWeight float64
+}
+
+// AddObserver registers o to be notified of lookups and membership changes.
+func (c *Consistent) AddObserver(o Observer) {
+	c.lock()
+	defer c.Unlock()
+	c.observers = append(c.observers[:len(c.observers):len(c.observers)], o)
+	r := *c.snapshot()
+	r.observers = c.observers
+	c.ring.Store(&r)
+}
+
+// need c.Lock() before calling
+func (c *Consistent) recordChange(kind ChangeKind, elt string, oldWgt, newWgt float64) {
+	if c.observers != nil || c.cfg.logger != nil || c.cfg.historySize > 0 {
+		c.changes = append(c.changes, Change{Kind: kind, Member: elt, OldWeight: oldWgt, NewWeight: newWgt})
+	}
+}
+
+// need c.Lock() before calling
+func (c *Consistent) notifyChanges() {
+	for _, ch := range c.changes {
+		for _, o := range c.observers {
+			o.ObserveChange(ch)
+		}
+	}
+	c.changes = c.changes[:0]
+}
+
+// primary returns the element a lookup of key selects when every element is
+// available.
+func (r *ring) primary(key string) string {
+	if elt, ok := r.pinned(key); ok {
+		return elt
+	}
+	return r.owner(r.hash(key))
+}
+
+// owner returns the owner of the first virtual node at or after the key hash
+// h, available or not.
+func (r *ring) owner(h uint32) string {
+	if len(r.hashes) == 0 {
+		return ""
+	}
+	return r.owners[r.search(h)]
+}
+
+// lookupStart returns the start time of a lookup that will be observed.
+func (r *ring) lookupStart() time.Time {
+	if r.observers == nil {
+		return time.Time{}
+	}
+	return time.Now()
+}
+
+// observeLookup reports a lookup of key to the observers. primary is the
+// element the lookup selects when every element is available, which the call
+// site computes from the key it hashed.
+func (r *ring) observeLookup(op, key, primary string, start time.Time, err error, members ...string) {
+	r.notifyLookup(r.lookup(op, key, primary, start, err, members...))
+}
+
+// lookup describes a lookup for observeLookup.
+func (r *ring) lookup(op, key, primary string, start time.Time, err error, members ...string) Lookup {
+	l := Lookup{
+		Op:       op,
+		Key:      key,
+		Members:  members,
+		Err:      err,
+		Duration: time.Since(start),
+		RingSize: len(r.hashes),
+	}
+	if len(members) > 0 && members[0] != "" {
+		l.Fallback = members[0] != primary
+	}
+	return l
+}
+
+// notifyLookup passes l to the observers.
+func (r *ring) notifyLookup(l Lookup) {
+	for _, o := range r.observers {
+		o.ObserveLookup(l)
+	}
+}
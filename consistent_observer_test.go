@@ -0,0 +1,59 @@
+package consistent
+
+import (
+	"fmt"
+	"testing"
+)
+
+type recordingObserver struct {
+	lookups []Lookup
+	changes []Change
+}
+
+func (o *recordingObserver) ObserveLookup(l Lookup)  { o.lookups = append(o.lookups, l) }
+func (o *recordingObserver) ObserveChange(ch Change) { o.changes = append(o.changes, ch) }
+
+func TestObserver(t *testing.T) {
+	c := New(20)
+	o := new(recordingObserver)
+	c.AddObserver(o)
+	c.Add("Host1", 1)
+	c.UpdateWeight("Host1", 2)
+	c.Remove("Host1")
+	want := []Change{{MemberAdded, "Host1", 0, 1}, {WeightChanged, "Host1", 1, 2}, {MemberRemoved, "Host1", 2, 0}}
+	if fmt.Sprint(o.changes) != fmt.Sprint(want) {
+		t.Fatalf("changes = %v, want %v", o.changes, want)
+	}
+	c.Add("Host2", 1)
+	c.Get("k")
+	if len(o.lookups) != 1 || o.lookups[0].Op != "Get" || o.lookups[0].Members[0] != "Host2" || o.lookups[0].Fallback {
+		t.Fatalf("lookups = %+v", o.lookups)
+	}
+	c.Add("Host3", 1)
+	keys := map[string]string{}
+	for i := 0; len(keys) < 2; i++ {
+		m, _ := c.Get(fmt.Sprint(i))
+		keys[m] = fmt.Sprint(i)
+	}
+	c.SetHealthy("Host2", false)
+	o.lookups = nil
+	c.Get(keys["Host2"])
+	c.Get(keys["Host3"])
+	if !o.lookups[0].Fallback || o.lookups[0].Members[0] != "Host3" || o.lookups[1].Fallback {
+		t.Fatalf("lookups = %+v", o.lookups)
+	}
+
+	c.SetHealthy("Host2", true)
+	ints := map[string]uint64{}
+	for i := uint64(0); len(ints) < 2; i++ {
+		m, _ := c.GetUint64(i)
+		ints[m] = i
+	}
+	c.SetHealthy("Host2", false)
+	o.lookups = nil
+	c.GetUint64(ints["Host2"])
+	c.GetNUint64(ints["Host3"], 1)
+	if !o.lookups[0].Fallback || o.lookups[1].Fallback {
+		t.Fatalf("uint64 lookups = %+v", o.lookups)
+	}
+}
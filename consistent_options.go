@@ -0,0 +1,84 @@
+package consistent
+
+// Option configures a Consistent created by New. Each option sets the field
+// of the same name, documented on Consistent.
+type Option func(c *Consistent)
+
+// WithReplicas sets NumberOfReplicas, overriding the count passed to New.
+func WithReplicas(n int) Option {
+	return func(c *Consistent) {
+		if n > 0 {
+			c.NumberOfReplicas = n
+		}
+	}
+}
+
+// WithFnv hashes with FNV-1a instead of CRC32.
+func WithFnv() Option {
+	return func(c *Consistent) { c.UseFnv = true }
+}
+
+// WithHasher sets Hasher.
+func WithHasher(hasher func(key string) uint32) Option {
+	return func(c *Consistent) { c.Hasher = hasher }
+}
+
+// WithUint64Hasher sets Uint64Hasher.
+func WithUint64Hasher(hasher func(key uint64) uint32) Option {
+	return func(c *Consistent) { c.Uint64Hasher = hasher }
+}
+
+// WithSeed sets Seed.
+func WithSeed(seed uint64) Option {
+	return func(c *Consistent) { c.Seed = seed }
+}
+
+// WithKetamaMode sets KetamaMode.
+func WithKetamaMode() Option {
+	return func(c *Consistent) { c.KetamaMode = true }
+}
+
+// WithVirtualNodeBudget sets VirtualNodeBudget.
+func WithVirtualNodeBudget(budget int) Option {
+	return func(c *Consistent) { c.VirtualNodeBudget = budget }
+}
+
+// WithMaxTraversal sets MaxTraversal.
+func WithMaxTraversal(n int) Option {
+	return func(c *Consistent) { c.MaxTraversal = n }
+}
+
+// WithLoadFactor sets LoadFactor.
+func WithLoadFactor(f float64) Option {
+	return func(c *Consistent) { c.LoadFactor = f }
+}
+
+// WithZoneSpread sets ZoneSpread.
+func WithZoneSpread() Option {
+	return func(c *Consistent) { c.ZoneSpread = true }
+}
+
+// WithKeyTag sets KeyTag.
+func WithKeyTag(keyTag func(key string) string) Option {
+	return func(c *Consistent) { c.KeyTag = keyTag }
+}
+
+// WithLogger sets Logger.
+func WithLogger(l Logger) Option {
+	return func(c *Consistent) { c.Logger = l }
+}
+
+// WithMaxMovedFraction sets MaxMovedFraction.
+func WithMaxMovedFraction(f float64) Option {
+	return func(c *Consistent) { c.MaxMovedFraction = f }
+}
+
+// WithHistorySize sets HistorySize.
+func WithHistorySize(n int) Option {
+	return func(c *Consistent) { c.HistorySize = n }
+}
+
+// WithExpireToUnhealthy sets ExpireToUnhealthy.
+func WithExpireToUnhealthy() Option {
+	return func(c *Consistent) { c.ExpireToUnhealthy = true }
+}
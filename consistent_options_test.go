@@ -0,0 +1,54 @@
+package consistent
+
+import (
+	"errors"
+	"hash/crc32"
+	"testing"
+)
+
+func TestOptions(t *testing.T) {
+	c := New(50, WithFnv(), WithSeed(7), WithMaxTraversal(9), WithZoneSpread())
+	ref := New(50)
+	ref.UseFnv, ref.Seed, ref.MaxTraversal, ref.ZoneSpread = true, 7, 9, true
+	if c.NumberOfReplicas != 50 || !c.UseFnv || c.Seed != 7 || c.MaxTraversal != 9 || !c.ZoneSpread {
+		t.Fatalf("options not applied: %+v", c)
+	}
+	c.Set(map[string]float64{"A": 1, "B": 2})
+	ref.Set(map[string]float64{"A": 1, "B": 2})
+	if !c.Equal(ref) {
+		t.Fatal("ring built with options differs from one built with fields")
+	}
+	if New(0, WithVirtualNodeBudget(100)).VirtualNodeBudget != 100 {
+		t.Fatal("WithVirtualNodeBudget not applied")
+	}
+	if n := New(0, WithReplicas(80)).NumberOfReplicas; n != 80 {
+		t.Fatalf("WithReplicas(80) gave %d replicas", n)
+	}
+	o := New(0, WithMaxMovedFraction(0.2), WithHistorySize(5), WithExpireToUnhealthy())
+	if o.MaxMovedFraction != 0.2 || o.HistorySize != 5 || !o.ExpireToUnhealthy {
+		t.Fatalf("options not applied: %+v", o)
+	}
+}
+
+func TestConfigure(t *testing.T) {
+	c := New(20)
+	if err := c.Configure(WithReplicas(40), WithFnv()); err != nil {
+		t.Fatal(err)
+	}
+	c.Set(map[string]float64{"A": 1, "B": 2})
+	salted := func(key string) uint32 { return crc32.ChecksumIEEE([]byte("salt" + key)) }
+	if err := c.Configure(WithHasher(salted)); !errors.Is(err, ErrSettingsFrozen) {
+		t.Fatalf("late Configure(WithHasher): %v", err)
+	}
+	if err := c.Configure(WithReplicas(80)); !errors.Is(err, ErrSettingsFrozen) {
+		t.Fatalf("late Configure(WithReplicas): %v", err)
+	}
+	if c.Hasher != nil || c.NumberOfReplicas != 40 {
+		t.Fatalf("rejected options were applied: %d replicas", c.NumberOfReplicas)
+	}
+	ref := New(40, WithFnv())
+	ref.Set(map[string]float64{"A": 1, "B": 2})
+	if !c.Equal(ref) {
+		t.Fatal("ring differs from one built with the configured settings")
+	}
+}
@@ -0,0 +1,65 @@
+package consistent
+
+// Pin maps key to elt regardless of where key hashes to, for example to keep
+// data in a given region or to move a hot key off an overloaded element.
+// Lookups of key return elt first; the remaining elements follow in ring
+// order. A pin is ignored while its element is absent, unhealthy or drained.
+// It returns ErrMemberNotFound if elt is not present.
+func (c *Consistent) Pin(key, elt string) error {
+	c.lock()
+	defer c.Unlock()
+	if _, ok := c.members[elt]; !ok {
+		return ErrMemberNotFound
+	}
+	if c.pins[key] == elt {
+		return nil
+	}
+	setEntry(c, ownPins, &c.pins, key, elt, false)
+	c.republish()
+	return nil
+}
+
+// Unpin removes the pin of key, returning it to hash placement.
+func (c *Consistent) Unpin(key string) {
+	c.lock()
+	defer c.Unlock()
+	if _, ok := c.pins[key]; !ok {
+		return
+	}
+	setEntry(c, ownPins, &c.pins, key, "", true)
+	c.republish()
+}
+
+// Pins returns a copy of the pinned keys and their elements.
+func (c *Consistent) Pins() map[string]string {
+	pins := c.snapshot().pins
+	m := make(map[string]string, len(pins))
+	for k, v := range pins {
+		m[k] = v
+	}
+	return m
+}
+
+// pinned returns the element name is pinned to, if it is available.
+func (r *ring) pinned(name string) (string, bool) {
+	elt, ok := r.pins[name]
+	if !ok {
+		return "", false
+	}
+	if _, present := r.members[elt]; !present || r.skip(elt) {
+		return "", false
+	}
+	return elt, true
+}
+
+// candidates returns up to n distinct elements for name not in exclude: the
+// element name is pinned to, then the elements from where name hashes to.
+func (r *ring) candidates(name string, n int, exclude []string) ([]string, error) {
+	start := r.search(r.hash(name))
+	elt, ok := r.pinned(name)
+	if !ok || n <= 0 || sliceContainsMember(exclude, elt) {
+		return r.getN(start, n, exclude)
+	}
+	res, err := r.getN(start, n-1, append(exclude[:len(exclude):len(exclude)], elt))
+	return append([]string{elt}, res...), err
+}
@@ -0,0 +1,37 @@
+package consistent
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPin(t *testing.T) {
+	c := New(20)
+	c.Set(map[string]float64{"A": 1, "B": 1, "C": 1})
+	owner, _ := c.Get("key")
+	target := "A"
+	if owner == "A" {
+		target = "B"
+	}
+	if err := c.Pin("key", target); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := c.Get("key"); got != target {
+		t.Fatalf("Get of pinned key = %q, want %q", got, target)
+	}
+	if res, _ := c.GetN("key", 3); len(res) != 3 || res[0] != target {
+		t.Fatalf("GetN of pinned key = %v, want %q first", res, target)
+	}
+	c.Drain(target)
+	if got, _ := c.Get("key"); got == target {
+		t.Fatal("pin to a drained member should be ignored")
+	}
+	c.Undrain(target)
+	c.Unpin("key")
+	if got, _ := c.Get("key"); got != owner {
+		t.Fatalf("Get after Unpin = %q, want %q", got, owner)
+	}
+	if err := c.Pin("key", "D"); !errors.Is(err, ErrMemberNotFound) {
+		t.Fatalf("Pin to unknown member: %v", err)
+	}
+}
@@ -0,0 +1,114 @@
+package consistent
+
+import "slices"
+
+// HashRange is an interval of the 32-bit hash space. Both bounds are
+// inclusive, so a single range can cover the whole space.
+type HashRange struct {
+	Start uint32
+	End   uint32
+}
+
+// Size returns the number of hash values in the range.
+func (h HashRange) Size() uint64 {
+	return uint64(h.End) - uint64(h.Start) + 1
+}
+
+// Ranges returns the hash intervals whose keys map to member, in ascending
+// order with adjacent intervals merged. It returns nil if member owns no
+// virtual node.
+func (c *Consistent) Ranges(member string) []HashRange {
+	var res []HashRange
+	for _, a := range c.snapshot().arcs() {
+		if a.owner != member {
+			continue
+		}
+		if n := len(res); n > 0 && uint64(res[n-1].End)+1 == uint64(a.Start) {
+			res[n-1].End = a.End
+			continue
+		}
+		res = append(res, a.HashRange)
+	}
+	return res
+}
+
+// arc is a hash range together with the member its keys map to.
+type arc struct {
+	HashRange
+	owner string
+}
+
+// arcs splits the hash space into the ranges served by each virtual node, in
+// ascending order. The range that wraps past the top of the space is split in
+// two, so the result always starts at 0 and ends at the maximum hash.
+func (r *ring) arcs() []arc {
+	n := len(r.hashes)
+	if n == 0 {
+		return nil
+	}
+	// A key maps to the first node above it, or at or above it when the ring
+	// is inclusive, so node i serves [lo(i), hi(i)].
+	lo := func(i int) uint32 {
+		if r.inclusive {
+			return r.hashes[i-1] + 1
+		}
+		return r.hashes[i-1]
+	}
+	hi := func(i int) uint32 {
+		if r.inclusive {
+			return r.hashes[i]
+		}
+		return r.hashes[i] - 1
+	}
+	res := make([]arc, 0, n+1)
+	// Keys below the first node wrap to node 0.
+	if r.inclusive || r.hashes[0] > 0 {
+		res = append(res, arc{HashRange{0, hi(0)}, r.owners[0]})
+	}
+	for i := 1; i < n; i++ {
+		res = append(res, arc{HashRange{lo(i), hi(i)}, r.owners[i]})
+	}
+	// Keys above the last node also wrap to node 0.
+	if last := r.hashes[n-1]; !r.inclusive || last < 1<<32-1 {
+		start := last
+		if r.inclusive {
+			start++
+		}
+		res = append(res, arc{HashRange{start, 1<<32 - 1}, r.owners[0]})
+	}
+	return res
+}
+
+// VirtualNode is a point on the ring.
+type VirtualNode struct {
+	Hash   uint32
+	Member string
+	// Replica is the index of the point among the member's virtual nodes.
+	Replica int
+}
+
+// DumpRing returns every virtual node on the ring in ascending hash order.
+// When several virtual nodes collide on a hash only the owning one is listed.
+// It works on a snapshot and does not hold the lock while traversing, so
+// dumping a large ring does not stall membership changes.
+func (c *Consistent) DumpRing() []VirtualNode {
+	c.RLock()
+	r := c.snapshot()
+	p := c.placement(r)
+	c.RUnlock()
+	replica := make(map[uint32]int, len(r.hashes))
+	for elt, wgt := range r.members {
+		for i, h := range p.nodeHashes(elt, wgt) {
+			if j, ok := slices.BinarySearch(r.hashes, h); ok && r.owners[j] == elt {
+				if _, ok := replica[h]; !ok {
+					replica[h] = i
+				}
+			}
+		}
+	}
+	nodes := make([]VirtualNode, len(r.hashes))
+	for i, h := range r.hashes {
+		nodes[i] = VirtualNode{Hash: h, Member: r.owners[i], Replica: replica[h]}
+	}
+	return nodes
+}
@@ -0,0 +1,60 @@
+package consistent
+
+import "testing"
+
+func TestRanges(t *testing.T) {
+	c := New(20)
+	c.Set(map[string]float64{"Host1": 1, "Host2": 2, "Host3": 1})
+	var total uint64
+	ownership := c.Stats().Ownership
+	for _, m := range c.Members() {
+		var size uint64
+		for _, hr := range c.Ranges(m) {
+			size += hr.Size()
+			for _, h := range []uint32{hr.Start, hr.End} {
+				r := c.snapshot()
+				if got := r.owners[r.search(h)]; got != m {
+					t.Fatalf("hash %d in range of %s maps to %s", h, m, got)
+				}
+			}
+		}
+		if f := float64(size) / (1 << 32); f-ownership[m] > 1e-9 || ownership[m]-f > 1e-9 {
+			t.Fatalf("%s: ranges cover %v, ownership %v", m, f, ownership[m])
+		}
+		total += size
+	}
+	if total != 1<<32 {
+		t.Fatalf("ranges cover %d hashes, want 2^32", total)
+	}
+}
+
+func TestDumpRing(t *testing.T) {
+	c := New(10)
+	c.Set(map[string]float64{"Host1": 1, "Host2": 2})
+	nodes := c.DumpRing()
+	if len(nodes) != 30 {
+		t.Fatalf("DumpRing returned %d nodes, want 30", len(nodes))
+	}
+	for _, n := range nodes {
+		if n.Hash != c.hashKey(c.eltKey(n.Member, n.Replica)) {
+			t.Fatalf("node %+v does not match its replica key", n)
+		}
+	}
+
+	// Dumps taken while the ring changes must each be a consistent snapshot.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 200; i++ {
+			c.Add("Host3", 1)
+			c.Remove("Host3")
+		}
+	}()
+	for i := 0; i < 200; i++ {
+		nodes := c.DumpRing()
+		if len(nodes) != 30 && len(nodes) != 40 {
+			t.Fatalf("DumpRing returned %d nodes during changes", len(nodes))
+		}
+	}
+	<-done
+}
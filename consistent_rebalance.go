@@ -0,0 +1,112 @@
+package consistent
+
+// Move is a range of key hashes whose owner changes in a rebalance.
+type Move struct {
+	HashRange
+	From string
+	To   string
+	// Size is the estimated amount of data in the range.
+	Size float64
+}
+
+// PlanRebalance returns the ranges of key hashes that change owner when a
+// ring with c's settings goes from the current members to the desired ones,
+// in ascending order with adjacent ranges of the same move merged. size
+// estimates the data held in a range; nil estimates it by the range's share
+// of the hash space. A From or To is empty when that side has no members.
+func (c *Consistent) PlanRebalance(current, desired map[string]float64, size func(HashRange) float64) ([]Move, error) {
+	from, err := c.withMembers(current)
+	if err != nil {
+		return nil, err
+	}
+	to, err := c.withMembers(desired)
+	if err != nil {
+		return nil, err
+	}
+	if size == nil {
+		size = func(h HashRange) float64 { return float64(h.Size()) / (1 << 32) }
+	}
+	var moves []Move
+	diffArcs(from.snapshot(), to.snapshot(), func(h HashRange, src, dst string) {
+		if n := len(moves); n > 0 && moves[n-1].From == src && moves[n-1].To == dst && uint64(moves[n-1].End)+1 == uint64(h.Start) {
+			moves[n-1].End = h.End
+			return
+		}
+		moves = append(moves, Move{HashRange: h, From: src, To: dst})
+	})
+	for i := range moves {
+		moves[i].Size = size(moves[i].HashRange)
+	}
+	return moves, nil
+}
+
+// withMembers returns a new Consistent with c's hash settings and members.
+func (c *Consistent) withMembers(members map[string]float64) (*Consistent, error) {
+	c.RLock()
+	n := c.settingsCopy()
+	c.RUnlock()
+	if err := n.Set(members); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// movedFraction returns the fraction of the hash space that changes owner
+// when the members of c become members.
+//
+// need c.RLock() before calling
+func (c *Consistent) movedFraction(members map[string]float64) float64 {
+	n := c.settingsCopy()
+	n.Set(members)
+	var f float64
+	diffArcs(c.snapshot(), n.snapshot(), func(h HashRange, _, _ string) {
+		f += float64(h.Size()) / (1 << 32)
+	})
+	return f
+}
+
+// settingsCopy returns an empty Consistent with c's hash settings.
+//
+// need c.RLock() before calling
+func (c *Consistent) settingsCopy() *Consistent {
+	cfg := c.conf()
+	n := New(0)
+	n.cfg = &settings{
+		replicas:     cfg.replicas,
+		fnv:          cfg.fnv,
+		hasher:       cfg.hasher,
+		ketama:       cfg.ketama,
+		budget:       cfg.budget,
+		seed:         cfg.seed,
+		uint64Hasher: cfg.uint64Hasher,
+	}
+	n.cfg.export(n)
+	n.replicas = c.replicas
+	return n
+}
+
+// diffArcs calls moved for every range of key hashes owned by different
+// elements in a and b, in ascending order.
+func diffArcs(a, b *ring, moved func(h HashRange, from, to string)) {
+	aa, ba := a.arcs(), b.arcs()
+	if len(aa) == 0 {
+		aa = []arc{{HashRange{0, 1<<32 - 1}, ""}}
+	}
+	if len(ba) == 0 {
+		ba = []arc{{HashRange{0, 1<<32 - 1}, ""}}
+	}
+	var start uint64
+	for i, j := 0, 0; i < len(aa) && j < len(ba); {
+		end := min(aa[i].End, ba[j].End)
+		if aa[i].owner != ba[j].owner {
+			moved(HashRange{uint32(start), end}, aa[i].owner, ba[j].owner)
+		}
+		start = uint64(end) + 1
+		if aa[i].End == end {
+			i++
+		}
+		if ba[j].End == end {
+			j++
+		}
+	}
+}
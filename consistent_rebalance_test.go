@@ -0,0 +1,45 @@
+package consistent
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestPlanRebalance(t *testing.T) {
+	c := New(20)
+	current := map[string]float64{"A": 1, "B": 1, "C": 1}
+	desired := map[string]float64{"A": 1, "B": 1, "C": 1, "D": 1}
+	moves, err := c.PlanRebalance(current, desired, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var moved float64
+	for _, m := range moves {
+		if m.To != "D" {
+			t.Fatalf("adding D moves %v from %q to %q", m.HashRange, m.From, m.To)
+		}
+		moved += m.Size
+	}
+	if moved < 0.1 || moved > 0.4 {
+		t.Fatalf("adding a fourth member moves %.2f of the hash space", moved)
+	}
+
+	old, next := New(20), New(20)
+	old.Set(current)
+	next.Set(desired)
+	for i := 0; i < 200; i++ {
+		key := fmt.Sprint(i)
+		from, _ := old.Get(key)
+		to, _ := next.Get(key)
+		h := c.Hash(key)
+		planned := false
+		for _, m := range moves {
+			if m.Start <= h && h <= m.End {
+				planned = m.From == from && m.To == to
+			}
+		}
+		if planned != (from != to) {
+			t.Fatalf("key %q moves %q -> %q, planned %v", key, from, to, planned)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package consistent
+
+import (
+	"math"
+	"math/rand/v2"
+	"sort"
+)
+
+// weightedSample returns up to k of names in weighted random order without
+// replacement, the weight of names[i] being weights[i]. It uses the A-ExpJ
+// reservoir algorithm of Efraimidis and Spirakis: each item gets the key
+// u^(1/w), the k largest keys win, and exponential jumps skip the items that
+// cannot enter the reservoir, so only O(k log(n/k)) random numbers are drawn.
+// Keys are kept as logarithms to stay accurate for large weights.
+// Items with a weight of 0 or less are never returned.
+func weightedSample(names []string, weights []float64, k int, random func() float64) []string {
+	if k <= 0 {
+		return nil
+	}
+	type entry struct {
+		key float64 // log of the A-Res key, at most 0
+		idx int
+	}
+	h := make([]entry, 0, min(k, len(names)))
+	// h is a min-heap on key.
+	down := func(i int) {
+		for {
+			c := 2*i + 1
+			if c >= len(h) {
+				return
+			}
+			if c+1 < len(h) && h[c+1].key < h[c].key {
+				c++
+			}
+			if h[i].key <= h[c].key {
+				return
+			}
+			h[i], h[c] = h[c], h[i]
+			i = c
+		}
+	}
+	up := func(i int) {
+		for i > 0 {
+			p := (i - 1) / 2
+			if h[p].key <= h[i].key {
+				return
+			}
+			h[i], h[p] = h[p], h[i]
+			i = p
+		}
+	}
+	logU := func() float64 {
+		// 1 - Float64 is in (0, 1], so the log is finite.
+		return math.Log(1 - random())
+	}
+
+	var jump float64 // weight to skip before the next replacement
+	for i, w := range weights {
+		if w <= 0 {
+			continue
+		}
+		if len(h) < k {
+			h = append(h, entry{logU() / w, i})
+			up(len(h) - 1)
+			if len(h) == k {
+				jump = logU() / h[0].key
+			}
+			continue
+		}
+		jump -= w
+		if jump > 0 {
+			continue
+		}
+		// The item enters the reservoir with a key drawn above the current
+		// minimum: u in (exp(min*w), 1].
+		tw := math.Exp(h[0].key * w)
+		u := tw + (1-tw)*(1-random())
+		h[0] = entry{math.Log(u) / w, i}
+		down(0)
+		jump = logU() / h[0].key
+	}
+	sort.Slice(h, func(i, j int) bool { return h[i].key > h[j].key })
+	res := make([]string, len(h))
+	for i, e := range h {
+		res[i] = names[e.idx]
+	}
+	return res
+}
+
+// AliasSampler picks members at random in proportion to their weight in
+// constant time per pick, using Vose's alias method. It is immutable and safe
+// for concurrent use.
+type AliasSampler struct {
+	names []string
+	prob  []float64
+	alias []int
+}
+
+// NewAliasSampler returns a sampler over weights. Members with a weight of 0
+// or less are never picked.
+func NewAliasSampler(weights map[string]float64) *AliasSampler {
+	s := new(AliasSampler)
+	var total float64
+	for name, w := range weights {
+		if w > 0 {
+			s.names = append(s.names, name)
+			total += w
+		}
+	}
+	sort.Strings(s.names)
+	n := len(s.names)
+	s.prob = make([]float64, n)
+	s.alias = make([]int, n)
+	var small, large []int
+	for i, name := range s.names {
+		s.prob[i] = weights[name] * float64(n) / total
+		if s.prob[i] < 1 {
+			small = append(small, i)
+		} else {
+			large = append(large, i)
+		}
+	}
+	for len(small) > 0 && len(large) > 0 {
+		l, g := small[len(small)-1], large[len(large)-1]
+		small = small[:len(small)-1]
+		s.alias[l] = g
+		s.prob[g] -= 1 - s.prob[l]
+		if s.prob[g] < 1 {
+			large = large[:len(large)-1]
+			small = append(small, g)
+		}
+	}
+	// What is left is 1 up to rounding.
+	for _, i := range append(small, large...) {
+		s.prob[i] = 1
+	}
+	return s
+}
+
+// Pick returns a member at random, using r as the source of randomness, or
+// the global source if r is nil. It returns "" if there are no members.
+func (s *AliasSampler) Pick(r *rand.Rand) string {
+	if len(s.names) == 0 {
+		return ""
+	}
+	intN, random := rand.IntN, rand.Float64
+	if r != nil {
+		intN, random = r.IntN, r.Float64
+	}
+	i := intN(len(s.names))
+	if random() < s.prob[i] {
+		return s.names[i]
+	}
+	return s.names[s.alias[i]]
+}
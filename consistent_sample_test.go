@@ -0,0 +1,64 @@
+package consistent
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"testing"
+)
+
+func TestWeightedSample(t *testing.T) {
+	m := map[string]float64{"A": 1, "B": 2, "C": 7, "Z": 0}
+	r := rand.New(rand.NewPCG(1, 2))
+	first := map[string]int{}
+	picks := map[string]int{}
+	s := NewAliasSampler(m)
+	for i := 0; i < 10000; i++ {
+		order := WeightedShuffleRand(m, r)
+		if len(order) != 4 || order[3] != "Z" {
+			t.Fatalf("WeightedShuffleRand = %v, want zero weight last", order)
+		}
+		first[order[0]]++
+		picks[s.Pick(r)]++
+	}
+	for name, w := range map[string]float64{"A": 0.1, "B": 0.2, "C": 0.7} {
+		for what, got := range map[string]int{"first of shuffle": first[name], "alias pick": picks[name]} {
+			if f := float64(got) / 10000; math.Abs(f-w) > 0.02 {
+				t.Errorf("%s %s: %.3f, want %.1f", name, what, f, w)
+			}
+		}
+	}
+	if picks["Z"] != 0 {
+		t.Fatal("alias sampler picked a zero weight member")
+	}
+}
+
+func benchmarkWeights(n int) map[string]float64 {
+	m := make(map[string]float64, n)
+	for i := 0; i < n; i++ {
+		m[fmt.Sprintf("Host%d", i)] = float64(i%10 + 1)
+	}
+	return m
+}
+
+func BenchmarkWeightedSampleTop3(b *testing.B) {
+	m := benchmarkWeights(1000)
+	names := make([]string, 0, len(m))
+	weights := make([]float64, 0, len(m))
+	for name, w := range m {
+		names = append(names, name)
+		weights = append(weights, w)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		weightedSample(names, weights, 3, rand.Float64)
+	}
+}
+
+func BenchmarkAliasPick(b *testing.B) {
+	s := NewAliasSampler(benchmarkWeights(1000))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.Pick(nil)
+	}
+}
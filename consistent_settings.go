@@ -0,0 +1,124 @@
+package consistent
+
+import "errors"
+
+// ErrSettingsFrozen is the error returned by Configure once the hash has
+// been changed and its settings are frozen.
+var ErrSettingsFrozen = errors.New("settings are frozen")
+
+// Configure applies opts to c if its settings are not frozen yet, and
+// returns ErrSettingsFrozen without applying any of them otherwise. Unlike
+// writing the exported fields, it is safe to call concurrently with other
+// methods. To change the settings of a hash already in use, write the fields
+// and call Rebuild.
+func (c *Consistent) Configure(opts ...Option) error {
+	c.Lock()
+	defer c.Unlock()
+	if c.cfg != nil {
+		return ErrSettingsFrozen
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return nil
+}
+
+// settings are the exported settings of a Consistent, frozen the first time
+// it is changed. Writers and the published rings only ever read the frozen
+// copy, so the exported fields can be read and written concurrently with
+// lookups without a data race, and later writes to them cannot leave the
+// circle placed with settings it is no longer searched with. A settings value
+// is never modified once frozen; changing it means replacing c.cfg.
+type settings struct {
+	replicas          int
+	fnv               bool
+	hasher            func(key string) uint32
+	ketama            bool
+	budget            int
+	seed              uint64
+	uint64Hasher      func(key uint64) uint32
+	loadFactor        float64
+	maxTraversal      int
+	zoneSpread        bool
+	keyTag            func(key string) string
+	maxMovedFraction  float64
+	logger            Logger
+	historySize       int
+	expireToUnhealthy bool
+}
+
+// exported returns the settings currently in the exported fields.
+func (c *Consistent) exported() *settings {
+	s := &settings{
+		replicas:          c.NumberOfReplicas,
+		fnv:               c.UseFnv,
+		hasher:            c.Hasher,
+		ketama:            c.KetamaMode,
+		budget:            c.VirtualNodeBudget,
+		seed:              c.Seed,
+		uint64Hasher:      c.Uint64Hasher,
+		loadFactor:        c.LoadFactor,
+		maxTraversal:      c.MaxTraversal,
+		zoneSpread:        c.ZoneSpread,
+		keyTag:            c.KeyTag,
+		maxMovedFraction:  c.MaxMovedFraction,
+		logger:            c.Logger,
+		historySize:       c.HistorySize,
+		expireToUnhealthy: c.ExpireToUnhealthy,
+	}
+	if s.replicas <= 0 {
+		s.replicas = 20
+	}
+	return s
+}
+
+// export copies s into the exported fields of c, which must not be shared
+// yet.
+func (s *settings) export(c *Consistent) {
+	c.NumberOfReplicas = s.replicas
+	c.UseFnv = s.fnv
+	c.Hasher = s.hasher
+	c.KetamaMode = s.ketama
+	c.VirtualNodeBudget = s.budget
+	c.Seed = s.seed
+	c.Uint64Hasher = s.uint64Hasher
+	c.LoadFactor = s.loadFactor
+	c.MaxTraversal = s.maxTraversal
+	c.ZoneSpread = s.zoneSpread
+	c.KeyTag = s.keyTag
+	c.MaxMovedFraction = s.maxMovedFraction
+	c.Logger = s.logger
+	c.HistorySize = s.historySize
+	c.ExpireToUnhealthy = s.expireToUnhealthy
+}
+
+// lock takes the write lock and freezes the settings if they are not yet.
+// Every change goes through it.
+func (c *Consistent) lock() {
+	c.Lock()
+	c.owned = 0
+	if c.cfg == nil {
+		c.cfg = c.exported()
+	}
+}
+
+// conf returns the frozen settings, or the exported ones if nothing was
+// changed yet.
+//
+// need c.RLock() before calling
+func (c *Consistent) conf() *settings {
+	if c.cfg != nil {
+		return c.cfg
+	}
+	return c.exported()
+}
+
+// setLogger replaces the Logger of c, frozen or not.
+func (c *Consistent) setLogger(l Logger) {
+	c.lock()
+	defer c.Unlock()
+	cfg := *c.cfg
+	cfg.logger = l
+	c.cfg = &cfg
+	c.Logger = l
+}
@@ -0,0 +1,29 @@
+package consistent
+
+// SimulationReport describes the effect of a change tried by Simulate.
+type SimulationReport struct {
+	// Moved is the fraction of the hash space that would change owner.
+	Moved float64
+	// Before and After are the shares of the hash space each element owns
+	// before and after the change.
+	Before, After map[string]float64
+}
+
+// Simulate applies change to a Clone of c and reports how it would move the
+// hash space, without touching c. The copy has no Logger or
+// MaxMovedFraction, so the report covers changes the guard would reject.
+func (c *Consistent) Simulate(change func(*Consistent)) SimulationReport {
+	n := c.Clone()
+	cfg := *n.cfg
+	cfg.logger, cfg.maxMovedFraction = nil, 0
+	n.cfg = &cfg
+	cfg.export(n)
+	before := n.snapshot()
+	change(n)
+	after := n.snapshot()
+	report := SimulationReport{Before: before.ownership(), After: after.ownership()}
+	diffArcs(before, after, func(h HashRange, _, _ string) {
+		report.Moved += float64(h.Size()) / (1 << 32)
+	})
+	return report
+}
@@ -0,0 +1,30 @@
+package consistent
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSimulate(t *testing.T) {
+	c := New(50)
+	c.MaxMovedFraction = 0.01
+	c.Set(map[string]float64{"A": 1, "B": 1, "C": 2})
+	before := c.Clone()
+	report := c.Simulate(func(s *Consistent) {
+		if err := s.UpdateWeight("A", 4); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if !c.Equal(before) {
+		t.Fatal("Simulate changed the live ring")
+	}
+	if math.Abs(report.Before["A"]-0.25) > 0.1 || math.Abs(report.After["A"]-4.0/7) > 0.1 {
+		t.Fatalf("A owns %v before and %v after", report.Before["A"], report.After["A"])
+	}
+	if got := report.After["A"] - report.Before["A"]; math.Abs(report.Moved-got) > 1e-9 {
+		t.Fatalf("Moved = %v, A gained %v", report.Moved, got)
+	}
+	if r := c.Simulate(func(*Consistent) {}); r.Moved != 0 {
+		t.Fatalf("no-op simulation moved %v", r.Moved)
+	}
+}
@@ -0,0 +1,152 @@
+package consistent
+
+import (
+	"errors"
+	"time"
+)
+
+// SlowStart configures how AddSlowStart ramps up the weight of a new element,
+// so that a cold node is not handed its full share of keys at once.
+type SlowStart struct {
+	// InitialFraction is the fraction of the target weight the element
+	// starts with. Default 0.1.
+	InitialFraction float64
+	// Steps is the number of weight increases up to the target weight.
+	// Default 10.
+	Steps int
+	// Duration spreads the steps evenly over time.
+	Duration time.Duration
+	// Lookups takes a step every Lookups lookups on the circle instead. It is
+	// used when Duration is 0. The lookup that makes a step due does not wait
+	// for it: the step is taken on its own goroutine shortly after.
+	Lookups uint64
+}
+
+// ErrInvalidSlowStart is the error returned by AddSlowStart when neither
+// Duration nor Lookups is set, so the ramp would never advance.
+var ErrInvalidSlowStart = errors.New("slow start needs a Duration or Lookups")
+
+// ramp is the slow start in progress of one element.
+type ramp struct {
+	target float64
+	opts   SlowStart
+	step   int
+	next   uint64 // lookup count of the next step
+	timer  *time.Timer
+}
+
+func (r *ramp) weight() float64 {
+	f := r.opts.InitialFraction
+	return r.target * (f + (1-f)*float64(r.step)/float64(r.opts.Steps))
+}
+
+// AddSlowStart inserts a string element in the consistent hash with a small
+// share of wgt, and raises its weight to wgt in steps as configured by opts.
+// UpdateWeight, Remove, or a Set with a different weight end the ramp. It
+// returns ErrMemberExists if elt is already present.
+func (c *Consistent) AddSlowStart(elt string, wgt float64, opts SlowStart) error {
+	if err := validateWeight(wgt); err != nil {
+		return err
+	}
+	if opts.Duration <= 0 && opts.Lookups == 0 {
+		return ErrInvalidSlowStart
+	}
+	if opts.InitialFraction <= 0 || opts.InitialFraction > 1 {
+		opts.InitialFraction = 0.1
+	}
+	if opts.Steps <= 0 {
+		opts.Steps = 10
+	}
+	c.lock()
+	defer c.Unlock()
+	if _, ok := c.members[elt]; ok {
+		return ErrMemberExists
+	}
+	r := &ramp{target: wgt, opts: opts}
+	if err := c.checkMoved(adder(elt, r.weight())); err != nil {
+		return err
+	}
+	c.add(elt, r.weight())
+	if c.ramps == nil {
+		c.ramps = make(map[string]*ramp)
+	}
+	c.ramps[elt] = r
+	c.scheduleRamp(elt, r)
+	c.updateSortedHashes()
+	return nil
+}
+
+// countLookup counts a lookup and starts advancing the lookup-based slow
+// starts that are due. The stripes of the count are only summed while such a
+// slow start is in progress. Only the lookup winning the swap of rampDue
+// starts it, and on another goroutine, so lookups never wait for the write
+// lock.
+func (c *Consistent) countLookup() {
+	c.lookups.add()
+	if due := c.rampDue.Load(); due != 0 && c.lookups.load() >= due && c.rampDue.CompareAndSwap(due, 0) {
+		go c.advanceRamps()
+	}
+}
+
+// advanceRamps takes a step for every lookup-based slow start that is due.
+func (c *Consistent) advanceRamps() {
+	c.lock()
+	defer c.Unlock()
+	n := c.lookups.load()
+	for elt, r := range c.ramps {
+		if r.opts.Duration <= 0 && r.next <= n {
+			c.stepRamp(elt, r)
+		}
+	}
+	var due uint64
+	for _, r := range c.ramps {
+		if r.opts.Duration <= 0 && (due == 0 || r.next < due) {
+			due = r.next
+		}
+	}
+	c.rampDue.Store(due)
+	c.updateSortedHashes()
+}
+
+// need c.Lock() before calling
+func (c *Consistent) stepRamp(elt string, r *ramp) {
+	r.step++
+	c.updateWeight(elt, r.weight())
+	if r.step >= r.opts.Steps {
+		delete(c.ramps, elt)
+		return
+	}
+	c.scheduleRamp(elt, r)
+}
+
+// need c.Lock() before calling
+func (c *Consistent) scheduleRamp(elt string, r *ramp) {
+	if r.opts.Duration > 0 {
+		r.timer = time.AfterFunc(r.opts.Duration/time.Duration(r.opts.Steps), func() {
+			c.lock()
+			defer c.Unlock()
+			if c.ramps[elt] != r {
+				return
+			}
+			c.stepRamp(elt, r)
+			c.updateSortedHashes()
+		})
+		return
+	}
+	r.next = c.lookups.load() + max(r.opts.Lookups, 1)
+	if due := c.rampDue.Load(); due == 0 || r.next < due {
+		c.rampDue.Store(r.next)
+	}
+}
+
+// need c.Lock() before calling
+func (c *Consistent) cancelRamp(elt string) {
+	r, ok := c.ramps[elt]
+	if !ok {
+		return
+	}
+	if r.timer != nil {
+		r.timer.Stop()
+	}
+	delete(c.ramps, elt)
+}
@@ -0,0 +1,79 @@
+package consistent
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestAddSlowStart(t *testing.T) {
+	c := New(20)
+	c.Add("A", 1)
+	if err := c.AddSlowStart("B", 2, SlowStart{InitialFraction: 0.5, Steps: 2, Lookups: 10}); err != nil {
+		t.Fatal(err)
+	}
+	weight := func() float64 {
+		for _, m := range c.MemberInfos() {
+			if m.Name == "B" {
+				return m.Weight
+			}
+		}
+		return 0
+	}
+	// Lookups take the steps they make due asynchronously.
+	waitWeight := func(want float64) float64 {
+		deadline := time.Now().Add(2 * time.Second)
+		for weight() != want && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+		}
+		return weight()
+	}
+	if w := weight(); w != 1 {
+		t.Fatalf("initial weight = %v, want 1", w)
+	}
+	for i := 0; i < 10; i++ {
+		c.Get(fmt.Sprint(i))
+	}
+	if w := waitWeight(1.5); w != 1.5 {
+		t.Fatalf("weight after 10 lookups = %v, want 1.5", w)
+	}
+	c.Set(map[string]float64{"A": 1, "B": 2})
+	if w := weight(); w != 1.5 {
+		t.Fatalf("weight after Set with the target = %v, want 1.5", w)
+	}
+	for i := 0; i < 10; i++ {
+		c.Get(fmt.Sprint(i))
+	}
+	if w := waitWeight(2); w != 2 {
+		t.Fatalf("weight after 20 lookups = %v, want 2", w)
+	}
+	if err := c.AddSlowStart("D", 1, SlowStart{}); !errors.Is(err, ErrInvalidSlowStart) {
+		t.Fatalf("AddSlowStart without Duration or Lookups = %v", err)
+	}
+
+	// A lookup making a step due must not wait for a writer.
+	e := New(20)
+	e.Add("A", 1)
+	e.AddSlowStart("B", 1, SlowStart{Steps: 2, Lookups: 1})
+	e.Lock()
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 5; i++ {
+			e.Get(fmt.Sprint(i))
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("lookup blocked on the write lock")
+	}
+	e.Unlock()
+
+	c.AddSlowStart("C", 1, SlowStart{Steps: 2, Duration: 10 * time.Millisecond})
+	time.Sleep(100 * time.Millisecond)
+	if got := c.VirtualNodes()["C"]; got != 20 {
+		t.Fatalf("C has %d virtual nodes after its ramp, want 20", got)
+	}
+}
@@ -0,0 +1,268 @@
+package consistent
+
+// ring is an immutable snapshot of the circle. Writers build a new ring under
+// c.Lock() and swap it in atomically, so lookups never block on writers.
+type ring struct {
+	hashes  []uint32
+	owners  []string
+	members map[string]float64
+	meta    map[string]map[string]string
+	zones   map[string]string
+	tags    map[string][]string
+	pins    map[string]string
+	// down holds the elements lookups skip without moving their keys.
+	down   map[string]bool
+	hash   func(string) uint32
+	hash64 func(uint64) uint32
+	// inclusive makes a key that lands exactly on a virtual node map to that
+	// node rather than the next one, as libketama does.
+	inclusive bool
+	// maxTraversal bounds the virtual nodes getN visits; 0 means no bound.
+	maxTraversal int
+	// zoneSpread makes replicaSet prefer elements of distinct zones.
+	zoneSpread bool
+	// loadFactor and keyTag are the LoadFactor and KeyTag the ring was
+	// published with.
+	loadFactor float64
+	keyTag     func(key string) string
+	observers  []Observer
+}
+
+var emptyRing = &ring{members: map[string]float64{}}
+
+// snapshot returns the current ring for lock-free reads.
+func (c *Consistent) snapshot() *ring {
+	if r := c.ring.Load(); r != nil {
+		return r
+	}
+	return emptyRing
+}
+
+// placement returns a detached copy of the settings that place the virtual
+// nodes of r, so that nodeHashes can be called on it after the lock is
+// released.
+//
+// need c.RLock() before calling
+func (c *Consistent) placement(r *ring) *Consistent {
+	return &Consistent{
+		members:  r.members,
+		alloc:    c.alloc,
+		replicas: c.replicas,
+		cfg:      c.conf(),
+	}
+}
+
+// need c.Lock() before calling
+func (c *Consistent) publish(hashes []uint32, owners []string) {
+	cfg := c.conf()
+	r := &ring{
+		hashes:  hashes,
+		owners:  owners,
+		members: make(map[string]float64, len(c.members)),
+		hash:    c.hashKeyCRC32,
+	}
+	if cfg.fnv {
+		r.hash = c.hashKeyFnv
+	}
+	if cfg.hasher != nil {
+		r.hash = cfg.hasher
+	}
+	if cfg.ketama {
+		r.hash = ketamaHash
+		r.inclusive = true
+	}
+	r.hash64 = splitmix64
+	if cfg.uint64Hasher != nil {
+		r.hash64 = cfg.uint64Hasher
+	}
+	if seed := cfg.seed; seed != 0 {
+		hash, hash64 := r.hash, r.hash64
+		r.hash = func(key string) uint32 { return seedHash(hash(key), seed) }
+		r.hash64 = func(key uint64) uint32 { return hash64(key ^ seed) }
+	}
+	r.zoneSpread = cfg.zoneSpread
+	r.maxTraversal = cfg.maxTraversal
+	r.loadFactor = cfg.loadFactor
+	r.keyTag = cfg.keyTag
+	r.observers = c.observers
+	r.meta = c.meta
+	r.zones = c.zones
+	r.tags = c.tags
+	r.pins = c.pins
+	r.down = c.down()
+	c.owned = 0
+	for k, v := range c.members {
+		r.members[k] = v
+	}
+	old := c.snapshot()
+	c.ring.Store(r)
+	c.wakeWaiters()
+	c.logChanges(old, r)
+	c.recordHistory(old, r)
+	c.notifyChanges()
+}
+
+// search returns the index of the first virtual node after key, wrapping around.
+func (r *ring) search(key uint32) int {
+	lo, hi := 0, len(r.hashes)
+	for lo < hi {
+		m := int(uint(lo+hi) >> 1)
+		if r.hashes[m] > key || (r.inclusive && r.hashes[m] == key) {
+			hi = m
+		} else {
+			lo = m + 1
+		}
+	}
+	if lo >= len(r.hashes) {
+		return 0
+	}
+	return lo
+}
+
+// smallN is the number of elements up to which getN checks for duplicates
+// with linear scans, which beat a map for the few replicas usually asked for.
+const smallN = 8
+
+// getN walks the ring from start collecting up to n distinct members not in
+// exclude. It returns ErrTraversalLimit with the members found so far if it
+// gives up after maxTraversal virtual nodes.
+func (r *ring) getN(start, n int, exclude []string) ([]string, error) {
+	res := make([]string, 0, n)
+	if n <= 0 {
+		return res, nil
+	}
+	var seen map[string]struct{}
+	if n+len(exclude) > smallN {
+		seen = make(map[string]struct{}, n+len(exclude))
+		for _, e := range exclude {
+			seen[e] = struct{}{}
+		}
+	}
+	limit := len(r.owners)
+	if r.maxTraversal > 0 && r.maxTraversal < limit {
+		limit = r.maxTraversal
+	}
+	for k := 0; k < limit; k++ {
+		elem := r.owners[(start+k)%len(r.owners)]
+		if r.skip(elem) {
+			continue
+		}
+		if seen != nil {
+			if _, ok := seen[elem]; ok {
+				continue
+			}
+			seen[elem] = struct{}{}
+		} else if sliceContainsMember(exclude, elem) || sliceContainsMember(res, elem) {
+			continue
+		}
+		res = append(res, elem)
+		if len(res) == n {
+			return res, nil
+		}
+	}
+	if limit < len(r.owners) {
+		return res, ErrTraversalLimit
+	}
+	return res, nil
+}
+
+// get returns the owner of name, or "" when the ring is empty.
+func (r *ring) get(name string) string {
+	if len(r.hashes) == 0 {
+		return ""
+	}
+	return r.owners[r.search(r.hash(name))]
+}
+
+func (r *ring) getOne(name string) (string, error) {
+	if len(r.hashes) == 0 {
+		return "", ErrEmptyCircle
+	}
+	if elt, ok := r.pinned(name); ok {
+		return elt, nil
+	}
+	return r.getHash(r.hash(name))
+}
+
+// getHash returns the first available element at or after the key hash h.
+func (r *ring) getHash(h uint32) (string, error) {
+	if len(r.hashes) == 0 {
+		return "", ErrEmptyCircle
+	}
+	start := r.search(h)
+	if len(r.down) == 0 {
+		return r.owners[start], nil
+	}
+	for k := 0; k < len(r.owners); k++ {
+		if elem := r.owners[(start+k)%len(r.owners)]; !r.skip(elem) {
+			return elem, nil
+		}
+	}
+	return "", ErrNoAvailableMember
+}
+
+func (r *ring) getFiltered(name string, accept func(member string) bool) (string, error) {
+	if len(r.hashes) == 0 {
+		return "", ErrEmptyCircle
+	}
+	if elt, ok := r.pinned(name); ok && accept(elt) {
+		return elt, nil
+	}
+	start := r.search(r.hash(name))
+	for k := 0; k < len(r.owners); k++ {
+		elem := r.owners[(start+k)%len(r.owners)]
+		if !r.skip(elem) && accept(elem) {
+			return elem, nil
+		}
+	}
+	return "", ErrNoMatchingMember
+}
+
+func (r *ring) getTwo(name string) (string, string, error) {
+	if len(r.hashes) == 0 {
+		return "", "", ErrEmptyCircle
+	}
+	res, err := r.candidates(name, 2, nil)
+	if len(res) == 0 {
+		if err == nil {
+			err = ErrNoAvailableMember
+		}
+		return "", "", err
+	}
+	if len(res) == 1 {
+		return res[0], "", ErrInsufficientMembers
+	}
+	return res[0], res[1], nil
+}
+
+func (r *ring) getNExcluding(name string, n int, exclude []string) ([]string, error) {
+	if len(r.hashes) == 0 {
+		return nil, ErrEmptyCircle
+	}
+	excluded := 0
+	for i, e := range exclude {
+		if _, ok := r.members[e]; ok && !r.skip(e) && !sliceContainsMember(exclude[:i], e) {
+			excluded++
+		}
+	}
+	excluded += len(r.down)
+	if remain := len(r.members) - excluded; remain < n {
+		n = remain
+	}
+	if n <= 0 {
+		return nil, nil
+	}
+	return r.candidates(name, n, exclude)
+}
+
+// atLeast returns err, or if err is nil and res has fewer than n elements,
+// ErrNoAvailableMember if it has none and ErrInsufficientMembers otherwise.
+func atLeast(res []string, n int, err error) error {
+	if err != nil || len(res) >= n {
+		return err
+	}
+	if len(res) == 0 {
+		return ErrNoAvailableMember
+	}
+	return ErrInsufficientMembers
+}
@@ -0,0 +1,163 @@
+package consistent
+
+import (
+	"expvar"
+	"math"
+	"math/rand/v2"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// Stats is a point-in-time summary of a Consistent.
+type Stats struct {
+	Members      int
+	VirtualNodes int
+	// LastRebuild is how long the last ring rebuild took.
+	LastRebuild time.Duration
+	// Lookups is the number of lookups served since the ring was created.
+	Lookups uint64
+	// Ownership is the fraction of the hash space owned by each element.
+	Ownership map[string]float64
+}
+
+// Stats returns the current statistics of the ring.
+func (c *Consistent) Stats() Stats {
+	c.RLock()
+	lastRebuild := c.lastRebuild
+	c.RUnlock()
+	r := c.snapshot()
+	return Stats{
+		Members:      len(r.members),
+		VirtualNodes: len(r.hashes),
+		LastRebuild:  lastRebuild,
+		Lookups:      c.lookups.load(),
+		Ownership:    r.ownership(),
+	}
+}
+
+// PublishExpvar publishes the ring statistics as the expvar variable name,
+// so they show up under /debug/vars. Like expvar.Publish, it panics if name
+// is already in use.
+func (c *Consistent) PublishExpvar(name string) {
+	expvar.Publish(name, expvar.Func(func() any { return c.Stats() }))
+}
+
+// lookupCounter counts lookups in stripes on separate cache lines, each
+// lookup adding to one picked at random, so that lookups on different cores
+// rarely write the same cache line.
+type lookupCounter [16]struct {
+	n atomic.Uint64
+	_ [56]byte
+}
+
+func (l *lookupCounter) add() {
+	l[rand.Uint32()%uint32(len(l))].n.Add(1)
+}
+
+func (l *lookupCounter) load() uint64 {
+	var n uint64
+	for i := range l {
+		n += l[i].n.Load()
+	}
+	return n
+}
+
+// need c.Lock() before calling
+func (c *Consistent) timeRebuild(start time.Time) {
+	c.lastRebuild = time.Since(start)
+}
+
+// ownership returns the fraction of the 32-bit hash space owned by each
+// member. A key maps to the first virtual node above it, so node i owns the
+// arc from the previous node up to itself, and node 0 also owns the wrap-around.
+func (r *ring) ownership() map[string]float64 {
+	m := make(map[string]float64, len(r.members))
+	for k := range r.members {
+		m[k] = 0
+	}
+	if len(r.hashes) == 0 {
+		return m
+	}
+	const space = 1 << 32
+	prev := uint64(r.hashes[len(r.hashes)-1])
+	for i, h := range r.hashes {
+		arc := uint64(h) - prev
+		if i == 0 {
+			arc = space - prev + uint64(h)
+		}
+		m[r.owners[i]] += float64(arc) / space
+		prev = uint64(h)
+	}
+	return m
+}
+
+// WeightError returns, for each element, the relative error between the
+// share of the hash space it owns and its share of the total weight:
+// (owned - share) / share. Positive values mean the element owns more than
+// its weight calls for. Unlike Distribution it measures the arcs exactly,
+// so it is cheap enough to compare NumberOfReplicas settings directly.
+func (c *Consistent) WeightError() map[string]float64 {
+	r := c.snapshot()
+	var total float64
+	for _, w := range r.members {
+		total += w
+	}
+	own := r.ownership()
+	for k, w := range r.members {
+		if w <= 0 || total <= 0 {
+			delete(own, k)
+			continue
+		}
+		share := w / total
+		own[k] = (own[k] - share) / share
+	}
+	return own
+}
+
+// DistributionReport is the result of sampling how keys spread over a ring.
+type DistributionReport struct {
+	// Ratios is the fraction of sampled keys owned by each element.
+	Ratios map[string]float64
+	// PeakToMean is the largest ratio of an element's load to its
+	// weight-proportional share; 1 means perfectly balanced.
+	PeakToMean float64
+	// StdDev is the standard deviation of those load-to-share ratios.
+	StdDev float64
+}
+
+// Distribution looks up the keys "0" to sampleKeys-1 and reports the share of
+// them each element receives. The sample is deterministic, so reports for the
+// same ring are comparable across runs.
+func (c *Consistent) Distribution(sampleKeys int) DistributionReport {
+	r := c.snapshot()
+	report := DistributionReport{Ratios: make(map[string]float64, len(r.members))}
+	var total float64
+	for k, w := range r.members {
+		report.Ratios[k] = 0
+		total += w
+	}
+	if len(r.hashes) == 0 || sampleKeys <= 0 || total <= 0 {
+		return report
+	}
+	for i := 0; i < sampleKeys; i++ {
+		report.Ratios[r.get(strconv.Itoa(i))]++
+	}
+	var sum, sumSq float64
+	n := 0
+	for k, cnt := range report.Ratios {
+		report.Ratios[k] = cnt / float64(sampleKeys)
+		if r.members[k] <= 0 {
+			continue
+		}
+		load := report.Ratios[k] / (r.members[k] / total)
+		report.PeakToMean = math.Max(report.PeakToMean, load)
+		sum += load
+		sumSq += load * load
+		n++
+	}
+	mean := sum / float64(n)
+	report.PeakToMean /= mean
+	report.StdDev = math.Sqrt(math.Max(sumSq/float64(n)-mean*mean, 0))
+	return report
+}
@@ -0,0 +1,60 @@
+package consistent
+
+import (
+	"math"
+	"testing"
+)
+
+func TestStats(t *testing.T) {
+	c := New(50)
+	c.Set(map[string]float64{"Host1": 1, "Host2": 3})
+	c.Get("a")
+	c.GetN("b", 2)
+	s := c.Stats()
+	if s.Members != 2 || s.VirtualNodes != len(c.circle) || s.Lookups != 2 {
+		t.Fatalf("Stats() = %+v", s)
+	}
+	if sum := s.Ownership["Host1"] + s.Ownership["Host2"]; sum < 0.999999 || sum > 1.000001 {
+		t.Fatalf("ownership sums to %v", sum)
+	}
+	if s.Ownership["Host2"] < s.Ownership["Host1"] {
+		t.Fatalf("heavier member owns less: %v", s.Ownership)
+	}
+}
+
+func TestDistribution(t *testing.T) {
+	c := New(200)
+	c.Set(map[string]float64{"Host1": 1, "Host2": 1, "Host3": 2})
+	d := c.Distribution(100000)
+	if d.Ratios["Host3"] < 0.4 || d.Ratios["Host3"] > 0.6 {
+		t.Fatalf("Host3 owns %.3f of keys, want about 0.5", d.Ratios["Host3"])
+	}
+	if d.PeakToMean < 1 || d.PeakToMean > 1.3 {
+		t.Fatalf("peak-to-mean = %.3f", d.PeakToMean)
+	}
+}
+
+func TestWeightError(t *testing.T) {
+	worst := func(c *Consistent) (w float64) {
+		for _, e := range c.WeightError() {
+			w = math.Max(w, math.Abs(e))
+		}
+		return w
+	}
+	members := map[string]float64{"A": 1, "B": 2, "C": 3}
+	low, high := New(2), New(200)
+	low.Set(members)
+	high.Set(members)
+	if e := high.WeightError(); len(e) != 3 {
+		t.Fatalf("errors %v", e)
+	}
+	if worst(high) >= worst(low) {
+		t.Fatalf("error with 200 replicas %v, with 2 %v", worst(high), worst(low))
+	}
+	if worst(high) > 0.2 {
+		t.Fatalf("error with 200 replicas is %v", worst(high))
+	}
+	if e := New(20).WeightError(); len(e) != 0 {
+		t.Fatalf("errors of an empty ring %v", e)
+	}
+}
@@ -0,0 +1,138 @@
+package consistent
+
+import (
+	"sync"
+	"time"
+)
+
+// StickyStore persists sticky key assignments for a Sticky router. It can
+// be backed by a shared store such as Redis so that every process honors
+// the same assignments.
+type StickyStore interface {
+	// Load returns the element key is assigned to, if it has an unexpired
+	// assignment.
+	Load(key string) (elt string, ok bool, err error)
+	// Store assigns key to elt for ttl.
+	Store(key, elt string, ttl time.Duration) error
+	// Delete removes the assignment of key.
+	Delete(key string) error
+}
+
+// Sticky routes keys through a Consistent but keeps each key on the element
+// it was first assigned to for a TTL, even if the ring changes in the
+// meantime: existing sessions stay put and only new keys follow the new
+// topology. An assignment to an element that is gone, unhealthy or drained
+// is replaced.
+type Sticky struct {
+	c     *Consistent
+	store StickyStore
+	ttl   time.Duration
+}
+
+// NewSticky returns a Sticky router over c keeping assignments in store for
+// ttl after they are made.
+func NewSticky(c *Consistent, store StickyStore, ttl time.Duration) *Sticky {
+	return &Sticky{c: c, store: store, ttl: ttl}
+}
+
+// Get returns the element key is assigned to, assigning it the owner of
+// key on the ring if it has no usable assignment.
+func (s *Sticky) Get(key string) (string, error) {
+	elt, ok, err := s.store.Load(key)
+	if err != nil {
+		return "", err
+	}
+	if ok {
+		r := s.c.snapshot()
+		if _, present := r.members[elt]; present && !r.skip(elt) {
+			return elt, nil
+		}
+	}
+	elt, err = s.c.Get(key)
+	if err != nil {
+		return "", err
+	}
+	return elt, s.store.Store(key, elt, s.ttl)
+}
+
+// Forget removes the assignment of key, returning it to ring placement.
+func (s *Sticky) Forget(key string) error {
+	return s.store.Delete(key)
+}
+
+// MemoryStickyStore is an in-process StickyStore. Expired assignments are
+// swept by Store whenever the map has doubled since the last sweep, so it
+// holds at most about twice the unexpired assignments without Purge.
+type MemoryStickyStore struct {
+	mu      sync.Mutex
+	entries map[string]stickyEntry
+	sweepAt int // len(entries) at which Store next purges
+}
+
+// minStickySweep is the smallest map size at which Store purges.
+const minStickySweep = 64
+
+type stickyEntry struct {
+	elt     string
+	expires time.Time
+}
+
+// NewMemoryStickyStore returns an empty MemoryStickyStore.
+func NewMemoryStickyStore() *MemoryStickyStore {
+	return &MemoryStickyStore{entries: make(map[string]stickyEntry), sweepAt: minStickySweep}
+}
+
+// Load implements StickyStore.
+func (m *MemoryStickyStore) Load(key string) (string, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.entries[key]
+	if !ok {
+		return "", false, nil
+	}
+	if time.Now().After(e.expires) {
+		delete(m.entries, key)
+		return "", false, nil
+	}
+	return e.elt, true, nil
+}
+
+// Store implements StickyStore.
+func (m *MemoryStickyStore) Store(key, elt string, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.entries[key]; !ok && len(m.entries) >= m.sweepAt {
+		m.purge()
+		m.sweepAt = max(2*len(m.entries), minStickySweep)
+	}
+	m.entries[key] = stickyEntry{elt, time.Now().Add(ttl)}
+	return nil
+}
+
+// Delete implements StickyStore.
+func (m *MemoryStickyStore) Delete(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.entries, key)
+	return nil
+}
+
+// Purge removes expired assignments. Store also does so as the store grows,
+// so calling it is only needed to release memory sooner.
+func (m *MemoryStickyStore) Purge() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.purge()
+}
+
+// purge removes expired assignments.
+//
+// need m.mu held before calling
+func (m *MemoryStickyStore) purge() {
+	now := time.Now()
+	for k, e := range m.entries {
+		if now.After(e.expires) {
+			delete(m.entries, k)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package consistent
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestSticky(t *testing.T) {
+	c := New(50)
+	c.Set(map[string]float64{"A": 1, "B": 1})
+	s := NewSticky(c, NewMemoryStickyStore(), 50*time.Millisecond)
+	before := map[string]string{}
+	for i := 0; i < 200; i++ {
+		key := fmt.Sprint(i)
+		before[key], _ = s.Get(key)
+	}
+	c.Add("C", 2)
+	for key, m := range before {
+		if got, _ := s.Get(key); got != m {
+			t.Fatalf("key %s moved from %s to %s while sticky", key, m, got)
+		}
+	}
+	if m, _ := s.Get("new-key"); m != c.snapshot().get("new-key") {
+		t.Fatalf("new key assigned to %s", m)
+	}
+
+	c.SetHealthy("A", false)
+	for key := range before {
+		if got, _ := s.Get(key); got == "A" {
+			t.Fatalf("key %s kept on unhealthy A", key)
+		}
+	}
+	c.SetHealthy("A", true)
+
+	s.Forget("0")
+	if got, _ := s.Get("0"); got != c.snapshot().get("0") {
+		t.Fatalf("forgotten key assigned to %s", got)
+	}
+	time.Sleep(60 * time.Millisecond)
+	for key := range before {
+		if got, _ := s.Get(key); got != c.snapshot().get(key) {
+			t.Fatalf("key %s still on %s after its assignment expired", key, got)
+		}
+	}
+}
+
+func TestMemoryStickyStoreSweeps(t *testing.T) {
+	m := NewMemoryStickyStore()
+	for i := 0; i < 10000; i++ {
+		m.Store(fmt.Sprint(i), "A", -time.Second)
+	}
+	if n := len(m.entries); n > minStickySweep {
+		t.Fatalf("%d expired assignments kept, want at most %d", n, minStickySweep)
+	}
+	for i := 0; i < 1000; i++ {
+		m.Store(fmt.Sprint("live", i), "A", time.Hour)
+	}
+	if _, ok, _ := m.Load("live0"); !ok {
+		t.Fatal("sweep dropped an unexpired assignment")
+	}
+	if n := len(m.entries); n > 2*1000 {
+		t.Fatalf("%d assignments kept for 1000 live ones", n)
+	}
+}
@@ -0,0 +1,64 @@
+package consistent
+
+import "strings"
+
+// HashTag returns the Redis-style hash tag of key: the text between the
+// first '{' and the next '}' if it is not empty, or else the whole key.
+// Keys like "{user1000}.following" and "{user1000}.followers" share the
+// tag "user1000".
+func HashTag(key string) string {
+	if i := strings.IndexByte(key, '{'); i >= 0 {
+		if j := strings.IndexByte(key[i+1:], '}'); j > 0 {
+			return key[i+1 : i+1+j]
+		}
+	}
+	return key
+}
+
+// PrefixTag returns a KeyTag taking the part of a key before the first sep,
+// or the whole key if it does not contain sep. With PrefixTag(":") the keys
+// "user1000:cart" and "user1000:orders" share the tag "user1000".
+func PrefixTag(sep string) func(key string) string {
+	return func(key string) string {
+		if i := strings.Index(key, sep); i >= 0 {
+			return key[:i]
+		}
+		return key
+	}
+}
+
+func (r *ring) tag(key string) string {
+	if r.keyTag != nil {
+		return r.keyTag(key)
+	}
+	return HashTag(key)
+}
+
+// GetTagged is like Get but hashes only the tag KeyTag extracts from key, so
+// that all keys with the same tag, for example the keys of a multi-key
+// transaction, map to the same element.
+func (c *Consistent) GetTagged(key string) (string, error) {
+	r := c.snapshot()
+	start := r.lookupStart()
+	c.countLookup()
+	tag := r.tag(key)
+	m, err := r.getOne(tag)
+	if r.observers != nil {
+		// Observers see the tag, which is what was looked up.
+		r.observeLookup("GetTagged", tag, r.primary(tag), start, err, m)
+	}
+	return m, err
+}
+
+// GetByPrefix returns the element that GetTagged maps every key tagged with
+// prefix to, without a key to extract the tag from.
+func (c *Consistent) GetByPrefix(prefix string) (string, error) {
+	r := c.snapshot()
+	start := r.lookupStart()
+	c.countLookup()
+	m, err := r.getOne(prefix)
+	if r.observers != nil {
+		r.observeLookup("GetByPrefix", prefix, r.primary(prefix), start, err, m)
+	}
+	return m, err
+}
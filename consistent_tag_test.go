@@ -0,0 +1,35 @@
+package consistent
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestGetTagged(t *testing.T) {
+	c := New(50)
+	c.Set(map[string]float64{"A": 1, "B": 2, "C": 1})
+	for i := 0; i < 100; i++ {
+		user := fmt.Sprintf("user%d", i)
+		want, _ := c.GetByPrefix(user)
+		for _, key := range []string{"{" + user + "}.following", "x{" + user + "}", "{" + user + "}"} {
+			if got, _ := c.GetTagged(key); got != want {
+				t.Fatalf("GetTagged(%q) = %s, want %s", key, got, want)
+			}
+		}
+	}
+	if got, want := HashTag("{}.a"), "{}.a"; got != want {
+		t.Fatalf("HashTag of empty tag = %q, want %q", got, want)
+	}
+
+	c.KeyTag = PrefixTag(":")
+	c.Rebuild()
+	for i := 0; i < 100; i++ {
+		user := fmt.Sprintf("user%d", i)
+		want, _ := c.GetByPrefix(user)
+		for _, key := range []string{user + ":cart", user + ":orders:1", user} {
+			if got, _ := c.GetTagged(key); got != want {
+				t.Fatalf("GetTagged(%q) = %s, want %s", key, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package consistent
+
+import (
+	"errors"
+	"math"
+)
+
+// AutoTuneNodeLimit is the largest number of virtual nodes AutoTuneReplicas
+// will place on a ring.
+const AutoTuneNodeLimit = 1 << 20
+
+// ErrTuneLimit is the error returned by AutoTuneReplicas when the target
+// cannot be met within AutoTuneNodeLimit virtual nodes, or when raising the
+// setting no longer adds virtual nodes, as in KetamaMode or when every
+// element has its own replica count.
+var ErrTuneLimit = errors.New("virtual node limit reached before target error")
+
+// AutoTuneReplicas doubles NumberOfReplicas, or VirtualNodeBudget when it is
+// set, until the largest absolute WeightError of the current elements is at
+// most targetMaxError, and rebuilds the ring with the result. It returns the
+// new setting. If the target is not met within AutoTuneNodeLimit virtual
+// nodes, or a doubling adds no virtual nodes, it keeps the largest setting
+// that helped and returns ErrTuneLimit.
+func (c *Consistent) AutoTuneReplicas(targetMaxError float64) (int, error) {
+	c.RLock()
+	members := make(map[string]float64, len(c.members))
+	for elt, wgt := range c.members {
+		members[elt] = wgt
+	}
+	budgeted := c.budgeted()
+	setting := c.conf().replicas
+	if budgeted {
+		setting = c.conf().budget
+	}
+	nodes := len(c.snapshot().hashes)
+	c.RUnlock()
+	if len(members) == 0 {
+		return setting, ErrEmptyCircle
+	}
+	if maxWeightError(c.WeightError()) <= targetMaxError {
+		return setting, nil
+	}
+
+	var err error
+	best := setting
+	for next := setting; ; {
+		if next > math.MaxInt32/2 {
+			err = ErrTuneLimit
+			break
+		}
+		next *= 2
+		trial, terr := c.withMembers(members)
+		if terr != nil {
+			return setting, terr
+		}
+		if budgeted {
+			if next > AutoTuneNodeLimit {
+				err = ErrTuneLimit
+				break
+			}
+			trial.VirtualNodeBudget = next
+		} else {
+			trial.NumberOfReplicas = next
+		}
+		trial.Rebuild()
+		n := len(trial.snapshot().hashes)
+		if n > AutoTuneNodeLimit || n <= nodes {
+			err = ErrTuneLimit
+			break
+		}
+		nodes = n
+		best = next
+		if maxWeightError(trial.WeightError()) <= targetMaxError {
+			break
+		}
+	}
+	if best == setting {
+		return setting, err
+	}
+
+	c.lock()
+	defer c.Unlock()
+	cfg := *c.cfg
+	if budgeted {
+		cfg.budget = best
+		c.VirtualNodeBudget = best
+	} else {
+		cfg.replicas = best
+		c.NumberOfReplicas = best
+	}
+	c.cfg = &cfg
+	c.stale = true
+	c.updateSortedHashes()
+	return best, err
+}
+
+// maxWeightError returns the largest absolute value in errs.
+func maxWeightError(errs map[string]float64) float64 {
+	var worst float64
+	for _, e := range errs {
+		worst = math.Max(worst, math.Abs(e))
+	}
+	return worst
+}
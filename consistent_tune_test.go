@@ -0,0 +1,48 @@
+package consistent
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestAutoTuneReplicas(t *testing.T) {
+	c := New(2)
+	c.Set(map[string]float64{"A": 1, "B": 2, "C": 3})
+	n, err := c.AutoTuneReplicas(0.1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n <= 2 || c.NumberOfReplicas != n {
+		t.Fatalf("tuned to %d, NumberOfReplicas %d", n, c.NumberOfReplicas)
+	}
+	if e := maxWeightError(c.WeightError()); e > 0.1 {
+		t.Fatalf("error after tuning %v", e)
+	}
+	if m, err := c.AutoTuneReplicas(0.1); err != nil || m != n {
+		t.Fatalf("second tune %d, %v", m, err)
+	}
+	if _, err := c.AutoTuneReplicas(0); !errors.Is(err, ErrTuneLimit) {
+		t.Fatalf("unreachable target: %v", err)
+	}
+	if _, err := New(20).AutoTuneReplicas(0.1); !errors.Is(err, ErrEmptyCircle) {
+		t.Fatalf("empty ring: %v", err)
+	}
+}
+
+func TestAutoTuneReplicasNoNewNodes(t *testing.T) {
+	k := New(0, WithKetamaMode())
+	k.Set(map[string]float64{"A": 1, "B": 2, "C": 3})
+	if n, err := k.AutoTuneReplicas(0); !errors.Is(err, ErrTuneLimit) || n != k.NumberOfReplicas {
+		t.Fatalf("ketama: %d, %v", n, err)
+	}
+
+	c := New(2)
+	c.AddWithReplicas("A", 1, 7)
+	c.AddWithReplicas("B", 2, 3)
+	if n, err := c.AutoTuneReplicas(0); !errors.Is(err, ErrTuneLimit) || n != 2 {
+		t.Fatalf("overrides: %d, %v", n, err)
+	}
+	if c.NumberOfReplicas != 2 {
+		t.Fatalf("NumberOfReplicas changed to %d", c.NumberOfReplicas)
+	}
+}
@@ -0,0 +1,57 @@
+package consistent
+
+import "strconv"
+
+// splitmix64 hashes an integer key with the splitmix64 finalizer, folded to
+// 32 bits.
+func splitmix64(x uint64) uint32 {
+	x = mix64(x + 0x9e3779b97f4a7c15)
+	return uint32(x ^ x>>32)
+}
+
+// mix64 is the splitmix64 finalizer.
+func mix64(x uint64) uint64 {
+	x = (x ^ x>>30) * 0xbf58476d1ce4e5b9
+	x = (x ^ x>>27) * 0x94d049bb133111eb
+	return x ^ x>>31
+}
+
+// GetUint64 returns an element close to where the integer key hashes to in
+// the circle, without formatting key as a string. Integer keys are hashed
+// with Uint64Hasher, so GetUint64(42) and Get("42") are unrelated, and pins
+// do not apply to them.
+func (c *Consistent) GetUint64(key uint64) (string, error) {
+	r := c.snapshot()
+	start := r.lookupStart()
+	c.countLookup()
+	m, err := r.getUint64(key)
+	if r.observers != nil {
+		r.observeLookup("GetUint64", strconv.FormatUint(key, 10), r.owner(r.hash64(key)), start, err, m)
+	}
+	return m, err
+}
+
+// GetNUint64 returns the N closest distinct elements to where the integer
+// key hashes to in the circle.
+func (c *Consistent) GetNUint64(key uint64, n int) ([]string, error) {
+	r := c.snapshot()
+	start := r.lookupStart()
+	c.countLookup()
+	var res []string
+	err := ErrEmptyCircle
+	if len(r.hashes) > 0 {
+		res, err = r.getN(r.search(r.hash64(key)), min(n, len(r.members)-len(r.down)), nil)
+		err = atLeast(res, n, err)
+	}
+	if r.observers != nil {
+		r.observeLookup("GetNUint64", strconv.FormatUint(key, 10), r.owner(r.hash64(key)), start, err, res...)
+	}
+	return res, err
+}
+
+func (r *ring) getUint64(key uint64) (string, error) {
+	if len(r.hashes) == 0 {
+		return "", ErrEmptyCircle
+	}
+	return r.getHash(r.hash64(key))
+}
@@ -0,0 +1,33 @@
+package consistent
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestGetUint64(t *testing.T) {
+	c := New(20)
+	if _, err := c.GetUint64(1); !errors.Is(err, ErrEmptyCircle) {
+		t.Fatalf("GetUint64 on empty circle: %v", err)
+	}
+	c.Set(map[string]float64{"A": 1, "B": 1, "C": 1})
+	counts := map[string]int{}
+	for i := uint64(0); i < 3000; i++ {
+		m, err := c.GetUint64(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res, _ := c.GetNUint64(i, 2); len(res) != 2 || res[0] != m {
+			t.Fatalf("GetNUint64(%d) = %v, want %q first", i, res, m)
+		}
+		counts[m]++
+	}
+	for m, n := range counts {
+		if n < 500 {
+			t.Fatalf("%s got %d of 3000 sequential keys", m, n)
+		}
+	}
+	if n := testing.AllocsPerRun(100, func() { c.GetUint64(42) }); n != 0 {
+		t.Fatalf("GetUint64 allocates %v times", n)
+	}
+}
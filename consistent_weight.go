@@ -2,11 +2,16 @@ package consistent
 
 import (
 	"errors"
+	"fmt"
 	"hash/crc32"
-	"hash/fnv"
+	"maps"
+	"math"
+	"slices"
 	"sort"
 	"strconv"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 type uints []uint32
@@ -20,38 +25,179 @@ func (x uints) Less(i, j int) bool { return x[i] < x[j] }
 // Swap exchanges elements i and j.
 func (x uints) Swap(i, j int) { x[i], x[j] = x[j], x[i] }
 
-// ErrEmptyCircle is the error returned when trying to get an element when nothing has been added to hash.
+// ErrEmptyCircle is the error returned by every lookup when nothing has been
+// added to hash.
 var ErrEmptyCircle = errors.New("empty circle")
 
+// ErrInsufficientMembers is the error returned with the elements found when a
+// lookup asking for several distinct elements, like GetTwo or GetN, finds
+// fewer available elements than requested.
+var ErrInsufficientMembers = errors.New("insufficient members")
+
+// ErrInvalidWeight is the error returned when a weight is negative, NaN or infinite.
+var ErrInvalidWeight = errors.New("invalid weight")
+
+// ErrZeroWeight is the error returned when a weight is zero. A member with
+// zero weight would own no virtual node and never receive traffic.
+var ErrZeroWeight = errors.New("zero weight")
+
+// ErrMemberExists is the error returned when adding an element that is already in the hash.
+var ErrMemberExists = errors.New("member already exists")
+
+// ErrMemberNotFound is the error returned when updating an element that is not in the hash.
+var ErrMemberNotFound = errors.New("member not found")
+
+// ErrTraversalLimit is the error returned when a lookup visits MaxTraversal
+// virtual nodes without finding enough distinct elements.
+var ErrTraversalLimit = errors.New("traversal limit reached")
+
+// ErrChangeTooLarge is the error returned by the changes that add, set or
+// reweight elements when the change would move more than MaxMovedFraction of
+// the hash space.
+var ErrChangeTooLarge = errors.New("change moves too much of the hash space")
+
+// ErrNoMatchingMember is the error returned when no element in the circle passes a filter.
+var ErrNoMatchingMember = errors.New("no matching member")
+
+// Member is an element of the hash with its weight and failure domain.
 type Member struct {
 	Name   string
 	Weight float64
+	// Zone is the failure domain of the member, such as a zone or rack.
+	Zone string
+	// Tags are the labels GetWithTags matches.
+	Tags []string
 }
 
 // Consistent holds the information about the members of the consistent hash circle.
+// The zero value is an empty hash with 20 replicas for each entry, ready to use.
+//
+// The exported settings are frozen by the first change to the hash: writing
+// them afterwards has no effect until Rebuild is called, so they never race
+// with lookups and the circle is always searched with the settings it was
+// placed with. Prefer passing them to New as options, or to Configure, which
+// returns ErrSettingsFrozen instead of being ignored once they are frozen.
 type Consistent struct {
 	circle           map[uint32]string
+	collisions       map[uint32][]string
 	members          map[string]float64
-	sortedHashes     uints
+	meta             map[string]map[string]string
+	zones            map[string]string
+	tags             map[string][]string
+	unhealthy        map[string]bool
+	drained          map[string]bool
+	expired          map[string]bool
+	pins             map[string]string
+	replicas         map[string]int
+	dirty            uints
 	NumberOfReplicas int
-	count            int64
-	scratch          [64]byte
 	UseFnv           bool
+	// Hasher, when set, replaces the built-in CRC32 or FNV hash for both
+	// virtual node placement and key lookup. Set it before adding entries,
+	// or call Rebuild after changing it.
+	Hasher     func(key string) uint32
+	KetamaMode bool
+	// VirtualNodeBudget, when greater than 0, is the total number of virtual
+	// nodes of the circle, split across the elements in proportion to their
+	// weight instead of placing NumberOfReplicas per unit of weight. Memory
+	// stays bounded whatever the weights; elements too light for a single
+	// node are not reachable. It has no effect in KetamaMode. Set it before
+	// adding entries.
+	VirtualNodeBudget int
+	// Seed, when not 0, is mixed into the hashes of both virtual nodes and
+	// keys, so that rings with the same elements but different seeds place
+	// keys independently of each other, and the placement of keys cannot be
+	// predicted without the seed. It does not stop keys crafted to collide
+	// in the underlying hash; use a keyed Hasher for that. Set it before
+	// adding entries.
+	Seed uint64
+	// Uint64Hasher hashes the keys of GetUint64 and GetNUint64; nil means a
+	// splitmix64 mix. Set it before adding entries.
+	Uint64Hasher func(key uint64) uint32
+	// LoadFactor bounds the load of each element in GetWithLoad; 0 means
+	// DefaultLoadFactor. Values below 1 leave too little room for all the
+	// assignments in flight and are raised to 1.
+	LoadFactor float64
+	// MaxTraversal bounds the number of virtual nodes GetN and its variants
+	// visit looking for distinct elements; 0 means the whole circle. Lookups
+	// stopped by the bound return the elements found with ErrTraversalLimit.
+	// Set it before adding entries.
+	MaxTraversal int
+	// ZoneSpread makes ReplicaSet spread the replicas of a key over zones.
+	// Set it before adding entries.
+	ZoneSpread bool
+	// KeyTag extracts the part of a key GetTagged hashes, so that keys with
+	// the same tag map to the same element; nil means HashTag.
+	KeyTag func(key string) string
+	// MaxMovedFraction, when greater than 0, makes Add, AddMany, Set,
+	// SetMembers, UpdateWeight and the other changes that return an error
+	// reject with ErrChangeTooLarge, before the ring changes, a change that
+	// would move more than this fraction of the hash space to new owners,
+	// guarding against mistyped weights. Remove, health changes and slow
+	// start steps are not checked. Checking a change costs a trial rebuild
+	// of the ring. Use ApplyGradually to make larger changes in bounded
+	// steps.
+	MaxMovedFraction float64
+	// Logger, if set, receives a record of every membership change and
+	// lease expiry, with the share of the hash space the member owned
+	// before and after the change. Set it before making changes.
+	Logger Logger
+	// HistorySize, when greater than 0, is the number of most recent
+	// membership changes History keeps. Set it before making changes.
+	HistorySize int
+	// ExpireToUnhealthy makes an element whose AddWithTTL lease runs out
+	// unhealthy instead of removing it.
+	ExpireToUnhealthy bool
+	loadMu            sync.Mutex
+	load              loadTracker
+	stale             bool
+	owned             uint8 // copy-on-write maps already copied, see setEntry
+	observers         []Observer
+	lookups           lookupCounter
+	ramps             map[string]*ramp
+	rampDue           atomic.Uint64
+	leases            map[string]*lease
+	alloc             map[string]int
+	realloc           bool
+	lastRebuild       time.Duration
+	changes           []Change
+	history           []HistoryEntry
+	cfg               *settings
+	waitMu            sync.Mutex
+	waitCh            chan struct{}
+	ring              atomic.Pointer[ring]
 	sync.RWMutex
 }
 
 // New creates a new Consistent object with a default setting of 20 replicas for each entry.
+// The options are applied in order before New returns.
 //
-// To change the number of replicas, set NumberOfReplicas before adding entries.
-func New(numberOfReplicas int) *Consistent {
+// To change the number of replicas, pass WithReplicas or set NumberOfReplicas
+// before adding entries.
+func New(numberOfReplicas int, opts ...Option) *Consistent {
 	if numberOfReplicas <= 0 {
 		numberOfReplicas = 20
 	}
 	c := new(Consistent)
 	c.NumberOfReplicas = numberOfReplicas
+	c.lazyInit()
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// lazyInit allocates the maps of a Consistent that was not created by New,
+// so that the zero value works.
+//
+// need c.Lock() before calling
+func (c *Consistent) lazyInit() {
+	if c.members != nil {
+		return
+	}
 	c.circle = make(map[uint32]string)
+	c.collisions = make(map[uint32][]string)
 	c.members = make(map[string]float64)
-	return c
 }
 
 // eltKey generates a string key for an element with an index.
@@ -60,11 +206,97 @@ func (c *Consistent) eltKey(elt string, idx int) string {
 	return strconv.Itoa(idx) + elt
 }
 
-// Add inserts a string element in the consistent hash.
-func (c *Consistent) Add(elt string, wgt float64) {
-	c.Lock()
+// validateWeight returns an error if wgt cannot be used as the weight of an element.
+func validateWeight(wgt float64) error {
+	switch {
+	case math.IsNaN(wgt) || math.IsInf(wgt, 0) || wgt < 0:
+		return fmt.Errorf("%w: %v", ErrInvalidWeight, wgt)
+	case wgt == 0:
+		return ErrZeroWeight
+	}
+	return nil
+}
+
+// validateWeights returns an error naming the first element of eltMap with an
+// unusable weight.
+func validateWeights(eltMap map[string]float64) error {
+	for elt, wgt := range eltMap {
+		if err := validateWeight(wgt); err != nil {
+			return fmt.Errorf("%s: %w", elt, err)
+		}
+	}
+	return nil
+}
+
+// Add inserts a string element in the consistent hash, labelled with tags. It
+// returns ErrMemberExists if elt is already present.
+func (c *Consistent) Add(elt string, wgt float64, tags ...string) error {
+	if err := validateWeight(wgt); err != nil {
+		return err
+	}
+	c.lock()
 	defer c.Unlock()
+	if _, ok := c.members[elt]; ok {
+		return ErrMemberExists
+	}
+	if err := c.checkMoved(adder(elt, wgt)); err != nil {
+		return err
+	}
+	c.add(elt, wgt)
+	c.setTags(elt, tags)
+	c.updateSortedHashes()
+	return nil
+}
+
+// AddWithReplicas is like Add but gives elt exactly replicas virtual nodes
+// whatever its weight, for example a single node for a tiny canary. The
+// weight is still reported by Weight and used by weight statistics. The
+// override has no effect in KetamaMode or with VirtualNodeBudget.
+func (c *Consistent) AddWithReplicas(elt string, wgt float64, replicas int) error {
+	if err := validateWeight(wgt); err != nil {
+		return err
+	}
+	if replicas < 0 {
+		return fmt.Errorf("invalid replica count %d", replicas)
+	}
+	c.lock()
+	defer c.Unlock()
+	if _, ok := c.members[elt]; ok {
+		return ErrMemberExists
+	}
+	if err := c.checkMoved(adder(elt, wgt)); err != nil {
+		return err
+	}
+	setEntry(c, ownReplicas, &c.replicas, elt, replicas, false)
 	c.add(elt, wgt)
+	c.updateSortedHashes()
+	return nil
+}
+
+// AddMany inserts all elements of eltMap in the consistent hash with a single
+// ring rebuild. Elements already present are left untouched. Nothing is added
+// if any weight is invalid.
+func (c *Consistent) AddMany(eltMap map[string]float64) error {
+	if err := validateWeights(eltMap); err != nil {
+		return err
+	}
+	c.lock()
+	defer c.Unlock()
+	err := c.checkMoved(func(m map[string]float64) {
+		for elt, wgt := range eltMap {
+			if _, ok := m[elt]; !ok {
+				m[elt] = wgt
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+	for elt, wgt := range eltMap {
+		c.add(elt, wgt)
+	}
+	c.updateSortedHashes()
+	return nil
 }
 
 // need c.Lock() before calling
@@ -72,19 +304,44 @@ func (c *Consistent) add(elt string, wgt float64) {
 	if _, ok := c.members[elt]; ok {
 		return
 	}
-	for i := 0; i < int(float64(c.NumberOfReplicas)*wgt); i++ {
-		c.circle[c.hashKey(c.eltKey(elt, i))] = elt
+	c.lazyInit()
+	c.recordChange(MemberAdded, elt, 0, wgt)
+	if c.cfg.ketama {
+		c.members[elt] = wgt
+		c.stale = true
+		return
+	}
+	if c.budgeted() {
+		c.members[elt] = wgt
+		c.realloc = true
+		return
+	}
+	for i, n := 0, c.nodeCount(elt, wgt); i < n; i++ {
+		c.setNode(c.hashKey(c.eltKey(elt, i)), elt)
 	}
 	c.members[elt] = wgt
-	c.updateSortedHashes()
-	c.count++
 }
 
-// Remove removes an element from the hash.
-func (c *Consistent) Remove(elt string) {
-	c.Lock()
+// Remove removes an element from the hash and reports whether it was present.
+func (c *Consistent) Remove(elt string) bool {
+	c.lock()
 	defer c.Unlock()
+	if _, ok := c.members[elt]; !ok {
+		return false
+	}
 	c.remove(elt)
+	c.updateSortedHashes()
+	return true
+}
+
+// RemoveMany removes all elts from the hash with a single ring rebuild.
+func (c *Consistent) RemoveMany(elts []string) {
+	c.lock()
+	defer c.Unlock()
+	for _, elt := range elts {
+		c.remove(elt)
+	}
+	c.updateSortedHashes()
 }
 
 // need c.Lock() before calling
@@ -93,19 +350,50 @@ func (c *Consistent) remove(elt string) {
 	if !ok {
 		return
 	}
-	for i := 0; i < int(float64(c.NumberOfReplicas)*wgt); i++ {
-		delete(c.circle, c.hashKey(c.eltKey(elt, i)))
+	c.recordChange(MemberRemoved, elt, wgt, 0)
+	n := c.nodeCount(elt, wgt)
+	setEntry(c, ownReplicas, &c.replicas, elt, 0, true)
+	c.setMeta(elt, nil)
+	c.setZone(elt, "")
+	c.setTags(elt, nil)
+	setEntry(c, ownUnhealthy, &c.unhealthy, elt, false, true)
+	setEntry(c, ownDrained, &c.drained, elt, false, true)
+	setEntry(c, ownExpired, &c.expired, elt, false, true)
+	c.cancelRamp(elt)
+	c.cancelLease(elt)
+	if c.cfg.ketama {
+		delete(c.members, elt)
+		c.stale = true
+		return
+	}
+	if c.budgeted() {
+		delete(c.members, elt)
+		c.realloc = true
+		return
+	}
+	for i := 0; i < n; i++ {
+		c.deleteNode(c.hashKey(c.eltKey(elt, i)), elt)
 	}
 	delete(c.members, elt)
-	c.updateSortedHashes()
-	c.count--
 }
 
-// UpdateWeight update weight.
-func (c *Consistent) UpdateWeight(elt string, wgt float64) {
-	c.Lock()
+// UpdateWeight update weight. It returns ErrMemberNotFound if elt is not present.
+func (c *Consistent) UpdateWeight(elt string, wgt float64) error {
+	if err := validateWeight(wgt); err != nil {
+		return err
+	}
+	c.lock()
 	defer c.Unlock()
+	if _, ok := c.members[elt]; !ok {
+		return ErrMemberNotFound
+	}
+	if err := c.checkMoved(func(m map[string]float64) { m[elt] = wgt }); err != nil {
+		return err
+	}
+	c.cancelRamp(elt)
 	c.updateWeight(elt, wgt)
+	c.updateSortedHashes()
+	return nil
 }
 
 // need c.Lock() before calling
@@ -117,195 +405,506 @@ func (c *Consistent) updateWeight(elt string, newWgt float64) {
 	if newWgt == oldWgt {
 		return
 	}
-	if newWgt > oldWgt {
-		for i := int(float64(c.NumberOfReplicas) * oldWgt); i < int(float64(c.NumberOfReplicas)*newWgt); i++ {
-			c.circle[c.hashKey(c.eltKey(elt, i))] = elt
-		}
-	} else {
-		for i := int(float64(c.NumberOfReplicas) * newWgt); i < int(float64(c.NumberOfReplicas)*oldWgt); i++ {
-			delete(c.circle, c.hashKey(c.eltKey(elt, i)))
-		}
+	c.recordChange(WeightChanged, elt, oldWgt, newWgt)
+	if c.cfg.ketama {
+		c.members[elt] = newWgt
+		c.stale = true
+		return
+	}
+	if c.budgeted() {
+		c.members[elt] = newWgt
+		c.realloc = true
+		return
+	}
+	oldN, newN := c.nodeCount(elt, oldWgt), c.nodeCount(elt, newWgt)
+	for i := oldN; i < newN; i++ {
+		c.setNode(c.hashKey(c.eltKey(elt, i)), elt)
+	}
+	for i := newN; i < oldN; i++ {
+		c.deleteNode(c.hashKey(c.eltKey(elt, i)), elt)
 	}
 	c.members[elt] = newWgt
-	c.updateSortedHashes()
 }
 
 // Set sets all the elements in the hash.  If there are existing elements not
-// present in elts, they will be removed.
-func (c *Consistent) Set(eltMap map[string]float64) {
-	c.Lock()
+// present in elts, they will be removed. Nothing changes if any weight is
+// invalid. Elements ramping up after AddSlowStart keep ramping if eltMap gives
+// them their target weight.
+func (c *Consistent) Set(eltMap map[string]float64) error {
+	if err := validateWeights(eltMap); err != nil {
+		return err
+	}
+	c.lock()
 	defer c.Unlock()
-	for elt, wgt := range c.members {
-		found := false
-		for newElt, newWgt := range eltMap {
-			if elt == newElt {
-				if wgt != newWgt {
-					c.updateWeight(elt, newWgt)
-				}
-				found = true
-				break
+	if err := c.checkMoved(c.setter(eltMap)); err != nil {
+		return err
+	}
+	c.set(eltMap)
+	c.updateSortedHashes()
+	return nil
+}
+
+// setter returns the edit set makes to the members of c, for checkMoved.
+// Elements still ramping up to their weight in eltMap keep their current one.
+//
+// need c.Lock() before calling
+func (c *Consistent) setter(eltMap map[string]float64) func(map[string]float64) {
+	return func(m map[string]float64) {
+		clear(m)
+		for elt, wgt := range eltMap {
+			if r := c.ramps[elt]; r != nil && r.target == wgt {
+				wgt = c.members[elt]
 			}
+			m[elt] = wgt
 		}
-		if !found {
+	}
+}
+
+// checkMoved returns ErrChangeTooLarge if applying edit to the members of c
+// would move more than MaxMovedFraction of the hash space. Every change that
+// can fail calls it before touching the circle, so a rejected change is
+// never published. Keys have no owner to move from on an empty circle, so
+// the first members can always be added.
+//
+// need c.Lock() before calling
+func (c *Consistent) checkMoved(edit func(members map[string]float64)) error {
+	if c.cfg.maxMovedFraction <= 0 || len(c.members) == 0 {
+		return nil
+	}
+	members := maps.Clone(c.members)
+	if members == nil {
+		members = make(map[string]float64)
+	}
+	edit(members)
+	if f := c.movedFraction(members); f > c.cfg.maxMovedFraction {
+		return fmt.Errorf("%w: %.2f%% of it", ErrChangeTooLarge, f*100)
+	}
+	return nil
+}
+
+// adder returns the edit adding elt with weight wgt, for checkMoved.
+func adder(elt string, wgt float64) func(map[string]float64) {
+	return func(m map[string]float64) { m[elt] = wgt }
+}
+
+// Rebuild discards every virtual node and places the elements again with the
+// settings currently in the exported fields, freezing them anew. Call it to
+// apply settings changed after the hash was first changed, or to recover a
+// ring that fails CheckInvariants.
+func (c *Consistent) Rebuild() {
+	c.Lock()
+	defer c.Unlock()
+	c.cfg = c.exported()
+	c.stale = true
+	c.updateSortedHashes()
+}
+
+// set makes the elements and weights of the hash those of eltMap.
+//
+// need c.Lock() before calling
+func (c *Consistent) set(eltMap map[string]float64) {
+	for elt, r := range c.ramps {
+		if eltMap[elt] != r.target {
+			c.cancelRamp(elt)
+		}
+	}
+	for elt := range c.members {
+		if _, ok := eltMap[elt]; !ok {
 			c.remove(elt)
 		}
 	}
-	for newElt, newWgt := range eltMap {
-		oldWgt, exists := c.members[newElt]
-		if exists {
-			if oldWgt != newWgt {
-				c.updateWeight(newElt, newWgt)
-			}
-			continue
+	for elt, wgt := range eltMap {
+		if _, ok := c.members[elt]; !ok {
+			c.add(elt, wgt)
+		} else if c.ramps[elt] == nil {
+			c.updateWeight(elt, wgt)
 		}
-		c.add(newElt, newWgt)
 	}
 }
 
-func (c *Consistent) Members() []string {
+// Clone returns an independent copy of c with the same elements, weights and
+// settings, for example to try out a change without affecting lookups on c.
+// Observers, in-flight loads, slow starts and leases are not copied; elements
+// that are ramping up or leased keep their current weight and stay in the
+// copy for good.
+func (c *Consistent) Clone() *Consistent {
 	c.RLock()
 	defer c.RUnlock()
+	n := New(0)
+	n.cfg = c.conf()
+	n.cfg.export(n)
+	n.alloc = maps.Clone(c.alloc)
+	for h, elt := range c.circle {
+		n.circle[h] = elt
+	}
+	for h, elts := range c.collisions {
+		n.collisions[h] = slices.Clone(elts)
+	}
+	for elt, wgt := range c.members {
+		n.members[elt] = wgt
+	}
+	// These maps are copied on write, so the copy can share them.
+	n.meta = c.meta
+	n.zones = c.zones
+	n.tags = c.tags
+	n.unhealthy = c.unhealthy
+	n.drained = c.drained
+	n.expired = c.expired
+	n.pins = c.pins
+	n.replicas = c.replicas
+	r := c.snapshot()
+	n.publish(r.hashes, r.owners)
+	return n
+}
+
+// Members returns the names of all elements in the hash.
+func (c *Consistent) Members() []string {
+	r := c.snapshot()
 	var m []string
-	for k := range c.members {
+	for k := range r.members {
 		m = append(m, k)
 	}
 	return m
 }
 
-// Get returns an element close to where name hashes to in the circle.
-func (c *Consistent) Get(name string) (string, error) {
-	c.RLock()
-	defer c.RUnlock()
-	if len(c.circle) == 0 {
-		return "", ErrEmptyCircle
+// Weight returns the weight elt has in the hash, and whether it is in the
+// hash at all.
+func (c *Consistent) Weight(elt string) (float64, bool) {
+	w, ok := c.snapshot().members[elt]
+	return w, ok
+}
+
+// Contains reports whether elt is in the hash, whether or not it is healthy.
+func (c *Consistent) Contains(elt string) bool {
+	_, ok := c.snapshot().members[elt]
+	return ok
+}
+
+// Count returns the number of elements in the hash.
+func (c *Consistent) Count() int {
+	return len(c.snapshot().members)
+}
+
+// MemberInfo describes an element of the hash.
+type MemberInfo struct {
+	Name         string
+	Weight       float64
+	VirtualNodes int
+}
+
+// MemberInfos returns every element with its weight and the number of virtual
+// nodes it owns, sorted by name.
+func (c *Consistent) MemberInfos() []MemberInfo {
+	r := c.snapshot()
+	nodes := make(map[string]int, len(r.members))
+	for _, o := range r.owners {
+		nodes[o]++
+	}
+	infos := make([]MemberInfo, 0, len(r.members))
+	for name, wgt := range r.members {
+		infos = append(infos, MemberInfo{Name: name, Weight: wgt, VirtualNodes: nodes[name]})
 	}
-	key := c.hashKey(name)
-	i := c.search(key)
-	return c.circle[c.sortedHashes[i]], nil
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos
 }
 
-func (c *Consistent) search(key uint32) (i int) {
-	f := func(x int) bool {
-		return c.sortedHashes[x] > key
+// Hash returns the position of key on the ring, using the ring's hash function.
+func (c *Consistent) Hash(key string) uint32 {
+	r := c.snapshot()
+	if r.hash == nil {
+		return c.hashKey(key)
 	}
-	i = sort.Search(len(c.sortedHashes), f)
-	if i >= len(c.sortedHashes) {
-		i = 0
+	return r.hash(key)
+}
+
+// VirtualNodes returns the number of virtual nodes owned by each element.
+// Elements without any virtual node are reported with 0.
+func (c *Consistent) VirtualNodes() map[string]int {
+	r := c.snapshot()
+	m := make(map[string]int, len(r.members))
+	for k := range r.members {
+		m[k] = 0
 	}
-	return
+	for _, o := range r.owners {
+		m[o]++
+	}
+	return m
 }
 
-// GetTwo returns the two closest distinct elements to the name input in the circle.
-func (c *Consistent) GetTwo(name string) (string, string, error) {
-	c.RLock()
-	defer c.RUnlock()
-	if len(c.circle) == 0 {
-		return "", "", ErrEmptyCircle
+// Get returns an element close to where name hashes to in the circle.
+func (c *Consistent) Get(name string) (string, error) {
+	r := c.snapshot()
+	start := r.lookupStart()
+	c.countLookup()
+	m, err := r.getOne(name)
+	if r.observers != nil {
+		r.observeLookup("Get", name, r.primary(name), start, err, m)
 	}
-	key := c.hashKey(name)
-	i := c.search(key)
-	a := c.circle[c.sortedHashes[i]]
+	return m, err
+}
 
-	if c.count == 1 {
-		return a, "", nil
+// GetFiltered returns the first element at or after where name hashes to in the
+// circle for which accept returns true.
+func (c *Consistent) GetFiltered(name string, accept func(member string) bool) (string, error) {
+	r := c.snapshot()
+	start := r.lookupStart()
+	c.countLookup()
+	m, err := r.getFiltered(name, accept)
+	if r.observers != nil {
+		r.observeLookup("GetFiltered", name, r.primary(name), start, err, m)
 	}
+	return m, err
+}
 
-	start := i
-	var b string
-	for i = start + 1; i != start; i++ {
-		if i >= len(c.sortedHashes) {
-			i = 0
-		}
-		b = c.circle[c.sortedHashes[i]]
-		if b != a {
-			break
-		}
+// GetTwo returns the two closest distinct elements to the name input in the
+// circle. With a single available element it returns it, "" and
+// ErrInsufficientMembers.
+func (c *Consistent) GetTwo(name string) (string, string, error) {
+	r := c.snapshot()
+	start := r.lookupStart()
+	c.countLookup()
+	a, b, err := r.getTwo(name)
+	if r.observers != nil {
+		r.observeLookup("GetTwo", name, r.primary(name), start, err, a, b)
 	}
-	return a, b, nil
+	return a, b, err
 }
 
 // GetN returns the N closest distinct elements to the name input in the circle.
-// weight = 0 can get
+// If fewer than n elements are available it returns them with
+// ErrInsufficientMembers.
 func (c *Consistent) GetN(name string, n int) ([]string, error) {
-	c.RLock()
-	defer c.RUnlock()
+	r := c.snapshot()
+	start := r.lookupStart()
+	c.countLookup()
+	res, err := r.getNExcluding(name, n, nil)
+	err = atLeast(res, n, err)
+	if r.observers != nil {
+		r.observeLookup("GetN", name, r.primary(name), start, err, res...)
+	}
+	return res, err
+}
 
-	if len(c.circle) == 0 {
-		return nil, nil
+// GetNExcluding returns the N closest distinct elements to the name input in the
+// circle, skipping any element listed in exclude. If fewer than n elements
+// are left it returns them with ErrInsufficientMembers.
+func (c *Consistent) GetNExcluding(name string, n int, exclude []string) ([]string, error) {
+	r := c.snapshot()
+	start := r.lookupStart()
+	c.countLookup()
+	res, err := r.getNExcluding(name, n, exclude)
+	err = atLeast(res, n, err)
+	if r.observers != nil {
+		r.observeLookup("GetNExcluding", name, r.primary(name), start, err, res...)
 	}
+	return res, err
+}
 
-	if c.count < int64(n) {
-		n = int(c.count)
+// GetAll returns all available distinct elements ordered by their distance to
+// the name input in the circle.
+func (c *Consistent) GetAll(name string) ([]string, error) {
+	r := c.snapshot()
+	start := r.lookupStart()
+	c.countLookup()
+	res, err := r.getNExcluding(name, len(r.members), nil)
+	err = atLeast(res, 1, err)
+	if r.observers != nil {
+		r.observeLookup("GetAll", name, r.primary(name), start, err, res...)
 	}
+	return res, err
+}
 
-	var (
-		key   = c.hashKey(name)
-		i     = c.search(key)
-		start = i
-		res   = make([]string, 0, n)
-		elem  = c.circle[c.sortedHashes[i]]
-	)
+func (c *Consistent) hashKey(key string) uint32 {
+	cfg := c.conf()
+	var h uint32
+	switch {
+	case cfg.ketama:
+		h = ketamaHash(key)
+	case cfg.hasher != nil:
+		h = cfg.hasher(key)
+	case cfg.fnv:
+		h = c.hashKeyFnv(key)
+	default:
+		h = c.hashKeyCRC32(key)
+	}
+	if cfg.seed != 0 {
+		h = seedHash(h, cfg.seed)
+	}
+	return h
+}
 
-	res = append(res, elem)
+// seedHash mixes seed into the hash h with the murmur3 finalizer. It is a
+// bijection, so it reorders the circle without adding collisions.
+func seedHash(h uint32, seed uint64) uint32 {
+	h ^= uint32(seed)
+	h ^= h >> 16
+	h *= 0x85ebca6b
+	h ^= h >> 13
+	h *= 0xc2b2ae35
+	h ^= h >> 16
+	return h ^ uint32(seed>>32)
+}
 
-	if len(res) == n {
-		return res, nil
-	}
+func (c *Consistent) hashKeyCRC32(key string) uint32 {
+	return crc32.ChecksumIEEE(stringBytes(key))
+}
 
-	for i = start + 1; i != start; i++ {
-		if i >= len(c.sortedHashes) {
-			i = 0
-		}
-		elem = c.circle[c.sortedHashes[i]]
-		if !sliceContainsMember(res, elem) {
-			res = append(res, elem)
-		}
-		if len(res) == n {
-			break
-		}
+// hashKeyFnv computes FNV-1a inline, as hash/fnv would allocate.
+func (c *Consistent) hashKeyFnv(key string) uint32 {
+	h := uint32(2166136261)
+	for i := 0; i < len(key); i++ {
+		h ^= uint32(key[i])
+		h *= 16777619
 	}
+	return h
+}
 
-	return res, nil
+// setNode claims the virtual node at h for elt. When several elements hash
+// to the same point, all of them are remembered and the smallest name owns
+// the node, so ownership does not depend on insertion order.
+//
+// need c.Lock() before calling
+func (c *Consistent) setNode(h uint32, elt string) {
+	c.dirty = append(c.dirty, h)
+	owner, ok := c.circle[h]
+	if !ok {
+		c.circle[h] = elt
+		return
+	}
+	claimants := c.collisions[h]
+	if claimants == nil {
+		claimants = []string{owner}
+	}
+	claimants = append(claimants, elt)
+	sort.Strings(claimants)
+	c.collisions[h] = claimants
+	c.circle[h] = claimants[0]
 }
 
-// GetAll returns the N closest distinct elements to the name input in the circle.
-func (c *Consistent) GetAll(name string) ([]string, error) {
-	return c.GetN(name, int(c.count))
+// deleteNode releases elt's claim on the virtual node at h.
+//
+// need c.Lock() before calling
+func (c *Consistent) deleteNode(h uint32, elt string) {
+	claimants, ok := c.collisions[h]
+	if !ok {
+		if owner, ok := c.circle[h]; ok && owner == elt {
+			delete(c.circle, h)
+			c.dirty = append(c.dirty, h)
+		}
+		return
+	}
+	i := slices.Index(claimants, elt)
+	if i < 0 {
+		return
+	}
+	claimants = slices.Delete(claimants, i, i+1)
+	c.circle[h] = claimants[0]
+	if len(claimants) == 1 {
+		delete(c.collisions, h)
+	} else {
+		c.collisions[h] = claimants
+	}
+	c.dirty = append(c.dirty, h)
 }
 
-func (c *Consistent) hashKey(key string) uint32 {
-	if c.UseFnv {
-		return c.hashKeyFnv(key)
+// updateSortedHashes splices the virtual nodes touched since the last call
+// into the sorted hashes of the current ring and publishes the result. Only
+// the dirty hashes are sorted; untouched runs are copied over in bulk.
+//
+// need c.Lock() before calling
+func (c *Consistent) updateSortedHashes() {
+	defer c.timeRebuild(time.Now())
+	if c.stale {
+		c.regenerate()
+		return
+	}
+	if c.realloc {
+		c.reallocate()
 	}
-	return c.hashKeyCRC32(key)
+	old := c.snapshot()
+	sort.Sort(c.dirty)
+	hashes := make([]uint32, 0, len(c.circle))
+	owners := make([]string, 0, len(c.circle))
+	i := 0
+	for j, h := range c.dirty {
+		if j > 0 && c.dirty[j-1] == h {
+			continue
+		}
+		k, found := slices.BinarySearch(old.hashes[i:], h)
+		hashes = append(hashes, old.hashes[i:i+k]...)
+		owners = append(owners, old.owners[i:i+k]...)
+		i += k
+		if found {
+			i++
+		}
+		if elt, ok := c.circle[h]; ok {
+			hashes = append(hashes, h)
+			owners = append(owners, elt)
+		}
+	}
+	hashes = append(hashes, old.hashes[i:]...)
+	owners = append(owners, old.owners[i:]...)
+	c.dirty = c.dirty[:0]
+	c.publish(hashes, owners)
 }
 
-func (c *Consistent) hashKeyCRC32(key string) uint32 {
-	if len(key) < 64 {
-		var scratch [64]byte
-		copy(scratch[:], key)
-		return crc32.ChecksumIEEE(scratch[:len(key)])
+// nodeHashes returns the hashes of elt's virtual nodes in replica order.
+//
+// need c.RLock() before calling
+func (c *Consistent) nodeHashes(elt string, wgt float64) []uint32 {
+	if c.conf().ketama {
+		return c.ketamaNodeHashes(elt, wgt)
+	}
+	n := c.nodeCount(elt, wgt)
+	hashes := make([]uint32, 0, max(n, 0))
+	for i := 0; i < n; i++ {
+		hashes = append(hashes, c.hashKey(c.eltKey(elt, i)))
 	}
-	return crc32.ChecksumIEEE([]byte(key))
+	return hashes
 }
 
-func (c *Consistent) hashKeyFnv(key string) uint32 {
-	h := fnv.New32a()
-	h.Write([]byte(key))
-	return h.Sum32()
+// nodeCount returns the number of virtual nodes of elt at weight wgt outside
+// KetamaMode.
+//
+// need c.RLock() before calling
+func (c *Consistent) nodeCount(elt string, wgt float64) int {
+	if c.budgeted() {
+		return c.alloc[elt]
+	}
+	if n, ok := c.replicas[elt]; ok {
+		return n
+	}
+	return int(float64(c.conf().replicas) * wgt)
 }
 
-func (c *Consistent) updateSortedHashes() {
-	hashes := c.sortedHashes[:0]
-	//reallocate if we're holding on to too much (1/4th)
-	if cap(c.sortedHashes)/(c.NumberOfReplicas*4) > len(c.circle) {
-		hashes = nil
+// regenerate discards every virtual node and places all members again from
+// scratch, then publishes the fully sorted ring.
+//
+// need c.Lock() before calling
+func (c *Consistent) regenerate() {
+	clear(c.circle)
+	clear(c.collisions)
+	if c.budgeted() {
+		c.alloc = c.allocate()
+		c.realloc = false
+	}
+	for elt, wgt := range c.members {
+		for _, h := range c.nodeHashes(elt, wgt) {
+			c.setNode(h, elt)
+		}
 	}
-	for k := range c.circle {
-		hashes = append(hashes, k)
+	hashes := make(uints, 0, len(c.circle))
+	for h := range c.circle {
+		hashes = append(hashes, h)
 	}
 	sort.Sort(hashes)
-	c.sortedHashes = hashes
+	owners := make([]string, len(hashes))
+	for i, h := range hashes {
+		owners[i] = c.circle[h]
+	}
+	c.dirty = c.dirty[:0]
+	c.stale = false
+	c.publish(hashes, owners)
 }
 
 func sliceContainsMember(set []string, member string) bool {
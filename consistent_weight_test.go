@@ -1,7 +1,13 @@
 package consistent
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"hash/crc32"
+	"math"
+	"sync"
 	"testing"
 )
 
@@ -15,8 +21,606 @@ func TestConsistentWeight(t *testing.T) {
 }
 
 func TestWeightedConsistent(t *testing.T) {
-	c := NewWeightedConsistent("123", 200, []Member{{"A10", 10}, {"B10", 10}, {"C100", 100}})
+	c := NewWeightedConsistent("123", 200, []Member{{Name: "A10", Weight: 10}, {Name: "B10", Weight: 10}, {Name: "C100", Weight: 100}})
 	for i := 0; i < 20; i++ {
 		fmt.Println(c.GetAll(fmt.Sprintf("%d", i)))
 	}
 }
+
+func TestGetNExcluding(t *testing.T) {
+	c := New(20)
+	c.Set(map[string]float64{"Host1": 1, "Host2": 1, "Host3": 1})
+	all, err := c.GetN("uri12", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := c.GetNExcluding("uri12", 3, []string{all[0]})
+	if err != ErrInsufficientMembers {
+		t.Fatalf("err = %v, want ErrInsufficientMembers", err)
+	}
+	if len(res) != 2 || res[0] != all[1] || res[1] != all[2] {
+		t.Fatalf("GetNExcluding = %v, want %v", res, all[1:])
+	}
+}
+
+func TestGetFiltered(t *testing.T) {
+	c := New(20)
+	c.Set(map[string]float64{"ssd1": 1, "hdd1": 1, "ssd2": 1})
+	for i := 0; i < 50; i++ {
+		m, err := c.GetFiltered(fmt.Sprint(i), func(m string) bool { return m[:3] == "ssd" })
+		if err != nil || m[:3] != "ssd" {
+			t.Fatalf("GetFiltered = %q, %v", m, err)
+		}
+	}
+	if _, err := c.GetFiltered("k", func(string) bool { return false }); err != ErrNoMatchingMember {
+		t.Fatalf("err = %v, want ErrNoMatchingMember", err)
+	}
+}
+
+func TestConcurrentGetDuringUpdates(t *testing.T) {
+	c := New(20)
+	c.Add("Host0", 1)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 1; i < 100; i++ {
+			c.Add(fmt.Sprintf("Host%d", i), 1)
+			c.Remove(fmt.Sprintf("Host%d", i-1))
+		}
+	}()
+	for i := 0; i < 1000; i++ {
+		if _, err := c.Get(fmt.Sprint(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	<-done
+}
+
+func TestIncrementalSortedHashes(t *testing.T) {
+	c := New(20)
+	for i := 0; i < 50; i++ {
+		c.Add(fmt.Sprintf("Host%d", i), float64(i%3+1))
+		if i%4 == 0 {
+			c.Remove(fmt.Sprintf("Host%d", i/2))
+		}
+		if i%5 == 0 {
+			c.UpdateWeight(fmt.Sprintf("Host%d", i/3), float64(i%7))
+		}
+	}
+	r := c.snapshot()
+	if len(r.hashes) != len(c.circle) {
+		t.Fatalf("ring has %d hashes, circle has %d", len(r.hashes), len(c.circle))
+	}
+	for i, h := range r.hashes {
+		if i > 0 && r.hashes[i-1] >= h {
+			t.Fatalf("hashes not sorted at %d", i)
+		}
+		if r.owners[i] != c.circle[h] {
+			t.Fatalf("owner of %d = %q, want %q", h, r.owners[i], c.circle[h])
+		}
+	}
+}
+
+func BenchmarkAdd(b *testing.B) {
+	c := New(200)
+	for i := 0; i < 1000; i++ {
+		c.Add(fmt.Sprintf("Host%d", i), 1)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.Add("extra", 1)
+		c.Remove("extra")
+	}
+}
+
+func BenchmarkGetParallel(b *testing.B) {
+	c := New(200)
+	for i := 0; i < 100; i++ {
+		c.Add(fmt.Sprintf("Host%d", i), 1)
+	}
+	keys := make([]string, 1024)
+	for i := range keys {
+		keys[i] = fmt.Sprint("key", i)
+	}
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for i := 0; pb.Next(); i++ {
+			c.Get(keys[i%len(keys)])
+		}
+	})
+}
+
+func TestAddManyRemoveMany(t *testing.T) {
+	c := New(20)
+	c.AddMany(map[string]float64{"Host1": 1, "Host2": 2, "Host3": 1})
+	d := New(20)
+	d.Add("Host1", 1)
+	d.Add("Host2", 2)
+	d.Add("Host3", 1)
+	for i := 0; i < 100; i++ {
+		a, _ := c.Get(fmt.Sprint(i))
+		b, _ := d.Get(fmt.Sprint(i))
+		if a != b {
+			t.Fatalf("key %d: AddMany -> %q, Add -> %q", i, a, b)
+		}
+	}
+	c.RemoveMany([]string{"Host1", "Host3"})
+	if m := c.Members(); len(m) != 1 || m[0] != "Host2" {
+		t.Fatalf("Members() = %v, want [Host2]", m)
+	}
+}
+
+func TestHashCollisionOwnership(t *testing.T) {
+	for _, order := range [][]string{{"b", "a", "c"}, {"c", "b", "a"}} {
+		c := New(1)
+		for _, elt := range order {
+			c.setNode(42, elt)
+		}
+		if c.circle[42] != "a" {
+			t.Fatalf("insertion order %v: owner = %q, want a", order, c.circle[42])
+		}
+		c.deleteNode(42, "a")
+		if c.circle[42] != "b" {
+			t.Fatalf("after removing a: owner = %q, want b", c.circle[42])
+		}
+		c.deleteNode(42, "b")
+		c.deleteNode(42, "c")
+		if _, ok := c.circle[42]; ok || len(c.collisions) != 0 {
+			t.Fatalf("node 42 still present after removing all claimants")
+		}
+	}
+}
+
+func TestWeightValidation(t *testing.T) {
+	c := New(20)
+	if err := c.Add("Host1", 0); err != ErrZeroWeight {
+		t.Fatalf("Add(0) = %v, want ErrZeroWeight", err)
+	}
+	if err := c.Add("Host1", -1); !errors.Is(err, ErrInvalidWeight) {
+		t.Fatalf("Add(-1) = %v, want ErrInvalidWeight", err)
+	}
+	if err := c.Set(map[string]float64{"Host1": 1, "Host2": math.NaN()}); !errors.Is(err, ErrInvalidWeight) {
+		t.Fatalf("Set(NaN) = %v, want ErrInvalidWeight", err)
+	}
+	if len(c.Members()) != 0 {
+		t.Fatalf("invalid Set applied members %v", c.Members())
+	}
+	c.Add("Host1", 1)
+	if err := c.UpdateWeight("Host1", 0); err != ErrZeroWeight {
+		t.Fatalf("UpdateWeight(0) = %v, want ErrZeroWeight", err)
+	}
+}
+
+func TestMutationResults(t *testing.T) {
+	c := New(20)
+	if err := c.Add("Host1", 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Add("Host1", 2); err != ErrMemberExists {
+		t.Fatalf("second Add = %v, want ErrMemberExists", err)
+	}
+	if err := c.UpdateWeight("Host2", 2); err != ErrMemberNotFound {
+		t.Fatalf("UpdateWeight(unknown) = %v, want ErrMemberNotFound", err)
+	}
+	if !c.Remove("Host1") || c.Remove("Host1") {
+		t.Fatal("Remove did not report presence correctly")
+	}
+}
+
+func TestMemberInfos(t *testing.T) {
+	c := New(10)
+	c.Set(map[string]float64{"Host2": 2, "Host1": 1})
+	infos := c.MemberInfos()
+	if len(infos) != 2 || infos[0].Name != "Host1" || infos[1].Weight != 2 || infos[1].VirtualNodes != 20 {
+		t.Fatalf("MemberInfos() = %+v", infos)
+	}
+}
+
+func TestCustomHasher(t *testing.T) {
+	calls := 0
+	c := New(10)
+	c.Hasher = func(key string) uint32 {
+		calls++
+		return crc32.ChecksumIEEE([]byte("salt" + key))
+	}
+	c.Add("Host1", 1)
+	c.Get("k")
+	if calls != 11 {
+		t.Fatalf("Hasher called %d times, want 11", calls)
+	}
+}
+
+func TestSetLarge(t *testing.T) {
+	c := New(20)
+	m := make(map[string]float64, 3000)
+	for i := 0; i < 3000; i++ {
+		m[fmt.Sprintf("Host%d", i)] = 1
+	}
+	c.Set(m)
+	delete(m, "Host0")
+	m["Host1"] = 2
+	m["Host3000"] = 1
+	c.Set(m)
+	vn := c.VirtualNodes()
+	if len(vn) != 3000 || vn["Host0"] != 0 || vn["Host1"] != 40 || vn["Host3000"] != 20 {
+		t.Fatalf("got %d members, Host0=%d Host1=%d Host3000=%d", len(vn), vn["Host0"], vn["Host1"], vn["Host3000"])
+	}
+}
+
+func TestGetNTraversalLimit(t *testing.T) {
+	c := New(20)
+	m := map[string]float64{}
+	for i := 0; i < 20; i++ {
+		m[fmt.Sprintf("Host%d", i)] = 1
+	}
+	c.Set(m)
+	res, err := c.GetN("key", 20)
+	if err != nil || len(res) != 20 {
+		t.Fatalf("GetN = %d members, %v", len(res), err)
+	}
+	seen := map[string]bool{}
+	for _, r := range res {
+		if seen[r] {
+			t.Fatalf("GetN returned %q twice", r)
+		}
+		seen[r] = true
+	}
+
+	c.MaxTraversal = 5
+	c.Rebuild()
+	c.Remove("Host0")
+	res, err = c.GetN("key", 19)
+	if !errors.Is(err, ErrTraversalLimit) || len(res) == 0 || len(res) > 5 {
+		t.Fatalf("GetN with MaxTraversal = %v, %v", res, err)
+	}
+}
+
+func TestClone(t *testing.T) {
+	c := New(20)
+	c.Set(map[string]float64{"A": 1, "B": 2, "C": 1})
+	c.Drain("C")
+	n := c.Clone()
+	for i := 0; i < 50; i++ {
+		a, _ := c.Get(fmt.Sprint(i))
+		b, _ := n.Get(fmt.Sprint(i))
+		if a != b {
+			t.Fatalf("clone maps %d to %q, original to %q", i, b, a)
+		}
+	}
+	n.Remove("A")
+	n.Undrain("C")
+	if len(c.Members()) != 3 || !c.Drained("C") {
+		t.Fatal("changing the clone changed the original")
+	}
+	if vn := c.VirtualNodes(); vn["A"] != 20 {
+		t.Fatalf("original lost virtual nodes of A: %d", vn["A"])
+	}
+}
+
+func TestSeed(t *testing.T) {
+	members := map[string]float64{"A": 1, "B": 1, "C": 1, "D": 1}
+	a, b := New(20), New(20)
+	a.Seed, b.Seed = 1, 2
+	a.Set(members)
+	b.Set(members)
+	same := 0
+	for i := 0; i < 1000; i++ {
+		x, _ := a.Get(fmt.Sprint(i))
+		y, _ := b.Get(fmt.Sprint(i))
+		if x == y {
+			same++
+		}
+	}
+	if same > 400 {
+		t.Fatalf("%d of 1000 keys map to the same member under different seeds", same)
+	}
+
+	var buf bytes.Buffer
+	a.WriteTo(&buf)
+	c := New(20)
+	if _, err := c.ReadFrom(&buf); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := json.Marshal(a)
+	d := New(20)
+	if err := json.Unmarshal(data, d); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 100; i++ {
+		want, _ := a.Get(fmt.Sprint(i))
+		x, _ := c.Get(fmt.Sprint(i))
+		y, _ := d.Get(fmt.Sprint(i))
+		if x != want || y != want {
+			t.Fatalf("decoded rings map %d to %q and %q, want %q", i, x, y, want)
+		}
+	}
+}
+
+func TestLookupErrors(t *testing.T) {
+	c := New(20)
+	b := NewBuilder(20)
+	f := b.Build()
+	for name, err := range map[string]error{
+		"Get":               second(c.Get("k")),
+		"GetTwo":            third(c.GetTwo("k")),
+		"GetN":              second(c.GetN("k", 2)),
+		"GetNExcluding":     second(c.GetNExcluding("k", 2, nil)),
+		"GetAll":            second(c.GetAll("k")),
+		"GetBytes":          second(c.GetBytes([]byte("k"))),
+		"GetTwoBytes":       third(c.GetTwoBytes([]byte("k"))),
+		"GetNBytes":         second(c.GetNBytes([]byte("k"), 2)),
+		"GetUint64":         second(c.GetUint64(1)),
+		"GetNUint64":        second(c.GetNUint64(1, 2)),
+		"GetNDistinctZones": second(c.GetNDistinctZones("k", 2)),
+		"FrozenRing.GetTwo": third(f.GetTwo("k")),
+		"FrozenRing.GetN":   second(f.GetN("k", 2)),
+	} {
+		if err != ErrEmptyCircle {
+			t.Errorf("%s on an empty ring: err = %v, want ErrEmptyCircle", name, err)
+		}
+	}
+
+	c.Add("A", 1)
+	b.Add("A", 1)
+	if m1, m2, err := c.GetTwo("k"); m1 != "A" || m2 != "" || err != ErrInsufficientMembers {
+		t.Errorf("GetTwo = %q, %q, %v", m1, m2, err)
+	}
+	for name, lookup := range map[string]func() ([]string, error){
+		"GetN":              func() ([]string, error) { return c.GetN("k", 2) },
+		"GetNBytes":         func() ([]string, error) { return c.GetNBytes([]byte("k"), 2) },
+		"GetNUint64":        func() ([]string, error) { return c.GetNUint64(1, 2) },
+		"GetNDistinctZones": func() ([]string, error) { return c.GetNDistinctZones("k", 2) },
+		"FrozenRing.GetN":   func() ([]string, error) { return b.Build().GetN("k", 2) },
+	} {
+		if res, err := lookup(); len(res) != 1 || err != ErrInsufficientMembers {
+			t.Errorf("%s = %v, %v, want [A] with ErrInsufficientMembers", name, res, err)
+		}
+	}
+	if res, err := c.GetAll("k"); len(res) != 1 || err != nil {
+		t.Errorf("GetAll = %v, %v", res, err)
+	}
+
+	c.SetHealthy("A", false)
+	if res, err := c.GetN("k", 2); res != nil || err != ErrNoAvailableMember {
+		t.Errorf("GetN with no available member = %v, %v", res, err)
+	}
+	if res, err := c.GetAll("k"); res != nil || err != ErrNoAvailableMember {
+		t.Errorf("GetAll with no available member = %v, %v", res, err)
+	}
+}
+
+func second[T any](_ T, err error) error { return err }
+
+func third[T, U any](_ T, _ U, err error) error { return err }
+
+func TestWeightContainsCount(t *testing.T) {
+	c := New(20)
+	if c.Count() != 0 || c.Contains("A") {
+		t.Fatal("empty ring reports members")
+	}
+	c.Set(map[string]float64{"A": 1, "B": 2.5})
+	c.SetHealthy("B", false)
+	if w, ok := c.Weight("B"); !ok || w != 2.5 {
+		t.Fatalf("Weight(B) = %v, %v", w, ok)
+	}
+	if _, ok := c.Weight("C"); ok {
+		t.Fatal("Weight reports a missing member")
+	}
+	if !c.Contains("B") || c.Contains("C") || c.Count() != 2 {
+		t.Fatalf("Contains(B) = %v, Contains(C) = %v, Count = %d", c.Contains("B"), c.Contains("C"), c.Count())
+	}
+	c.Remove("A")
+	if c.Contains("A") || c.Count() != 1 {
+		t.Fatal("removed member still counted")
+	}
+}
+
+func TestZeroValue(t *testing.T) {
+	var c Consistent
+	if _, err := c.Get("k"); err != ErrEmptyCircle {
+		t.Fatalf("Get on the zero value = %v", err)
+	}
+	if c.Remove("A") || c.UpdateWeight("A", 2) == nil {
+		t.Fatal("the zero value has members")
+	}
+	c.SetHealthy("A", false)
+	if err := c.Add("A", 1); err != nil {
+		t.Fatal(err)
+	}
+	c.AddMember(Member{Name: "B", Weight: 2, Zone: "z1"})
+	ref := New(20)
+	ref.Set(map[string]float64{"A": 1, "B": 2})
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprint(i)
+		got, _ := c.Get(key)
+		want, _ := ref.Get(key)
+		if got != want {
+			t.Fatalf("zero value maps %q to %s, New(20) to %s", key, got, want)
+		}
+	}
+	if err := c.CheckInvariants(); err != nil {
+		t.Fatal(err)
+	}
+
+	d := &Consistent{NumberOfReplicas: 50, KetamaMode: true}
+	d.Set(map[string]float64{"A": 1, "B": 1})
+	if d.NumberOfReplicas != 50 || d.Count() != 2 {
+		t.Fatalf("NumberOfReplicas = %d, Count = %d", d.NumberOfReplicas, d.Count())
+	}
+	if err := d.CheckInvariants(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSettingsFrozen(t *testing.T) {
+	c := New(20)
+	c.Set(map[string]float64{"A": 1, "B": 2, "C": 1})
+	c.NumberOfReplicas = 40
+	c.UseFnv = true
+	c.Remove("C")
+	if err := c.CheckInvariants(); err != nil {
+		t.Fatal(err)
+	}
+	ref := New(20)
+	ref.Set(map[string]float64{"A": 1, "B": 2})
+	if !c.Equal(ref) {
+		t.Fatal("settings written after the first change were applied")
+	}
+
+	c.Rebuild()
+	ref = New(40, WithFnv())
+	ref.Set(map[string]float64{"A": 1, "B": 2})
+	if err := c.CheckInvariants(); err != nil {
+		t.Fatal(err)
+	}
+	if !c.Equal(ref) {
+		t.Fatal("Rebuild did not apply NumberOfReplicas and UseFnv")
+	}
+	if got := c.Clone(); !got.Equal(ref) || got.NumberOfReplicas != 40 {
+		t.Fatalf("Clone has %d replicas", got.NumberOfReplicas)
+	}
+}
+
+func TestSettingsRace(t *testing.T) {
+	c := New(20)
+	c.Set(map[string]float64{"A": 1, "B": 2, "C": 1})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			c.Get(fmt.Sprint(i))
+			c.GetTagged(fmt.Sprint(i))
+			c.GetWithLoad(fmt.Sprint(i))
+			c.Add("D", 1)
+			c.Remove("D")
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		c.NumberOfReplicas = i + 1
+		c.LoadFactor = 2
+		c.KeyTag = HashTag
+		c.MaxTraversal = i
+	}
+	wg.Wait()
+	if err := c.CheckInvariants(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRebuild(t *testing.T) {
+	c := New(20)
+	c.Set(map[string]float64{"A": 1, "B": 2, "C": 1})
+	salted := func(key string) uint32 { return crc32.ChecksumIEEE([]byte("salt" + key)) }
+	c.Hasher = salted
+	c.Rebuild()
+	ref := New(20, WithHasher(salted))
+	ref.Set(map[string]float64{"A": 1, "B": 2, "C": 1})
+	if !c.Equal(ref) {
+		t.Fatal("Rebuild did not apply the new Hasher")
+	}
+
+	c.Lock()
+	for h := range c.circle {
+		delete(c.circle, h)
+		break
+	}
+	c.Unlock()
+	if c.CheckInvariants() == nil {
+		t.Fatal("corrupted ring passes CheckInvariants")
+	}
+	c.Rebuild()
+	if err := c.CheckInvariants(); err != nil {
+		t.Fatal(err)
+	}
+	if !c.Equal(ref) {
+		t.Fatal("Rebuild did not restore the ring")
+	}
+}
+
+func TestAddWithReplicas(t *testing.T) {
+	c := New(20)
+	c.Set(map[string]float64{"A": 1, "B": 1})
+	if err := c.AddWithReplicas("canary", 5, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.AddWithReplicas("bad", 1, -1); err == nil {
+		t.Fatal("negative replica count accepted")
+	}
+	nodes := func(elt string) int {
+		for _, info := range c.MemberInfos() {
+			if info.Name == elt {
+				return info.VirtualNodes
+			}
+		}
+		return -1
+	}
+	if n := nodes("canary"); n != 1 {
+		t.Fatalf("canary has %d virtual nodes, want 1", n)
+	}
+	c.UpdateWeight("canary", 10)
+	if w, _ := c.Weight("canary"); nodes("canary") != 1 || w != 10 {
+		t.Fatalf("after UpdateWeight canary has %d virtual nodes and weight %v", nodes("canary"), w)
+	}
+	if err := c.CheckInvariants(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, _ := json.Marshal(c)
+	var fromJSON Consistent
+	if err := json.Unmarshal(data, &fromJSON); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	c.WriteTo(&buf)
+	var fromBinary Consistent
+	if _, err := fromBinary.ReadFrom(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !fromJSON.Equal(c) || !fromBinary.Equal(c) {
+		t.Fatal("replica overrides lost in encoding")
+	}
+
+	c.Remove("canary")
+	c.Add("canary", 2)
+	if n := nodes("canary"); n != 40 {
+		t.Fatalf("re-added canary has %d virtual nodes, want 40", n)
+	}
+	if err := c.CheckInvariants(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMaxMovedFraction(t *testing.T) {
+	c := New(50, WithMaxMovedFraction(0.1))
+	if err := c.Set(map[string]float64{"A": 1, "B": 1, "C": 1, "D": 1}); err != nil {
+		t.Fatalf("Set on an empty ring: %v", err)
+	}
+	before := c.Clone()
+	if err := c.UpdateWeight("A", 1000); !errors.Is(err, ErrChangeTooLarge) {
+		t.Fatalf("UpdateWeight to 1000 = %v, want ErrChangeTooLarge", err)
+	}
+	if w, _ := c.Weight("A"); w != 1 || !c.Equal(before) {
+		t.Fatalf("rejected change was applied: weight %v", w)
+	}
+	for name, change := range map[string]func() error{
+		"Add":        func() error { return c.Add("E", 10) },
+		"AddMany":    func() error { return c.AddMany(map[string]float64{"E": 1, "F": 1}) },
+		"AddMember":  func() error { return c.AddMember(Member{Name: "E", Weight: 10}) },
+		"Set":        func() error { return c.Set(map[string]float64{"A": 1, "E": 1}) },
+		"SetMembers": func() error { return c.SetMembers([]Member{{Name: "A", Weight: 1}}) },
+	} {
+		if err := change(); !errors.Is(err, ErrChangeTooLarge) {
+			t.Fatalf("%s = %v, want ErrChangeTooLarge", name, err)
+		}
+		if !c.Equal(before) {
+			t.Fatalf("rejected %s was applied", name)
+		}
+	}
+	if err := c.UpdateWeight("A", 1.2); err != nil {
+		t.Fatalf("small change rejected: %v", err)
+	}
+	if w, _ := c.Weight("A"); w != 1.2 {
+		t.Fatalf("weight = %v after accepted change", w)
+	}
+}
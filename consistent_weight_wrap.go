@@ -1,18 +1,63 @@
 package consistent
 
 import (
+	"math"
 	"math/rand/v2"
+	"slices"
 	"sort"
+	"sync"
 )
 
+// Normalization 权重缩放方式, 零值为只按最小权重缩放
+type Normalization struct {
+	// Log 对数缩放: 缩放后的权重w变为1+ln(w), 权重相差悬殊时避免虚拟节点过多
+	Log bool
+	// MaxRatio 缩放后权重的上限(即最大与最小权重之比), 0为不限制
+	MaxRatio float64
+	// Budget 虚拟节点总数的上限, 0为不限制. 超出时所有权重等比例缩小,
+	// 权重过小分不到虚拟节点的成员不会被选中
+	Budget int
+}
+
 type WeightedConsistent struct {
 	name       string
 	c          *Consistent
+	norm       Normalization
 	rawMembers []Member
 	cMembers   map[string]float64
+	names      []string  // cMembers的key, 排序后
+	weights    []float64 // names对应的权重
+	mu         sync.RWMutex
 }
 
 func NewWeightedConsistent(name string, numberOfReplicas int, members []Member) *WeightedConsistent {
+	return NewWeightedConsistentNormalized(name, numberOfReplicas, members, Normalization{})
+}
+
+// NewWeightedConsistentNormalized 同 NewWeightedConsistent, 按norm缩放权重
+func NewWeightedConsistentNormalized(name string, numberOfReplicas int, members []Member, norm Normalization) *WeightedConsistent {
+	if numberOfReplicas <= 0 {
+		numberOfReplicas = 200
+	}
+	eltMap := normalize(members, norm, numberOfReplicas)
+	cons := &WeightedConsistent{
+		name:       name,
+		c:          nil,
+		norm:       norm,
+		rawMembers: members,
+		cMembers:   eltMap,
+	}
+	cons.names, cons.weights = sortedWeights(eltMap)
+	c := New(numberOfReplicas)
+	if len(eltMap) > 0 {
+		c.Set(eltMap)
+	}
+	cons.c = c
+	return cons
+}
+
+// normalize 权重按照比例缩放, 非0最小的权重为1, 再按norm调整
+func normalize(members []Member, norm Normalization, numberOfReplicas int) map[string]float64 {
 	minW := 0.0
 	for _, m := range members {
 		if m.Weight > 0 {
@@ -24,29 +69,148 @@ func NewWeightedConsistent(name string, numberOfReplicas int, members []Member)
 			}
 		}
 	}
-	// 权重按照比例缩放, 非0最小的权重为1
 	eltMap := make(map[string]float64)
 	for _, m := range members {
 		if m.Weight > 0 {
 			w := m.Weight / minW
+			if norm.Log {
+				w = 1 + math.Log(w)
+			}
+			if norm.MaxRatio > 0 && w > norm.MaxRatio {
+				w = norm.MaxRatio
+			}
 			eltMap[m.Name] = w
 		}
 	}
-	cons := &WeightedConsistent{
-		name:       name,
-		c:          nil,
-		rawMembers: members,
-		cMembers:   eltMap,
+	if norm.Budget > 0 {
+		var total float64
+		for _, w := range eltMap {
+			total += w
+		}
+		if f := float64(norm.Budget) / (total * float64(numberOfReplicas)); f < 1 {
+			for name, w := range eltMap {
+				eltMap[name] = w * f
+			}
+		}
 	}
-	if numberOfReplicas <= 0 {
-		numberOfReplicas = 200
+	return eltMap
+}
+
+// sortedWeights 按名称排序返回成员和权重
+func sortedWeights(eltMap map[string]float64) ([]string, []float64) {
+	names := make([]string, 0, len(eltMap))
+	for name := range eltMap {
+		names = append(names, name)
 	}
-	c := New(numberOfReplicas)
-	if len(eltMap) > 0 {
-		c.Set(eltMap)
+	sort.Strings(names)
+	weights := make([]float64, len(names))
+	for i, name := range names {
+		weights[i] = eltMap[name]
 	}
-	cons.c = c
-	return cons
+	return names, weights
+}
+
+// validateMemberWeight 权重为0的成员保留, 但不参与hash
+func validateMemberWeight(wgt float64) error {
+	if err := validateWeight(wgt); err != nil && err != ErrZeroWeight {
+		return err
+	}
+	return nil
+}
+
+// Add 添加成员, 已存在返回 ErrMemberExists
+func (c *WeightedConsistent) Add(elt string, wgt float64) error {
+	if err := validateMemberWeight(wgt); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.index(elt) >= 0 {
+		return ErrMemberExists
+	}
+	c.rawMembers = append(slices.Clip(c.rawMembers), Member{Name: elt, Weight: wgt})
+	c.rebuild()
+	return nil
+}
+
+// Remove 删除成员, 不存在返回false
+func (c *WeightedConsistent) Remove(elt string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	i := c.index(elt)
+	if i < 0 {
+		return false
+	}
+	c.rawMembers = slices.Delete(slices.Clone(c.rawMembers), i, i+1)
+	c.rebuild()
+	return true
+}
+
+// UpdateWeight 修改权重, 不存在返回 ErrMemberNotFound
+func (c *WeightedConsistent) UpdateWeight(elt string, wgt float64) error {
+	if err := validateMemberWeight(wgt); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	i := c.index(elt)
+	if i < 0 {
+		return ErrMemberNotFound
+	}
+	c.rawMembers = slices.Clone(c.rawMembers)
+	c.rawMembers[i].Weight = wgt
+	c.rebuild()
+	return nil
+}
+
+// Set 替换全部成员
+func (c *WeightedConsistent) Set(members []Member) error {
+	for _, m := range members {
+		if err := validateMemberWeight(m.Weight); err != nil {
+			return err
+		}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.rawMembers = slices.Clone(members)
+	c.rebuild()
+	return nil
+}
+
+// Members 返回成员(含原始权重)
+func (c *WeightedConsistent) Members() []Member {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return slices.Clone(c.rawMembers)
+}
+
+// need c.mu.Lock() before calling
+func (c *WeightedConsistent) index(elt string) int {
+	return slices.IndexFunc(c.rawMembers, func(m Member) bool { return m.Name == elt })
+}
+
+// rebuild 重新计算缩放后的权重并更新hash环
+//
+// need c.mu.Lock() before calling
+func (c *WeightedConsistent) rebuild() {
+	c.cMembers = normalize(c.rawMembers, c.norm, c.c.NumberOfReplicas)
+	c.names, c.weights = sortedWeights(c.cMembers)
+	c.c.Set(c.cMembers)
+}
+
+// Get 一致性hash, 返回key对应的成员
+func (c *WeightedConsistent) Get(key string) (string, error) {
+	return c.c.Get(key)
+}
+
+// GetTwo 一致性hash, 返回key对应的两个不同成员
+func (c *WeightedConsistent) GetTwo(key string) (string, string, error) {
+	return c.c.GetTwo(key)
+}
+
+// GetN 一致性hash, 返回key对应的n个不同成员
+func (c *WeightedConsistent) GetN(key string, n int) ([]string, error) {
+	return c.c.GetN(key, n)
 }
 
 // GetAll 一致性hash加权随机
@@ -54,38 +218,66 @@ func (c *WeightedConsistent) GetAll(key string) ([]string, error) {
 	return c.c.GetAll(key)
 }
 
-// GetRandomAll 加权随机
+// GetRandomAll 加权随机, 相同的key得到相同的顺序; key为空时每次随机
 func (c *WeightedConsistent) GetRandomAll(key string) ([]string, error) {
-	return WeightedShuffle(c.cMembers), nil
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	random := rand.Float64
+	if key != "" {
+		random = KeyRand(key).Float64
+	}
+	return weightedSample(c.names, c.weights, len(c.names), random), nil
+}
+
+// GetRandomN 加权随机选取n个成员, 不需要打乱全部成员; key的用法同 GetRandomAll
+func (c *WeightedConsistent) GetRandomN(key string, n int) ([]string, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	random := rand.Float64
+	if key != "" {
+		random = KeyRand(key).Float64
+	}
+	return weightedSample(c.names, c.weights, n, random), nil
 }
 
 func (c *WeightedConsistent) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
 	return len(c.cMembers)
 }
 
+// KeyRand 返回由key决定种子的随机源, 用于同一个key得到固定的随机结果
+func KeyRand(key string) *rand.Rand {
+	h := xxhash64(key)
+	return rand.New(rand.NewPCG(h, mix64(h)))
+}
+
 func WeightedShuffle(cMembers map[string]float64) []string {
-	// 为每个项目生成随机权重
-	weightedRandom := make([]struct {
-		name   string
-		random float64
-	}, 0, len(cMembers))
-	for name, weight := range cMembers {
-		weightedRandom = append(weightedRandom, struct {
-			name   string
-			random float64
-		}{
-			name:   name,
-			random: rand.Float64() * weight,
-		})
-	}
-	// 按随机权重排序
-	sort.Slice(weightedRandom, func(i, j int) bool {
-		return weightedRandom[i].random > weightedRandom[j].random
-	})
-	// 提取排序后的项目
-	result := make([]string, len(weightedRandom))
-	for i, wr := range weightedRandom {
-		result[i] = wr.name
+	return WeightedShuffleRand(cMembers, nil)
+}
+
+// WeightedShuffleRand 同 WeightedShuffle, 使用r作为随机源, r为nil时使用全局随机源.
+// r的状态相同时结果相同. 权重越大越靠前, 权重不大于0的排在最后.
+func WeightedShuffleRand(cMembers map[string]float64, r *rand.Rand) []string {
+	random := rand.Float64
+	if r != nil {
+		random = r.Float64
+	}
+	// map的遍历顺序是随机的, 先排序保证结果可复现
+	names := make([]string, 0, len(cMembers))
+	for name := range cMembers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	weights := make([]float64, len(names))
+	for i, name := range names {
+		weights[i] = cMembers[name]
+	}
+	result := weightedSample(names, weights, len(names), random)
+	for _, name := range names {
+		if cMembers[name] <= 0 {
+			result = append(result, name)
+		}
 	}
 	return result
 }
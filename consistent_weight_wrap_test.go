@@ -0,0 +1,139 @@
+package consistent
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestWeightedConsistentMutations(t *testing.T) {
+	members := []Member{{Name: "A", Weight: 2}, {Name: "B", Weight: 4}}
+	c := NewWeightedConsistent("test", 20, members)
+	if err := c.Add("C", 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Add("C", 1); !errors.Is(err, ErrMemberExists) {
+		t.Fatalf("Add of existing member: %v", err)
+	}
+	if vn := c.c.VirtualNodes(); vn["C"] != 20 || vn["A"] != 40 || vn["B"] != 80 {
+		t.Fatalf("virtual nodes after Add = %v", vn)
+	}
+	if err := c.UpdateWeight("C", 0); err != nil {
+		t.Fatal(err)
+	}
+	if c.Len() != 2 || len(c.Members()) != 3 {
+		t.Fatalf("Len = %d, Members = %v", c.Len(), c.Members())
+	}
+	if !c.Remove("A") || c.Remove("A") {
+		t.Fatal("Remove should succeed once")
+	}
+	if members[0].Name != "A" {
+		t.Fatal("mutations changed the caller's members")
+	}
+	c.Set([]Member{{Name: "D", Weight: 3}})
+	if all, _ := c.GetAll("key"); len(all) != 1 || all[0] != "D" {
+		t.Fatalf("GetAll after Set = %v", all)
+	}
+}
+
+func TestWeightedConsistentGet(t *testing.T) {
+	c := NewWeightedConsistent("test", 20, []Member{{Name: "A", Weight: 1}, {Name: "B", Weight: 9}})
+	counts := map[string]int{}
+	for i := 0; i < 1000; i++ {
+		key := fmt.Sprint(i)
+		m, err := c.Get(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		counts[m]++
+		all, _ := c.GetAll(key)
+		a, b, _ := c.GetTwo(key)
+		n, _ := c.GetN(key, 2)
+		if m != all[0] || a != m || b != all[1] || fmt.Sprint(n) != fmt.Sprint(all) {
+			t.Fatalf("lookups of %q disagree: Get %q, GetTwo %q %q, GetN %v, GetAll %v", key, m, a, b, n, all)
+		}
+	}
+	if counts["B"] < 5*counts["A"] {
+		t.Fatalf("counts = %v, want B to hold about 9 times A", counts)
+	}
+}
+
+func TestWeightedShuffleRand(t *testing.T) {
+	m := map[string]float64{"A": 1, "B": 2, "C": 3, "D": 4, "E": 5}
+	a := WeightedShuffleRand(m, KeyRand("key"))
+	b := WeightedShuffleRand(m, KeyRand("key"))
+	if fmt.Sprint(a) != fmt.Sprint(b) {
+		t.Fatalf("shuffles with the same seed differ: %v, %v", a, b)
+	}
+	c := NewWeightedConsistent("test", 20, []Member{{Name: "A", Weight: 1}, {Name: "B", Weight: 2}, {Name: "C", Weight: 3}})
+	x, _ := c.GetRandomAll("user-1")
+	y, _ := c.GetRandomAll("user-1")
+	if fmt.Sprint(x) != fmt.Sprint(y) {
+		t.Fatalf("GetRandomAll of the same key differs: %v, %v", x, y)
+	}
+}
+
+func BenchmarkWeightedShuffle(b *testing.B) {
+	m := benchmarkWeights(1000)
+	for i := 0; i < b.N; i++ {
+		WeightedShuffle(m)
+	}
+}
+
+func TestGetRandomN(t *testing.T) {
+	var members []Member
+	for i := 0; i < 1000; i++ {
+		members = append(members, Member{Name: fmt.Sprintf("Host%d", i), Weight: 1})
+	}
+	members[0].Weight = 1000
+	c := NewWeightedConsistent("test", 1, members)
+	hits := 0
+	for i := 0; i < 200; i++ {
+		res, err := c.GetRandomN(fmt.Sprint(i), 3)
+		if err != nil || len(res) != 3 {
+			t.Fatalf("GetRandomN = %v, %v", res, err)
+		}
+		if res[0] == res[1] || res[1] == res[2] || res[0] == res[2] {
+			t.Fatalf("GetRandomN returned duplicates: %v", res)
+		}
+		if sliceContainsMember(res, "Host0") {
+			hits++
+		}
+		again, _ := c.GetRandomN(fmt.Sprint(i), 3)
+		if fmt.Sprint(again) != fmt.Sprint(res) {
+			t.Fatalf("GetRandomN of the same key differs: %v, %v", res, again)
+		}
+	}
+	if hits < 150 {
+		t.Fatalf("the heaviest member was picked in %d of 200 samples", hits)
+	}
+}
+
+func TestNormalization(t *testing.T) {
+	members := []Member{{Name: "A", Weight: 1}, {Name: "B", Weight: 1000}}
+	total := func(c *WeightedConsistent) int {
+		n := 0
+		for _, v := range c.c.VirtualNodes() {
+			n += v
+		}
+		return n
+	}
+	if n := total(NewWeightedConsistent("test", 10, members)); n != 10010 {
+		t.Fatalf("default normalization places %d virtual nodes", n)
+	}
+	if n := total(NewWeightedConsistentNormalized("test", 10, members, Normalization{MaxRatio: 10})); n != 110 {
+		t.Fatalf("MaxRatio 10 places %d virtual nodes, want 110", n)
+	}
+	log := NewWeightedConsistentNormalized("test", 10, members, Normalization{Log: true})
+	if vn := log.c.VirtualNodes(); vn["A"] != 10 || vn["B"] != 79 {
+		t.Fatalf("log normalization places %v", vn)
+	}
+	budget := NewWeightedConsistentNormalized("test", 10, members, Normalization{Budget: 500})
+	if n := total(budget); n > 500 || n < 450 {
+		t.Fatalf("budget 500 places %d virtual nodes", n)
+	}
+	budget.Add("C", 1000)
+	if n := total(budget); n > 500 {
+		t.Fatalf("budget 500 places %d virtual nodes after Add", n)
+	}
+}
@@ -0,0 +1,101 @@
+package discovery
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// Consul is a Watcher of the passing instances of a Consul service, using
+// blocking queries on the health endpoint of the HTTP API. An instance is
+// named by its service address, or its node address if it has none, and
+// port; its weight is the service's passing weight.
+type Consul struct {
+	// Addr is the base URL of the Consul agent. Default
+	// "http://127.0.0.1:8500".
+	Addr string
+	// Service is the name of the watched service.
+	Service string
+	// Tag, if set, restricts the instances to those with the tag.
+	Tag string
+	// Token, if set, is sent as the ACL token.
+	Token string
+	// Client sends the requests. Default http.DefaultClient.
+	Client *http.Client
+
+	index uint64
+}
+
+type consulEntry struct {
+	Node struct {
+		Address string
+	}
+	Service struct {
+		Address string
+		Port    int
+		Weights struct {
+			Passing float64
+		}
+	}
+}
+
+// Next implements Watcher. The first call returns the current instances;
+// later calls block until the Consul index moves.
+func (w *Consul) Next(ctx context.Context) ([]Instance, error) {
+	base := w.Addr
+	if base == "" {
+		base = "http://127.0.0.1:8500"
+	}
+	q := url.Values{"passing": {"true"}}
+	if w.Tag != "" {
+		q.Set("tag", w.Tag)
+	}
+	if w.index > 0 {
+		q.Set("index", strconv.FormatUint(w.index, 10))
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, base+"/v1/health/service/"+url.PathEscape(w.Service)+"?"+q.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if w.Token != "" {
+		req.Header.Set("X-Consul-Token", w.Token)
+	}
+	client := w.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("discovery: consul returned %s", resp.Status)
+	}
+	var entries []consulEntry
+	if err := json.NewDecoder(resp.Body).Decode(&entries); err != nil {
+		return nil, err
+	}
+	// An index going backwards means the agent state was reset; start over.
+	index, _ := strconv.ParseUint(resp.Header.Get("X-Consul-Index"), 10, 64)
+	if index < w.index {
+		index = 0
+	}
+	w.index = index
+	instances := make([]Instance, 0, len(entries))
+	for _, e := range entries {
+		host := e.Service.Address
+		if host == "" {
+			host = e.Node.Address
+		}
+		instances = append(instances, Instance{
+			Addr:   net.JoinHostPort(host, strconv.Itoa(e.Service.Port)),
+			Weight: e.Service.Weights.Passing,
+		})
+	}
+	return instances, nil
+}
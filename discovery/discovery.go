@@ -0,0 +1,213 @@
+// Package discovery keeps the members of a ring in sync with a service
+// catalog. A Watcher reports the instances of a service as they change;
+// Sync applies them to a Consistent, holding back removals so that a
+// flapping instance does not move its keys back and forth.
+//
+//	w := &discovery.Consul{Service: "cache"}
+//	go discovery.Sync(ctx, ring, w, discovery.Options{Debounce: 10 * time.Second})
+//
+// Watchers for other catalogs, such as etcd, only need to implement Next.
+package discovery
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	consistent "github.com/kingreatwill/weighted-consistent-hashing"
+)
+
+// Instance is an instance of a service, used as a ring member.
+type Instance struct {
+	// Addr is the member name, usually host:port.
+	Addr string
+	// Weight is the member weight; 0 or less means 1.
+	Weight float64
+}
+
+// Watcher watches the instances of a service.
+type Watcher interface {
+	// Next blocks until the set of instances may have changed since the
+	// last call, or ctx is done, and returns the full current set. The
+	// first call returns immediately.
+	Next(ctx context.Context) ([]Instance, error)
+}
+
+// Options configures Sync. The zero value uses the defaults.
+type Options struct {
+	// Debounce is how long an instance must be missing from the catalog
+	// before it is removed from the ring. Instances that come back within
+	// Debounce stay on the ring as if they never left. Additions and weight
+	// changes are applied at once. Default 0, removing at once.
+	Debounce time.Duration
+	// RetryInterval is the time to wait after Next fails. Default 1s.
+	RetryInterval time.Duration
+	// MaxChurn, if greater than 0, is the largest number of members added
+	// or removed at once. Larger changes are applied in steps of MaxChurn
+	// members, ChurnInterval apart, additions first. Weight changes do not
+	// count.
+	MaxChurn int
+	// ChurnInterval is the time between steps of a change larger than
+	// MaxChurn. Default 1s.
+	ChurnInterval time.Duration
+	// OnError, if set, is called with errors from the Watcher and from
+	// applying changes to the ring.
+	OnError func(err error)
+}
+
+// Sync makes the members of c follow the instances reported by w until ctx
+// is done, and returns ctx.Err(). Sync owns the membership of c: members
+// not reported by w are removed.
+func Sync(ctx context.Context, c *consistent.Consistent, w Watcher, opts Options) error {
+	if opts.RetryInterval <= 0 {
+		opts.RetryInterval = time.Second
+	}
+	if opts.ChurnInterval <= 0 {
+		opts.ChurnInterval = time.Second
+	}
+	updates := make(chan []Instance)
+	go func() {
+		for {
+			instances, err := w.Next(ctx)
+			if ctx.Err() != nil {
+				return
+			}
+			if err != nil {
+				if opts.OnError != nil {
+					opts.OnError(err)
+				}
+				select {
+				case <-time.After(opts.RetryInterval):
+					continue
+				case <-ctx.Done():
+					return
+				}
+			}
+			select {
+			case updates <- instances:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	s := syncer{
+		c:        c,
+		debounce: opts.Debounce,
+		maxChurn: opts.MaxChurn,
+		churnGap: opts.ChurnInterval,
+		gone:     make(map[string]time.Time),
+	}
+	timer := time.NewTimer(time.Hour)
+	timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case instances := <-updates:
+			s.latest = make(map[string]float64, len(instances))
+			for _, in := range instances {
+				s.latest[in.Addr] = in.Weight
+				if in.Weight <= 0 {
+					s.latest[in.Addr] = 1
+				}
+			}
+		case <-timer.C:
+		}
+		next, err := s.apply(time.Now())
+		if err != nil && opts.OnError != nil {
+			opts.OnError(err)
+		}
+		if next > 0 {
+			timer.Reset(next)
+		}
+	}
+}
+
+type syncer struct {
+	c        *consistent.Consistent
+	debounce time.Duration
+	maxChurn int
+	churnGap time.Duration
+	latest   map[string]float64
+	// gone holds when each member still on the ring went missing.
+	gone map[string]time.Time
+}
+
+// apply sets the members of the ring to the latest instances plus those
+// missing for less than the debounce period, at most maxChurn additions and
+// removals at a time, and returns how long until apply should run again, or
+// 0.
+func (s *syncer) apply(now time.Time) (time.Duration, error) {
+	desired := make(map[string]float64, len(s.latest))
+	for addr, wgt := range s.latest {
+		desired[addr] = wgt
+		delete(s.gone, addr)
+	}
+	current := s.c.MemberInfos()
+	var next time.Duration
+	for _, m := range current {
+		if _, ok := desired[m.Name]; ok {
+			continue
+		}
+		since, ok := s.gone[m.Name]
+		if !ok {
+			since = now
+			s.gone[m.Name] = now
+		}
+		if left := s.debounce - now.Sub(since); left > 0 {
+			desired[m.Name] = m.Weight
+			if next == 0 || left < next {
+				next = left
+			}
+		}
+	}
+	if s.maxChurn > 0 && s.limitChurn(current, desired) && (next == 0 || s.churnGap < next) {
+		next = s.churnGap
+	}
+	for name := range s.gone {
+		if _, ok := desired[name]; !ok {
+			delete(s.gone, name)
+		}
+	}
+	return next, s.c.Set(desired)
+}
+
+// limitChurn trims desired so that it differs from current by at most
+// maxChurn additions and removals, preferring additions and then names in
+// order, and reports whether it had to.
+func (s *syncer) limitChurn(current []consistent.MemberInfo, desired map[string]float64) bool {
+	held := make(map[string]float64, len(current))
+	for _, m := range current {
+		held[m.Name] = m.Weight
+	}
+	var added, removed []string
+	for addr := range desired {
+		if _, ok := held[addr]; !ok {
+			added = append(added, addr)
+		}
+	}
+	for _, m := range current {
+		if _, ok := desired[m.Name]; !ok {
+			removed = append(removed, m.Name)
+		}
+	}
+	if len(added)+len(removed) <= s.maxChurn {
+		return false
+	}
+	sort.Strings(added)
+	budget := s.maxChurn
+	for i, addr := range added {
+		if i >= budget {
+			delete(desired, addr)
+		}
+	}
+	budget -= min(len(added), budget)
+	for i, name := range removed {
+		if i >= budget {
+			desired[name] = held[name]
+		}
+	}
+	return true
+}
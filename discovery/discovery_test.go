@@ -0,0 +1,177 @@
+package discovery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sort"
+	"testing"
+	"time"
+
+	consistent "github.com/kingreatwill/weighted-consistent-hashing"
+)
+
+type chanWatcher chan []Instance
+
+func (w chanWatcher) Next(ctx context.Context) ([]Instance, error) {
+	select {
+	case in := <-w:
+		return in, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func members(c *consistent.Consistent) []string {
+	m := c.Members()
+	sort.Strings(m)
+	return m
+}
+
+func waitFor(t *testing.T, c *consistent.Consistent, want ...string) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !slices.Equal(members(c), want) {
+		if time.Now().After(deadline) {
+			t.Fatalf("members %v, want %v", members(c), want)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestSyncDebounce(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c := consistent.New(20)
+	w := make(chanWatcher)
+	go Sync(ctx, c, w, Options{Debounce: 200 * time.Millisecond})
+
+	w <- []Instance{{"a:1", 1}, {"b:1", 2}}
+	waitFor(t, c, "a:1", "b:1")
+
+	// b flaps: it is kept while missing for less than the debounce period.
+	w <- []Instance{{"a:1", 1}}
+	time.Sleep(50 * time.Millisecond)
+	w <- []Instance{{"a:1", 1}, {"b:1", 2}}
+	time.Sleep(250 * time.Millisecond)
+	waitFor(t, c, "a:1", "b:1")
+
+	w <- []Instance{{"a:1", 1}, {"c:1", 0}}
+	waitFor(t, c, "a:1", "b:1", "c:1")
+	waitFor(t, c, "a:1", "c:1")
+}
+
+func TestConsul(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/health/service/cache" || r.URL.Query().Get("passing") != "true" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		if calls > 0 && r.URL.Query().Get("index") != "7" {
+			t.Errorf("blocking query without index: %s", r.URL)
+		}
+		calls++
+		rw.Header().Set("X-Consul-Index", "7")
+		fmt.Fprint(rw, `[{"Node":{"Address":"10.0.0.1"},"Service":{"Port":11211,"Weights":{"Passing":3}}},
+			{"Node":{"Address":"10.0.0.2"},"Service":{"Address":"10.1.0.2","Port":11211,"Weights":{"Passing":1}}}]`)
+	}))
+	defer srv.Close()
+	w := &Consul{Addr: srv.URL, Service: "cache"}
+	for i := 0; i < 2; i++ {
+		in, err := w.Next(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []Instance{{"10.0.0.1:11211", 3}, {"10.1.0.2:11211", 1}}
+		if !slices.Equal(in, want) {
+			t.Fatalf("instances %v, want %v", in, want)
+		}
+	}
+}
+
+func TestDNS(t *testing.T) {
+	answers := [][]*net.SRV{
+		{{Target: "b.example.com.", Port: 11211, Priority: 10, Weight: 2}, {Target: "a.example.com.", Port: 11211, Priority: 10, Weight: 1}, {Target: "backup.example.com.", Port: 11211, Priority: 20, Weight: 5}},
+		{{Target: "a.example.com.", Port: 11211, Priority: 10, Weight: 1}, {Target: "b.example.com.", Port: 11211, Priority: 10, Weight: 2}},
+		{{Target: "a.example.com.", Port: 11211, Priority: 10, Weight: 1}},
+	}
+	w := &DNS{Name: "_memcache._tcp.example.com", SRV: true, Interval: time.Millisecond}
+	w.lookupSRV = func(ctx context.Context, name string) ([]*net.SRV, error) {
+		srvs := answers[0]
+		if len(answers) > 1 {
+			answers = answers[1:]
+		}
+		return srvs, nil
+	}
+	in, err := w.Next(context.Background())
+	want := []Instance{{"a.example.com:11211", 1}, {"b.example.com:11211", 2}}
+	if err != nil || !slices.Equal(in, want) {
+		t.Fatalf("instances %v, %v, want %v", in, err, want)
+	}
+	// The second answer is the same set in another order and is skipped.
+	in, err = w.Next(context.Background())
+	if err != nil || !slices.Equal(in, want[:1]) {
+		t.Fatalf("instances %v, %v, want %v", in, err, want[:1])
+	}
+
+	h := &DNS{Name: "cache.example.com", Port: 6379}
+	h.lookupHost = func(ctx context.Context, name string) ([]string, error) {
+		return []string{"10.0.0.2", "10.0.0.1"}, nil
+	}
+	in, _ = h.Next(context.Background())
+	if want := []Instance{{"10.0.0.1:6379", 1}, {"10.0.0.2:6379", 1}}; !slices.Equal(in, want) {
+		t.Fatalf("instances %v, want %v", in, want)
+	}
+}
+
+func TestDNSKeepsLastGoodSet(t *testing.T) {
+	answers := [][]string{{"10.0.0.1"}, nil, nil, {"10.0.0.1"}, nil, {"10.0.0.2"}}
+	w := &DNS{Name: "cache.example.com", Interval: time.Millisecond}
+	w.lookupHost = func(ctx context.Context, name string) ([]string, error) {
+		if len(answers) == 0 {
+			return nil, errors.New("no more answers")
+		}
+		addrs := answers[0]
+		answers = answers[1:]
+		return addrs, nil
+	}
+	in, err := w.Next(context.Background())
+	if want := []Instance{{"10.0.0.1", 1}}; err != nil || !slices.Equal(in, want) {
+		t.Fatalf("instances %v, %v, want %v", in, err, want)
+	}
+	// Empty answers and the unchanged set in between are not reported.
+	in, err = w.Next(context.Background())
+	if want := []Instance{{"10.0.0.2", 1}}; err != nil || !slices.Equal(in, want) {
+		t.Fatalf("instances %v, %v, want %v", in, err, want)
+	}
+	if _, err := w.Next(context.Background()); err == nil {
+		t.Fatal("lookup error not returned")
+	}
+}
+
+func TestSyncMaxChurn(t *testing.T) {
+	c := consistent.New(20)
+	c.Set(map[string]float64{"a": 1, "b": 1, "c": 1})
+	s := syncer{c: c, maxChurn: 2, churnGap: time.Second, gone: make(map[string]time.Time)}
+	s.latest = map[string]float64{"d": 1, "e": 1, "f": 1}
+	now := time.Now()
+	var steps [][]string
+	for i := 0; i < 5; i++ {
+		next, err := s.apply(now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		steps = append(steps, members(c))
+		if next == 0 {
+			break
+		}
+	}
+	want := [][]string{{"a", "b", "c", "d", "e"}, {"b", "c", "d", "e", "f"}, {"d", "e", "f"}}
+	if fmt.Sprint(steps) != fmt.Sprint(want) {
+		t.Fatalf("steps %v, want %v", steps, want)
+	}
+}
@@ -0,0 +1,115 @@
+package discovery
+
+import (
+	"context"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DNS is a Watcher polling a DNS name, as commonly used for memcached and
+// Redis fleets. With SRV set, Name is looked up as an SRV record: members
+// are named target:port, weighted by the SRV weight, and only the targets
+// of the lowest priority are used. Otherwise Name is looked up as a host
+// name and every address becomes a member of weight 1, named ip:port if
+// Port is set. An empty answer after the first is taken as a lookup
+// failure, not as every member leaving: the last set reported stays until
+// the name resolves to a different, non-empty set.
+type DNS struct {
+	Name string
+	SRV  bool
+	Port int
+	// Interval is the time between lookups. Default 30s.
+	Interval time.Duration
+	// Resolver performs the lookups. Default net.DefaultResolver.
+	Resolver *net.Resolver
+
+	last    []Instance
+	started bool
+	sent    bool
+	// lookupSRV and lookupHost replace the resolver in tests.
+	lookupSRV  func(ctx context.Context, name string) ([]*net.SRV, error)
+	lookupHost func(ctx context.Context, name string) ([]string, error)
+}
+
+// Next implements Watcher. The first call resolves the name at once; later
+// calls poll every Interval until the answer changes to a non-empty set.
+func (w *DNS) Next(ctx context.Context) ([]Instance, error) {
+	interval := w.Interval
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	for {
+		if w.started {
+			select {
+			case <-time.After(interval):
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+		w.started = true
+		instances, err := w.resolve(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if !w.sent || len(instances) > 0 && !slices.Equal(instances, w.last) {
+			w.last, w.sent = instances, true
+			return instances, nil
+		}
+	}
+}
+
+// resolve looks up the name once and returns the instances sorted by name.
+func (w *DNS) resolve(ctx context.Context) ([]Instance, error) {
+	r := w.Resolver
+	if r == nil {
+		r = net.DefaultResolver
+	}
+	var instances []Instance
+	if w.SRV {
+		lookup := w.lookupSRV
+		if lookup == nil {
+			lookup = func(ctx context.Context, name string) ([]*net.SRV, error) {
+				_, srvs, err := r.LookupSRV(ctx, "", "", name)
+				return srvs, err
+			}
+		}
+		srvs, err := lookup(ctx, w.Name)
+		if err != nil {
+			return nil, err
+		}
+		top := -1
+		for _, srv := range srvs {
+			if top < 0 || int(srv.Priority) < top {
+				top = int(srv.Priority)
+			}
+		}
+		for _, srv := range srvs {
+			if int(srv.Priority) == top {
+				instances = append(instances, Instance{
+					Addr:   net.JoinHostPort(strings.TrimSuffix(srv.Target, "."), strconv.Itoa(int(srv.Port))),
+					Weight: float64(srv.Weight),
+				})
+			}
+		}
+	} else {
+		lookup := w.lookupHost
+		if lookup == nil {
+			lookup = r.LookupHost
+		}
+		addrs, err := lookup(ctx, w.Name)
+		if err != nil {
+			return nil, err
+		}
+		for _, addr := range addrs {
+			if w.Port > 0 {
+				addr = net.JoinHostPort(addr, strconv.Itoa(w.Port))
+			}
+			instances = append(instances, Instance{Addr: addr, Weight: 1})
+		}
+	}
+	slices.SortFunc(instances, func(a, b Instance) int { return strings.Compare(a.Addr, b.Addr) })
+	return slices.CompactFunc(instances, func(a, b Instance) bool { return a.Addr == b.Addr }), nil
+}
@@ -0,0 +1,10 @@
+module github.com/kingreatwill/weighted-consistent-hashing/examples/memcachedrouter
+
+go 1.23.0
+
+require (
+	github.com/bradfitz/gomemcache v0.0.0-20260422231931-4d751bb6e37c
+	github.com/kingreatwill/weighted-consistent-hashing v0.0.0
+)
+
+replace github.com/kingreatwill/weighted-consistent-hashing => ../../
@@ -0,0 +1,134 @@
+// Package memcachedrouter is a gomemcache ServerSelector backed by a
+// weighted ring, so a memcache.Client spreads keys over servers in
+// proportion to their weight and keeps most keys on their server when the
+// server list changes. In ketama mode servers are placed the way libketama
+// places them; for servers of equal weight the mapping is checked against
+// libcouchbase's ketama output.
+//
+//	s, err := memcachedrouter.NewKetama(map[string]float64{
+//		"10.0.0.1:11211": 1,
+//		"10.0.0.2:11211": 2,
+//	})
+//	mc := memcache.NewFromSelector(s)
+package memcachedrouter
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/bradfitz/gomemcache/memcache"
+	consistent "github.com/kingreatwill/weighted-consistent-hashing"
+)
+
+var _ memcache.ServerSelector = (*Selector)(nil)
+
+// Selector is a memcache.ServerSelector picking the server of a key on a
+// weighted ring. It is safe for concurrent use.
+type Selector struct {
+	c *consistent.Consistent
+
+	mu    sync.RWMutex
+	addrs map[string]net.Addr
+}
+
+// New returns a Selector of servers, keyed by address with their weight,
+// on a ring with numberOfReplicas virtual nodes per unit of weight.
+func New(numberOfReplicas int, servers map[string]float64) (*Selector, error) {
+	s := &Selector{c: consistent.New(numberOfReplicas)}
+	if err := s.SetServers(servers); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// NewKetama returns a Selector of servers mapping keys like libketama.
+func NewKetama(servers map[string]float64) (*Selector, error) {
+	s := &Selector{c: consistent.New(0, consistent.WithKetamaMode())}
+	if err := s.SetServers(servers); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// SetServers replaces the servers, keyed by address with their weight. An
+// address containing a "/" is a Unix socket path, others are host:port
+// TCP addresses. Like memcache.ServerList.SetServers it resolves every
+// address first and changes nothing if any fails.
+func (s *Selector) SetServers(servers map[string]float64) error {
+	addrs := make(map[string]net.Addr, len(servers))
+	for server := range servers {
+		var (
+			addr net.Addr
+			err  error
+		)
+		if strings.Contains(server, "/") {
+			addr, err = net.ResolveUnixAddr("unix", server)
+		} else {
+			addr, err = net.ResolveTCPAddr("tcp", server)
+		}
+		if err != nil {
+			return err
+		}
+		addrs[server] = addr
+	}
+	// Lookups running during the change may pick a new server before its
+	// address is published; keep the old and new addresses until then.
+	s.mu.Lock()
+	merged := make(map[string]net.Addr, len(s.addrs)+len(addrs))
+	for k, v := range s.addrs {
+		merged[k] = v
+	}
+	for k, v := range addrs {
+		merged[k] = v
+	}
+	s.addrs = merged
+	s.mu.Unlock()
+	if err := s.c.Set(servers); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.addrs = addrs
+	s.mu.Unlock()
+	return nil
+}
+
+// Ring returns the ring of the Selector, for health and drain control.
+func (s *Selector) Ring() *consistent.Consistent {
+	return s.c
+}
+
+// PickServer returns the server of key.
+func (s *Selector) PickServer(key string) (net.Addr, error) {
+	server, err := s.c.Get(key)
+	if errors.Is(err, consistent.ErrEmptyCircle) || errors.Is(err, consistent.ErrNoAvailableMember) {
+		return nil, memcache.ErrNoServers
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	addr, ok := s.addrs[server]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, memcache.ErrNoServers
+	}
+	return addr, nil
+}
+
+// Each calls f for every server.
+func (s *Selector) Each(f func(net.Addr) error) error {
+	s.mu.RLock()
+	addrs := make([]net.Addr, 0, len(s.addrs))
+	for _, addr := range s.addrs {
+		addrs = append(addrs, addr)
+	}
+	s.mu.RUnlock()
+	for _, addr := range addrs {
+		if err := f(addr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
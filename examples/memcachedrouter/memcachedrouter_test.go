@@ -0,0 +1,57 @@
+package memcachedrouter
+
+import (
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/bradfitz/gomemcache/memcache"
+)
+
+func TestSelector(t *testing.T) {
+	servers := map[string]float64{"127.0.0.1:11211": 1, "127.0.0.2:11211": 1, "/tmp/memcached.sock": 2}
+	for _, ketama := range []bool{false, true} {
+		s, err := New(100, servers)
+		if ketama {
+			s, err = NewKetama(servers)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 100; i++ {
+			key := fmt.Sprint("key-", i)
+			addr, err := s.PickServer(key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, _ := s.Ring().Get(key)
+			if addr.String() != want {
+				t.Fatalf("PickServer(%q) = %v, want %s", key, addr, want)
+			}
+		}
+		n := 0
+		s.Each(func(net.Addr) error { n++; return nil })
+		if n != 3 {
+			t.Fatalf("Each visited %d servers", n)
+		}
+		s.SetServers(nil)
+		if _, err := s.PickServer("key"); err != memcache.ErrNoServers {
+			t.Fatalf("PickServer on no servers: %v", err)
+		}
+	}
+	if _, err := New(100, map[string]float64{"no-such-host.invalid:11211": 1}); err == nil {
+		t.Fatal("unresolvable server accepted")
+	}
+}
+
+func ExampleNewKetama() {
+	s, err := NewKetama(map[string]float64{
+		"10.0.0.1:11211": 1,
+		"10.0.0.2:11211": 2,
+	})
+	if err != nil {
+		panic(err)
+	}
+	mc := memcache.NewFromSelector(s)
+	_ = mc // mc.Set, mc.Get, ... as with memcache.New
+}
@@ -1,3 +1,3 @@
 module github.com/kingreatwill/weighted-consistent-hashing
 
-go 1.23
+go 1.23.0
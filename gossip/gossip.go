@@ -0,0 +1,123 @@
+// Package gossip keeps a ring in sync with the members of a
+// hashicorp/memberlist cluster, so that every process of the cluster
+// maintains the same ring without a central catalog. Each node gossips its
+// weight in its node metadata; weight changes propagate like any other
+// metadata update.
+//
+//	a := gossip.New(ring, 1)
+//	conf := memberlist.DefaultLANConfig()
+//	a.Configure(conf)
+//	ml, err := memberlist.Create(conf)
+//	...
+//	a.SetWeight(ml, 2, 5*time.Second)
+package gossip
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/memberlist"
+	consistent "github.com/kingreatwill/weighted-consistent-hashing"
+)
+
+// Adapter applies memberlist membership events to a Consistent, naming
+// members after their memberlist node names. It implements
+// memberlist.EventDelegate and memberlist.Delegate.
+type Adapter struct {
+	c *consistent.Consistent
+	// OnError, if set, is called with errors from applying events to the
+	// ring, such as a node gossiping an invalid weight.
+	OnError func(err error)
+
+	mu     sync.Mutex
+	weight float64
+}
+
+// nodeMeta is the metadata a node gossips about itself.
+type nodeMeta struct {
+	Weight float64 `json:"weight"`
+}
+
+// New returns an Adapter for c gossiping weight as the weight of the local
+// node.
+func New(c *consistent.Consistent, weight float64) *Adapter {
+	return &Adapter{c: c, weight: weight}
+}
+
+// Configure makes conf deliver membership events to a and gossip the local
+// weight.
+func (a *Adapter) Configure(conf *memberlist.Config) {
+	conf.Events = a
+	conf.Delegate = a
+}
+
+// SetWeight changes the weight of the local node and gossips it to the
+// cluster, waiting at most timeout for the update to be broadcast.
+func (a *Adapter) SetWeight(ml *memberlist.Memberlist, weight float64, timeout time.Duration) error {
+	a.mu.Lock()
+	a.weight = weight
+	a.mu.Unlock()
+	return ml.UpdateNode(timeout)
+}
+
+// weightOf returns the weight gossiped by n, or 1 if it gossips none.
+func weightOf(n *memberlist.Node) float64 {
+	var meta nodeMeta
+	if json.Unmarshal(n.Meta, &meta) != nil || meta.Weight == 0 {
+		return 1
+	}
+	return meta.Weight
+}
+
+func (a *Adapter) report(err error) {
+	if err != nil && a.OnError != nil {
+		a.OnError(err)
+	}
+}
+
+// NotifyJoin adds n to the ring.
+func (a *Adapter) NotifyJoin(n *memberlist.Node) {
+	err := a.c.Add(n.Name, weightOf(n))
+	if err == consistent.ErrMemberExists {
+		err = a.c.UpdateWeight(n.Name, weightOf(n))
+	}
+	a.report(err)
+}
+
+// NotifyLeave removes n from the ring.
+func (a *Adapter) NotifyLeave(n *memberlist.Node) {
+	a.c.Remove(n.Name)
+}
+
+// NotifyUpdate applies the weight gossiped by n.
+func (a *Adapter) NotifyUpdate(n *memberlist.Node) {
+	err := a.c.UpdateWeight(n.Name, weightOf(n))
+	if err == consistent.ErrMemberNotFound {
+		err = a.c.Add(n.Name, weightOf(n))
+	}
+	a.report(err)
+}
+
+// NodeMeta returns the metadata of the local node.
+func (a *Adapter) NodeMeta(limit int) []byte {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	b, _ := json.Marshal(nodeMeta{Weight: a.weight})
+	if len(b) > limit {
+		return nil
+	}
+	return b
+}
+
+// NotifyMsg ignores user messages.
+func (a *Adapter) NotifyMsg([]byte) {}
+
+// GetBroadcasts has no broadcasts to send.
+func (a *Adapter) GetBroadcasts(overhead, limit int) [][]byte { return nil }
+
+// LocalState has no state to exchange.
+func (a *Adapter) LocalState(join bool) []byte { return nil }
+
+// MergeRemoteState ignores remote state.
+func (a *Adapter) MergeRemoteState(buf []byte, join bool) {}
@@ -0,0 +1,73 @@
+package gossip
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"slices"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/memberlist"
+	consistent "github.com/kingreatwill/weighted-consistent-hashing"
+)
+
+func start(t *testing.T, name string, weight float64) (*consistent.Consistent, *Adapter, *memberlist.Memberlist) {
+	t.Helper()
+	c := consistent.New(20)
+	a := New(c, weight)
+	conf := memberlist.DefaultLocalConfig()
+	conf.Name = name
+	conf.BindAddr = "127.0.0.1"
+	conf.BindPort = 0
+	conf.Logger = log.New(io.Discard, "", 0)
+	a.Configure(conf)
+	ml, err := memberlist.Create(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ml.Shutdown() })
+	return c, a, ml
+}
+
+func eventually(t *testing.T, cond func() bool, msg func() string) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal(msg())
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestAdapter(t *testing.T) {
+	c1, a1, ml1 := start(t, "node-1", 1)
+	c2, _, ml2 := start(t, "node-2", 3)
+	if _, err := ml2.Join([]string{ml1.LocalNode().Address()}); err != nil {
+		t.Fatal(err)
+	}
+	same := func() bool {
+		i1, i2 := c1.MemberInfos(), c2.MemberInfos()
+		return len(i1) == 2 && slices.Equal(i1, i2)
+	}
+	infos := func() string { return fmt.Sprint(c1.MemberInfos(), c2.MemberInfos()) }
+	eventually(t, same, infos)
+	if i := c1.MemberInfos(); i[0].Weight != 1 || i[1].Weight != 3 {
+		t.Fatalf("members %v", i)
+	}
+
+	if err := a1.SetWeight(ml1, 5, time.Second); err != nil {
+		t.Fatal(err)
+	}
+	eventually(t, func() bool { return same() && c2.MemberInfos()[0].Weight == 5 }, infos)
+
+	ml2.Leave(time.Second)
+	ml2.Shutdown()
+	eventually(t, func() bool {
+		m := c1.Members()
+		sort.Strings(m)
+		return slices.Equal(m, []string{"node-1"})
+	}, infos)
+}
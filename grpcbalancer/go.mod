@@ -0,0 +1,15 @@
+module github.com/kingreatwill/weighted-consistent-hashing/grpcbalancer
+
+go 1.23.0
+
+require (
+	github.com/kingreatwill/weighted-consistent-hashing v0.0.0
+	google.golang.org/grpc v1.70.0
+)
+
+require (
+	golang.org/x/sys v0.29.0 // indirect
+	google.golang.org/protobuf v1.35.2 // indirect
+)
+
+replace github.com/kingreatwill/weighted-consistent-hashing => ../
@@ -0,0 +1,104 @@
+// Package grpcbalancer provides a gRPC balancer that routes each RPC to a
+// sub-connection chosen by a weighted consistent hash of a per-RPC key, for
+// sticky routing.
+//
+// Register the balancer with a service config such as
+//
+//	{"loadBalancingConfig": [{"weighted_consistent_hash": {}}]}
+//
+// and attach the routing key to outgoing RPCs with WithHashKey. Resolvers can
+// give an address a weight with SetWeight; addresses default to weight 1.
+package grpcbalancer
+
+import (
+	"context"
+	"math/rand/v2"
+
+	consistent "github.com/kingreatwill/weighted-consistent-hashing"
+	"google.golang.org/grpc/balancer"
+	"google.golang.org/grpc/balancer/base"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/resolver"
+)
+
+// Name is the name the balancer is registered under.
+const Name = "weighted_consistent_hash"
+
+// MetadataKey is the outgoing metadata key carrying the per-RPC hash key.
+const MetadataKey = "x-consistent-hash-key"
+
+// NumberOfReplicas is the number of virtual nodes per unit of weight.
+const NumberOfReplicas = 200
+
+func init() {
+	balancer.Register(NewBuilder())
+}
+
+// NewBuilder returns a balancer.Builder for the consistent hash balancer.
+func NewBuilder() balancer.Builder {
+	return base.NewBalancerBuilder(Name, pickerBuilder{}, base.Config{HealthCheck: true})
+}
+
+// WithHashKey returns a context whose outgoing RPCs are routed by key.
+func WithHashKey(ctx context.Context, key string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, MetadataKey, key)
+}
+
+type weightKey struct{}
+
+// SetWeight returns a copy of addr carrying the weight the balancer uses
+// for it. The weight must be positive.
+func SetWeight(addr resolver.Address, weight float64) resolver.Address {
+	addr.BalancerAttributes = addr.BalancerAttributes.WithValue(weightKey{}, weight)
+	return addr
+}
+
+func weightOf(addr resolver.Address) float64 {
+	if w, ok := addr.BalancerAttributes.Value(weightKey{}).(float64); ok {
+		return w
+	}
+	return 1
+}
+
+type pickerBuilder struct{}
+
+func (pickerBuilder) Build(info base.PickerBuildInfo) balancer.Picker {
+	if len(info.ReadySCs) == 0 {
+		return base.NewErrPicker(balancer.ErrNoSubConnAvailable)
+	}
+	p := &picker{
+		ring:     consistent.New(NumberOfReplicas),
+		subConns: make(map[string]balancer.SubConn, len(info.ReadySCs)),
+	}
+	members := make(map[string]float64, len(info.ReadySCs))
+	for sc, sci := range info.ReadySCs {
+		members[sci.Address.Addr] = weightOf(sci.Address)
+		p.subConns[sci.Address.Addr] = sc
+		p.all = append(p.all, sc)
+	}
+	if err := p.ring.Set(members); err != nil {
+		return base.NewErrPicker(err)
+	}
+	return p
+}
+
+type picker struct {
+	ring     *consistent.Consistent
+	subConns map[string]balancer.SubConn
+	all      []balancer.SubConn
+}
+
+// Pick routes RPCs carrying a hash key to the key's owner, and the others to
+// a random ready sub-connection.
+func (p *picker) Pick(info balancer.PickInfo) (balancer.PickResult, error) {
+	md, _ := metadata.FromOutgoingContext(info.Ctx)
+	keys := md.Get(MetadataKey)
+	if len(keys) == 0 {
+		return balancer.PickResult{SubConn: p.all[rand.IntN(len(p.all))]}, nil
+	}
+	addr, err := p.ring.Get(keys[0])
+	if err != nil {
+		return balancer.PickResult{}, balancer.ErrNoSubConnAvailable
+	}
+	return balancer.PickResult{SubConn: p.subConns[addr]}, nil
+}
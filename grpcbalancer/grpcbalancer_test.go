@@ -0,0 +1,36 @@
+package grpcbalancer
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"google.golang.org/grpc/balancer"
+	"google.golang.org/grpc/balancer/base"
+	"google.golang.org/grpc/resolver"
+)
+
+type fakeSubConn struct {
+	balancer.SubConn
+	addr string
+}
+
+func TestPickIsSticky(t *testing.T) {
+	ready := make(map[balancer.SubConn]base.SubConnInfo)
+	for i := 0; i < 3; i++ {
+		addr := resolver.Address{Addr: fmt.Sprintf("10.0.0.%d:50051", i)}
+		ready[&fakeSubConn{addr: addr.Addr}] = base.SubConnInfo{Address: SetWeight(addr, float64(i+1))}
+	}
+	p := pickerBuilder{}.Build(base.PickerBuildInfo{ReadySCs: ready})
+	ctx := WithHashKey(context.Background(), "user-42")
+	first, err := p.Pick(balancer.PickInfo{Ctx: ctx})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		res, _ := p.Pick(balancer.PickInfo{Ctx: ctx})
+		if res.SubConn != first.SubConn {
+			t.Fatalf("pick %d went to %s, want %s", i, res.SubConn.(*fakeSubConn).addr, first.SubConn.(*fakeSubConn).addr)
+		}
+	}
+}
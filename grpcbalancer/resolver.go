@@ -0,0 +1,70 @@
+package grpcbalancer
+
+import (
+	"context"
+	"time"
+
+	"github.com/kingreatwill/weighted-consistent-hashing/discovery"
+	"google.golang.org/grpc/resolver"
+)
+
+// NewResolverBuilder returns a gRPC resolver.Builder for scheme that feeds
+// the instances reported by the Watcher newWatcher returns for a target to
+// the ClientConn, carrying their weights for the balancer.
+//
+//	resolver.Register(grpcbalancer.NewResolverBuilder("consul", func(t resolver.Target) (discovery.Watcher, error) {
+//		return &discovery.Consul{Service: t.Endpoint()}, nil
+//	}))
+func NewResolverBuilder(scheme string, newWatcher func(target resolver.Target) (discovery.Watcher, error)) resolver.Builder {
+	return &resolverBuilder{scheme: scheme, newWatcher: newWatcher}
+}
+
+type resolverBuilder struct {
+	scheme     string
+	newWatcher func(resolver.Target) (discovery.Watcher, error)
+}
+
+func (b *resolverBuilder) Scheme() string { return b.scheme }
+
+func (b *resolverBuilder) Build(target resolver.Target, cc resolver.ClientConn, _ resolver.BuildOptions) (resolver.Resolver, error) {
+	w, err := b.newWatcher(target)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		for {
+			instances, err := w.Next(ctx)
+			if ctx.Err() != nil {
+				return
+			}
+			if err != nil {
+				cc.ReportError(err)
+				select {
+				case <-time.After(time.Second):
+					continue
+				case <-ctx.Done():
+					return
+				}
+			}
+			addrs := make([]resolver.Address, 0, len(instances))
+			for _, in := range instances {
+				wgt := in.Weight
+				if wgt <= 0 {
+					wgt = 1
+				}
+				addrs = append(addrs, SetWeight(resolver.Address{Addr: in.Addr}, wgt))
+			}
+			cc.UpdateState(resolver.State{Addresses: addrs})
+		}
+	}()
+	return &watchResolver{cancel: cancel}, nil
+}
+
+type watchResolver struct {
+	cancel context.CancelFunc
+}
+
+func (r *watchResolver) ResolveNow(resolver.ResolveNowOptions) {}
+
+func (r *watchResolver) Close() { r.cancel() }
@@ -0,0 +1,209 @@
+// Package health probes the members of a ring and marks them healthy or
+// unhealthy with SetHealthy. Unhealthy members keep their place on the ring,
+// so a failing node does not cause keys to be rehashed; lookups just skip it
+// until it recovers.
+//
+//	m := health.NewMonitor(ring, health.TCP(time.Second), health.Options{})
+//	go m.Run(ctx)
+package health
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	consistent "github.com/kingreatwill/weighted-consistent-hashing"
+)
+
+// Checker probes a member and returns an error if it is unhealthy.
+type Checker interface {
+	Check(ctx context.Context, member string) error
+}
+
+// CheckerFunc adapts an ordinary function to a Checker.
+type CheckerFunc func(ctx context.Context, member string) error
+
+// Check calls f(ctx, member).
+func (f CheckerFunc) Check(ctx context.Context, member string) error {
+	return f(ctx, member)
+}
+
+// TCP returns a Checker that dials the member, taken as a host:port address.
+func TCP(timeout time.Duration) Checker {
+	return CheckerFunc(func(ctx context.Context, member string) error {
+		d := net.Dialer{Timeout: timeout}
+		conn, err := d.DialContext(ctx, "tcp", member)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	})
+}
+
+// HTTP returns a Checker that sends a GET for path to the member, taken as a
+// host:port address, and expects a 2xx response. A nil client uses
+// http.DefaultClient.
+func HTTP(client *http.Client, path string) Checker {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return CheckerFunc(func(ctx context.Context, member string) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+member+path, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return fmt.Errorf("health: %s returned %s", member, resp.Status)
+		}
+		return nil
+	})
+}
+
+// Options configures a Monitor. The zero value uses the defaults.
+type Options struct {
+	// Interval is the time between rounds of checks. Default 5s.
+	Interval time.Duration
+	// Timeout bounds each check. Default 2s.
+	Timeout time.Duration
+	// FailureThreshold is the number of consecutive failed checks after
+	// which a member is marked unhealthy. Default 1.
+	FailureThreshold int
+	// SuccessThreshold is the number of consecutive successful checks after
+	// which an unhealthy member is marked healthy again. Default 1.
+	SuccessThreshold int
+}
+
+// Target is the set of members a Monitor checks and marks. Both a
+// consistent.Consistent and a p2c.Balancer are Targets.
+type Target interface {
+	Members() []string
+	SetHealthy(member string, healthy bool) error
+}
+
+var _ Target = (*consistent.Consistent)(nil)
+
+// Monitor periodically checks every member of a Target.
+type Monitor struct {
+	ring    Target
+	checker Checker
+	opts    Options
+
+	mu       sync.Mutex
+	checkers map[string]Checker
+	streaks  map[string]int // > 0 successes in a row, < 0 failures in a row
+}
+
+// NewMonitor returns a Monitor checking the members of ring with checker.
+func NewMonitor(ring Target, checker Checker, opts Options) *Monitor {
+	if opts.Interval <= 0 {
+		opts.Interval = 5 * time.Second
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 2 * time.Second
+	}
+	opts.FailureThreshold = max(opts.FailureThreshold, 1)
+	opts.SuccessThreshold = max(opts.SuccessThreshold, 1)
+	return &Monitor{
+		ring:     ring,
+		checker:  checker,
+		opts:     opts,
+		checkers: make(map[string]Checker),
+		streaks:  make(map[string]int),
+	}
+}
+
+// SetChecker checks member with checker instead of the Monitor's default. A
+// nil checker restores the default.
+func (m *Monitor) SetChecker(member string, checker Checker) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if checker == nil {
+		delete(m.checkers, member)
+		return
+	}
+	m.checkers[member] = checker
+}
+
+// Run checks all members every Interval until ctx is done.
+func (m *Monitor) Run(ctx context.Context) {
+	t := time.NewTicker(m.opts.Interval)
+	defer t.Stop()
+	for {
+		m.CheckNow(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// CheckNow checks all members concurrently once and updates their health.
+func (m *Monitor) CheckNow(ctx context.Context) {
+	members := m.ring.Members()
+	var wg sync.WaitGroup
+	for _, member := range members {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			m.check(ctx, member)
+		}()
+	}
+	wg.Wait()
+	m.forget(members)
+}
+
+func (m *Monitor) check(ctx context.Context, member string) {
+	m.mu.Lock()
+	checker, ok := m.checkers[member]
+	m.mu.Unlock()
+	if !ok {
+		checker = m.checker
+	}
+	ctx, cancel := context.WithTimeout(ctx, m.opts.Timeout)
+	err := checker.Check(ctx, member)
+	cancel()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	streak := m.streaks[member]
+	switch {
+	case err == nil && streak >= 0:
+		streak++
+	case err == nil:
+		streak = 1
+	case streak <= 0:
+		streak--
+	default:
+		streak = -1
+	}
+	m.streaks[member] = streak
+	if streak >= m.opts.SuccessThreshold {
+		m.ring.SetHealthy(member, true)
+	} else if -streak >= m.opts.FailureThreshold {
+		m.ring.SetHealthy(member, false)
+	}
+}
+
+// forget drops the state of members no longer in the ring.
+func (m *Monitor) forget(members []string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	present := make(map[string]bool, len(members))
+	for _, member := range members {
+		present[member] = true
+	}
+	for member := range m.streaks {
+		if !present[member] {
+			delete(m.streaks, member)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	consistent "github.com/kingreatwill/weighted-consistent-hashing"
+)
+
+func TestMonitor(t *testing.T) {
+	ring := consistent.New(20)
+	ring.Set(map[string]float64{"a": 1, "b": 1})
+	down := map[string]bool{"b": true}
+	m := NewMonitor(ring, CheckerFunc(func(_ context.Context, member string) error {
+		if down[member] {
+			return errors.New("down")
+		}
+		return nil
+	}), Options{FailureThreshold: 2})
+
+	m.CheckNow(context.Background())
+	if !ring.Healthy("b") {
+		t.Fatal("b marked unhealthy before reaching FailureThreshold")
+	}
+	m.CheckNow(context.Background())
+	if ring.Healthy("b") || !ring.Healthy("a") {
+		t.Fatalf("healthy a=%v b=%v, want a only", ring.Healthy("a"), ring.Healthy("b"))
+	}
+
+	down["b"] = false
+	m.CheckNow(context.Background())
+	if !ring.Healthy("b") {
+		t.Fatal("b not restored after a successful check")
+	}
+}
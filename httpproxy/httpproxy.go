@@ -0,0 +1,122 @@
+// Package httpproxy routes reverse-proxied HTTP requests to upstreams chosen
+// by a weighted consistent hash of a request attribute.
+//
+// Ring members are upstream "host:port" addresses:
+//
+//	ring := consistent.New(200)
+//	ring.Set(map[string]float64{"10.0.0.1:8080": 1, "10.0.0.2:8080": 2})
+//	proxy := httpproxy.NewReverseProxy(ring, httpproxy.HeaderKey("X-User-ID"), 3)
+package httpproxy
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httputil"
+
+	consistent "github.com/kingreatwill/weighted-consistent-hashing"
+)
+
+// KeyFunc extracts the hash key from an incoming request.
+type KeyFunc func(r *http.Request) string
+
+// PathKey hashes the request path.
+func PathKey(r *http.Request) string {
+	return r.URL.Path
+}
+
+// HeaderKey hashes the value of the named request header.
+func HeaderKey(name string) KeyFunc {
+	return func(r *http.Request) string {
+		return r.Header.Get(name)
+	}
+}
+
+// CookieKey hashes the value of the named cookie, or "" when it is absent.
+func CookieKey(name string) KeyFunc {
+	return func(r *http.Request) string {
+		c, err := r.Cookie(name)
+		if err != nil {
+			return ""
+		}
+		return c.Value
+	}
+}
+
+// ClientIPKey hashes the IP address of the client connection.
+func ClientIPKey(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+type hashKeyContextKey struct{}
+
+// NewRewrite returns an httputil.ReverseProxy Rewrite function sending each
+// request to the owner of its key over plain HTTP. Requests are failed with
+// 502 by the proxy when the ring is empty.
+func NewRewrite(ring *consistent.Consistent, key KeyFunc) func(*httputil.ProxyRequest) {
+	return func(pr *httputil.ProxyRequest) {
+		k := key(pr.In)
+		pr.Out = pr.Out.WithContext(context.WithValue(pr.Out.Context(), hashKeyContextKey{}, k))
+		pr.SetXForwarded()
+		pr.Out.URL.Scheme = "http"
+		pr.Out.URL.Host, _ = ring.Get(k)
+		pr.Out.Host = ""
+	}
+}
+
+// FailoverTransport retries requests that fail or get a 5xx response on the
+// next distinct owners of their key, up to Attempts upstreams in total. Only
+// requests without a body or with GetBody set are retried.
+type FailoverTransport struct {
+	Ring     *consistent.Consistent
+	Attempts int
+	// Base performs the requests; nil means http.DefaultTransport.
+	Base http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *FailoverTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	k, ok := req.Context().Value(hashKeyContextKey{}).(string)
+	if !ok || t.Attempts <= 1 || (req.Body != nil && req.Body != http.NoBody && req.GetBody == nil) {
+		return base.RoundTrip(req)
+	}
+	upstreams, err := t.Ring.GetN(k, t.Attempts)
+	if len(upstreams) == 0 {
+		return base.RoundTrip(req)
+	}
+	var resp *http.Response
+	for i, upstream := range upstreams {
+		out := req.Clone(req.Context())
+		out.URL.Host = upstream
+		if i > 0 && req.GetBody != nil {
+			if out.Body, err = req.GetBody(); err != nil {
+				return nil, err
+			}
+		}
+		resp, err = base.RoundTrip(out)
+		if err == nil && resp.StatusCode < 500 {
+			return resp, nil
+		}
+		if i < len(upstreams)-1 && err == nil {
+			resp.Body.Close()
+		}
+	}
+	return resp, err
+}
+
+// NewReverseProxy returns a reverse proxy routing by key that fails over to
+// up to attempts distinct upstreams per request.
+func NewReverseProxy(ring *consistent.Consistent, key KeyFunc, attempts int) *httputil.ReverseProxy {
+	return &httputil.ReverseProxy{
+		Rewrite:   NewRewrite(ring, key),
+		Transport: &FailoverTransport{Ring: ring, Attempts: attempts},
+	}
+}
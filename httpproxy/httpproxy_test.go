@@ -0,0 +1,39 @@
+package httpproxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	consistent "github.com/kingreatwill/weighted-consistent-hashing"
+)
+
+func TestFailoverOn5xx(t *testing.T) {
+	bad := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer bad.Close()
+	good := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer good.Close()
+	badHost, _ := url.Parse(bad.URL)
+	goodHost, _ := url.Parse(good.URL)
+
+	ring := consistent.New(20)
+	ring.Set(map[string]float64{badHost.Host: 1, goodHost.Host: 1})
+	front := httptest.NewServer(NewReverseProxy(ring, PathKey, 2))
+	defer front.Close()
+
+	for _, path := range []string{"/a", "/b", "/c", "/d", "/e"} {
+		resp, err := http.Get(front.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("GET %s = %d, want 200", path, resp.StatusCode)
+		}
+	}
+}
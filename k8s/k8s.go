@@ -0,0 +1,163 @@
+// Package k8s watches the EndpointSlices of a Kubernetes Service and reports
+// its ready endpoints as a discovery.Watcher, so a ring can follow the pods
+// of a Service with discovery.Sync, or a gRPC client with
+// grpcbalancer.NewResolverBuilder.
+//
+//	factory := informers.NewSharedInformerFactoryWithOptions(clientset, 0, informers.WithNamespace("default"))
+//	w := k8s.NewWatcher(factory, "default", "cache", k8s.Options{Port: "memcache"})
+//	factory.Start(ctx.Done())
+//	go discovery.Sync(ctx, ring, w, discovery.Options{})
+package k8s
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net"
+	"sort"
+	"strconv"
+
+	"github.com/kingreatwill/weighted-consistent-hashing/discovery"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	discoverylisters "k8s.io/client-go/listers/discovery/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// Options configures a Watcher. The zero value uses the defaults.
+type Options struct {
+	// Port, if set, is the name of the Service port to use: members are
+	// named pod-ip:port. By default members are named by pod IP alone.
+	Port string
+	// WeightAnnotation, if set, is the Pod annotation holding the weight of
+	// the pod's endpoints. Pods without a valid positive weight in it get
+	// weight 1.
+	WeightAnnotation string
+}
+
+// Watcher reports the ready endpoints of a Service. It implements
+// discovery.Watcher.
+type Watcher struct {
+	namespace string
+	selector  labels.Selector
+	opts      Options
+	slices    discoverylisters.EndpointSliceLister
+	pods      corelisters.PodLister
+	synced    []cache.InformerSynced
+	changed   chan struct{}
+	started   bool
+}
+
+// NewWatcher returns a Watcher of the Service service in namespace, using
+// informers of factory. The factory must be started, after NewWatcher, for
+// Next to return.
+func NewWatcher(factory informers.SharedInformerFactory, namespace, service string, opts Options) *Watcher {
+	w := &Watcher{
+		namespace: namespace,
+		selector:  labels.SelectorFromSet(labels.Set{discoveryv1.LabelServiceName: service}),
+		opts:      opts,
+		changed:   make(chan struct{}, 1),
+	}
+	notify := cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(any) { w.notify() },
+		UpdateFunc: func(any, any) { w.notify() },
+		DeleteFunc: func(any) { w.notify() },
+	}
+	slices := factory.Discovery().V1().EndpointSlices()
+	slices.Informer().AddEventHandler(notify)
+	w.slices = slices.Lister()
+	w.synced = append(w.synced, slices.Informer().HasSynced)
+	if opts.WeightAnnotation != "" {
+		pods := factory.Core().V1().Pods()
+		pods.Informer().AddEventHandler(notify)
+		w.pods = pods.Lister()
+		w.synced = append(w.synced, pods.Informer().HasSynced)
+	}
+	return w
+}
+
+func (w *Watcher) notify() {
+	select {
+	case w.changed <- struct{}{}:
+	default:
+	}
+}
+
+// Next implements discovery.Watcher. The first call waits for the informer
+// caches to sync; later calls wait for a change to an EndpointSlice, or to
+// a Pod when WeightAnnotation is set.
+func (w *Watcher) Next(ctx context.Context) ([]discovery.Instance, error) {
+	if !w.started {
+		if !cache.WaitForCacheSync(ctx.Done(), w.synced...) {
+			return nil, errors.Join(errors.New("k8s: informer caches did not sync"), ctx.Err())
+		}
+		w.started = true
+	} else {
+		select {
+		case <-w.changed:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	return w.Instances()
+}
+
+// Instances returns the current ready endpoints of the Service, sorted by
+// name.
+func (w *Watcher) Instances() ([]discovery.Instance, error) {
+	list, err := w.slices.EndpointSlices(w.namespace).List(w.selector)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	var instances []discovery.Instance
+	for _, s := range list {
+		port := ""
+		if w.opts.Port != "" {
+			for _, p := range s.Ports {
+				if p.Name != nil && *p.Name == w.opts.Port && p.Port != nil {
+					port = strconv.Itoa(int(*p.Port))
+				}
+			}
+			if port == "" {
+				continue
+			}
+		}
+		for _, ep := range s.Endpoints {
+			if ep.Conditions.Ready != nil && !*ep.Conditions.Ready {
+				continue
+			}
+			wgt := w.weight(ep)
+			for _, addr := range ep.Addresses {
+				if port != "" {
+					addr = net.JoinHostPort(addr, port)
+				}
+				if !seen[addr] {
+					seen[addr] = true
+					instances = append(instances, discovery.Instance{Addr: addr, Weight: wgt})
+				}
+			}
+		}
+	}
+	sort.Slice(instances, func(i, j int) bool { return instances[i].Addr < instances[j].Addr })
+	return instances, nil
+}
+
+// weight returns the weight of ep from its pod's annotation, or 1 if the
+// annotation is missing or not a positive finite number.
+func (w *Watcher) weight(ep discoveryv1.Endpoint) float64 {
+	if w.pods == nil || ep.TargetRef == nil || ep.TargetRef.Kind != "Pod" {
+		return 1
+	}
+	pod, err := w.pods.Pods(w.namespace).Get(ep.TargetRef.Name)
+	if err != nil {
+		return 1
+	}
+	wgt, err := strconv.ParseFloat(pod.Annotations[w.opts.WeightAnnotation], 64)
+	if err != nil || !(wgt > 0) || math.IsInf(wgt, 1) {
+		return 1
+	}
+	return wgt
+}
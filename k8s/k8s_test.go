@@ -0,0 +1,109 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	consistent "github.com/kingreatwill/weighted-consistent-hashing"
+	"github.com/kingreatwill/weighted-consistent-hashing/discovery"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func ptr[T any](v T) *T { return &v }
+
+func TestWatcher(t *testing.T) {
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Name: "cache-0", Namespace: "default",
+		Annotations: map[string]string{"example.com/weight": "3"},
+	}}
+	slice := &discoveryv1.EndpointSlice{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "cache-abc", Namespace: "default",
+			Labels: map[string]string{discoveryv1.LabelServiceName: "cache"},
+		},
+		Ports: []discoveryv1.EndpointPort{{Name: ptr("memcache"), Port: ptr(int32(11211))}},
+		Endpoints: []discoveryv1.Endpoint{
+			{Addresses: []string{"10.0.0.1"}, TargetRef: &corev1.ObjectReference{Kind: "Pod", Name: "cache-0"}},
+			{Addresses: []string{"10.0.0.2"}},
+			{Addresses: []string{"10.0.0.3"}, Conditions: discoveryv1.EndpointConditions{Ready: ptr(false)}},
+		},
+	}
+	client := fake.NewClientset(pod, slice)
+	factory := informers.NewSharedInformerFactory(client, 0)
+	w := NewWatcher(factory, "default", "cache", Options{Port: "memcache", WeightAnnotation: "example.com/weight"})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	factory.Start(ctx.Done())
+
+	in, err := w.Next(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []discovery.Instance{{Addr: "10.0.0.1:11211", Weight: 3}, {Addr: "10.0.0.2:11211", Weight: 1}}
+	if !slices.Equal(in, want) {
+		t.Fatalf("instances %v, want %v", in, want)
+	}
+
+	slice.Endpoints = slice.Endpoints[1:]
+	if _, err := client.DiscoveryV1().EndpointSlices("default").Update(ctx, slice, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	want = want[1:]
+	for !slices.Equal(in, want) {
+		if in, err = w.Next(ctx); err != nil {
+			t.Fatalf("instances %v, want %v: %v", in, want, err)
+		}
+	}
+}
+
+func TestWatcherInvalidWeight(t *testing.T) {
+	var objs []runtime.Object
+	slice := &discoveryv1.EndpointSlice{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "cache-abc", Namespace: "default",
+			Labels: map[string]string{discoveryv1.LabelServiceName: "cache"},
+		},
+	}
+	for i, v := range []string{"inf", "+Inf", "1e400", "-2", "NaN", "x"} {
+		name := fmt.Sprintf("cache-%d", i)
+		objs = append(objs, &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Name: name, Namespace: "default",
+			Annotations: map[string]string{"example.com/weight": v},
+		}})
+		slice.Endpoints = append(slice.Endpoints, discoveryv1.Endpoint{
+			Addresses: []string{fmt.Sprintf("10.0.0.%d", i)},
+			TargetRef: &corev1.ObjectReference{Kind: "Pod", Name: name},
+		})
+	}
+	client := fake.NewClientset(append(objs, slice)...)
+	factory := informers.NewSharedInformerFactory(client, 0)
+	w := NewWatcher(factory, "default", "cache", Options{WeightAnnotation: "example.com/weight"})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	factory.Start(ctx.Done())
+
+	in, err := w.Next(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, inst := range in {
+		if inst.Weight != 1 {
+			t.Fatalf("instance %s has weight %v, want the default 1", inst.Addr, inst.Weight)
+		}
+	}
+	members := map[string]float64{}
+	for _, inst := range in {
+		members[inst.Addr] = inst.Weight
+	}
+	if err := consistent.New(20).Set(members); err != nil {
+		t.Fatalf("ring rejected the instances: %v", err)
+	}
+}
@@ -0,0 +1,16 @@
+module github.com/kingreatwill/weighted-consistent-hashing/kafkapartitioner
+
+go 1.23.0
+
+require (
+	github.com/kingreatwill/weighted-consistent-hashing v0.0.0
+	github.com/twmb/franz-go v1.17.0
+)
+
+require (
+	github.com/klauspost/compress v1.17.9 // indirect
+	github.com/pierrec/lz4/v4 v4.1.21 // indirect
+	github.com/twmb/franz-go/pkg/kmsg v1.8.0 // indirect
+)
+
+replace github.com/kingreatwill/weighted-consistent-hashing => ../
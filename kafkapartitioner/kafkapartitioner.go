@@ -0,0 +1,107 @@
+// Package kafkapartitioner provides a franz-go partitioner that assigns
+// record keys to partitions with weighted consistent hashing, so partitions
+// led by larger brokers can take a proportionally larger share of keys while
+// keys keep their partition when the partition count grows.
+//
+//	cl, err := kgo.NewClient(
+//		kgo.SeedBrokers(seeds...),
+//		kgo.RecordPartitioner(kafkapartitioner.New(weights)),
+//	)
+package kafkapartitioner
+
+import (
+	"math/rand/v2"
+	"strconv"
+	"sync"
+
+	consistent "github.com/kingreatwill/weighted-consistent-hashing"
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+// NumberOfReplicas is the number of virtual nodes per unit of weight.
+const NumberOfReplicas = 100
+
+// WeightFunc returns the relative weight of a partition of topic. Partitions
+// with a weight of 0 or less receive no keyed records.
+type WeightFunc func(topic string, partition int32) float64
+
+var _ kgo.Partitioner = (*Partitioner)(nil)
+
+// Partitioner is a kgo.Partitioner backed by one weighted ring per topic.
+type Partitioner struct {
+	weights WeightFunc
+
+	mu    sync.Mutex
+	rings map[string]*topicRing
+}
+
+type topicRing struct {
+	partitions int
+	ring       *consistent.Consistent
+}
+
+// New returns a Partitioner using weights to size each partition's share of
+// the keyspace. A nil weights gives every partition weight 1.
+func New(weights WeightFunc) *Partitioner {
+	if weights == nil {
+		weights = func(string, int32) float64 { return 1 }
+	}
+	return &Partitioner{weights: weights, rings: make(map[string]*topicRing)}
+}
+
+// Reset drops the cached rings so that the next records pick up changed
+// weights.
+func (p *Partitioner) Reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	clear(p.rings)
+}
+
+// ForTopic implements kgo.Partitioner.
+func (p *Partitioner) ForTopic(topic string) kgo.TopicPartitioner {
+	return &topicPartitioner{p: p, topic: topic}
+}
+
+// ring returns the ring of topic for n partitions, building it if needed.
+func (p *Partitioner) ring(topic string, n int) *consistent.Consistent {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if tr, ok := p.rings[topic]; ok && tr.partitions == n {
+		return tr.ring
+	}
+	members := make(map[string]float64, n)
+	for i := 0; i < n; i++ {
+		if w := p.weights(topic, int32(i)); w > 0 {
+			members[strconv.Itoa(i)] = w
+		}
+	}
+	r := consistent.New(NumberOfReplicas)
+	r.Set(members)
+	p.rings[topic] = &topicRing{partitions: n, ring: r}
+	return r
+}
+
+type topicPartitioner struct {
+	p     *Partitioner
+	topic string
+}
+
+// RequiresConsistency reports that keyed records must always be sent to the
+// partition they hash to.
+func (*topicPartitioner) RequiresConsistency(r *kgo.Record) bool {
+	return r.Key != nil
+}
+
+// Partition returns the partition owning the record key, or a random
+// partition for records without a key.
+func (t *topicPartitioner) Partition(r *kgo.Record, n int) int {
+	if r.Key == nil {
+		return rand.IntN(n)
+	}
+	owner, err := t.p.ring(t.topic, n).Get(string(r.Key))
+	if err != nil {
+		return rand.IntN(n)
+	}
+	partition, _ := strconv.Atoi(owner)
+	return partition
+}
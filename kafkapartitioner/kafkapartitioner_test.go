@@ -0,0 +1,82 @@
+package kafkapartitioner
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+
+	consistent "github.com/kingreatwill/weighted-consistent-hashing"
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+func partition(p *Partitioner, key string, n int) int {
+	return p.ForTopic("orders").Partition(&kgo.Record{Key: []byte(key)}, n)
+}
+
+func TestPartition(t *testing.T) {
+	weights := func(_ string, partition int32) float64 {
+		if partition == 2 {
+			return 0
+		}
+		return float64(partition + 1)
+	}
+	p := New(weights)
+	ref := consistent.New(NumberOfReplicas)
+	ref.Set(map[string]float64{"0": 1, "1": 2, "3": 4})
+	other := New(weights)
+	counts := make([]int, 4)
+	for i := 0; i < 2000; i++ {
+		key := fmt.Sprint("key", i)
+		got := partition(p, key, 4)
+		want, _ := ref.Get(key)
+		if strconv.Itoa(got) != want {
+			t.Fatalf("key %s on partition %d, want %s", key, got, want)
+		}
+		if o := partition(other, key, 4); o != got {
+			t.Fatalf("key %s on partition %d and %d by two partitioners", key, got, o)
+		}
+		counts[got]++
+	}
+	if counts[2] != 0 {
+		t.Fatalf("partition of weight 0 got %d keys", counts[2])
+	}
+	if counts[3] < counts[1] || counts[1] < counts[0] {
+		t.Fatalf("keys per partition %v do not follow the weights", counts)
+	}
+}
+
+func TestPartitionCountChange(t *testing.T) {
+	p := New(nil)
+	before := map[string]int{}
+	for i := 0; i < 2000; i++ {
+		key := fmt.Sprint("key", i)
+		before[key] = partition(p, key, 4)
+	}
+	moved := 0
+	for key, old := range before {
+		got := partition(p, key, 5)
+		if got != old {
+			moved++
+			if got != 4 {
+				t.Fatalf("key %s moved from %d to the old partition %d", key, old, got)
+			}
+		}
+	}
+	// A fifth partition of equal weight takes about a fifth of the keys.
+	if moved < 250 || moved > 550 {
+		t.Fatalf("%d of 2000 keys moved to the new partition", moved)
+	}
+}
+
+func TestPartitionUnkeyed(t *testing.T) {
+	tp := New(nil).ForTopic("orders")
+	r := &kgo.Record{Value: []byte("v")}
+	if tp.RequiresConsistency(r) {
+		t.Fatal("unkeyed records require consistency")
+	}
+	for i := 0; i < 100; i++ {
+		if got := tp.Partition(r, 3); got < 0 || got >= 3 {
+			t.Fatalf("unkeyed record on partition %d of 3", got)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+// Package multiring routes keys across several datacenters, keeping one
+// weighted ring of members per datacenter and a weighted ring of the
+// datacenters themselves.
+//
+//	m := multiring.New("us-east", multiring.LocalFirst)
+//	m.AddDatacenter("us-east", 2)
+//	m.AddDatacenter("eu-west", 1)
+//	m.Ring("us-east").Add("10.0.0.1:80", 1)
+//	dc, member, err := m.Locate(key)
+package multiring
+
+import (
+	"errors"
+	"sync"
+
+	consistent "github.com/kingreatwill/weighted-consistent-hashing"
+)
+
+// NumberOfReplicas is the number of virtual nodes per unit of weight used by
+// the rings created by a Manager.
+const NumberOfReplicas = 20
+
+// ErrDatacenterExists is returned when adding a datacenter that is already present.
+var ErrDatacenterExists = errors.New("datacenter already exists")
+
+// ErrDatacenterNotFound is returned when a datacenter is not present.
+var ErrDatacenterNotFound = errors.New("datacenter not found")
+
+// Policy selects how Locate chooses a datacenter for a key.
+type Policy int
+
+const (
+	// Global spreads keys over all datacenters by their weight.
+	Global Policy = iota
+	// LocalFirst keeps keys in the local datacenter while it has members.
+	LocalFirst
+)
+
+// Manager holds one ring per datacenter and a top-level ring of datacenters.
+// The member rings returned by Ring may be changed concurrently with Locate.
+type Manager struct {
+	local  string
+	policy Policy
+
+	mu    sync.RWMutex
+	dcs   *consistent.Consistent
+	rings map[string]*consistent.Consistent
+}
+
+// New returns a Manager routing with policy. local names the datacenter the
+// caller runs in; it is only used by LocalFirst.
+func New(local string, policy Policy) *Manager {
+	return &Manager{
+		local:  local,
+		policy: policy,
+		dcs:    consistent.New(NumberOfReplicas),
+		rings:  make(map[string]*consistent.Consistent),
+	}
+}
+
+// AddDatacenter adds an empty datacenter with weight.
+func (m *Manager) AddDatacenter(dc string, weight float64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.rings[dc]; ok {
+		return ErrDatacenterExists
+	}
+	if err := m.dcs.Add(dc, weight); err != nil {
+		return err
+	}
+	m.rings[dc] = consistent.New(NumberOfReplicas)
+	return nil
+}
+
+// RemoveDatacenter removes dc and its members.
+func (m *Manager) RemoveDatacenter(dc string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.rings[dc]; !ok {
+		return false
+	}
+	m.dcs.Remove(dc)
+	delete(m.rings, dc)
+	return true
+}
+
+// SetDatacenterWeight changes the share of keys dc receives under Global, and
+// when LocalFirst fails over.
+func (m *Manager) SetDatacenterWeight(dc string, weight float64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.rings[dc]; !ok {
+		return ErrDatacenterNotFound
+	}
+	return m.dcs.UpdateWeight(dc, weight)
+}
+
+// Datacenters returns the names of the datacenters.
+func (m *Manager) Datacenters() []string {
+	return m.dcs.Members()
+}
+
+// Ring returns the member ring of dc, or nil if dc is not present.
+func (m *Manager) Ring(dc string) *consistent.Consistent {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.rings[dc]
+}
+
+// Locate returns the datacenter and member that key maps to. Datacenters
+// without members are skipped, so keys fail over to the next datacenter on
+// the top-level ring.
+func (m *Manager) Locate(key string) (dc, member string, err error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.policy == LocalFirst {
+		if r, ok := m.rings[m.local]; ok {
+			if member, err := r.Get(key); err == nil {
+				return m.local, member, nil
+			}
+		}
+	}
+	dcs, err := m.dcs.GetAll(key)
+	if err != nil {
+		return "", "", err
+	}
+	for _, dc := range dcs {
+		if member, err := m.rings[dc].Get(key); err == nil {
+			return dc, member, nil
+		}
+	}
+	return "", "", consistent.ErrEmptyCircle
+}
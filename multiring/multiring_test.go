@@ -0,0 +1,51 @@
+package multiring
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestLocate(t *testing.T) {
+	m := New("a", LocalFirst)
+	m.AddDatacenter("a", 1)
+	m.AddDatacenter("b", 1)
+	m.Ring("a").Add("a1", 1)
+	m.Ring("b").Add("b1", 1)
+
+	for i := 0; i < 20; i++ {
+		dc, member, err := m.Locate(fmt.Sprint(i))
+		if err != nil || dc != "a" || member != "a1" {
+			t.Fatalf("Locate = %q, %q, %v, want local", dc, member, err)
+		}
+	}
+
+	m.Ring("a").Remove("a1")
+	dc, member, err := m.Locate("key")
+	if err != nil || dc != "b" || member != "b1" {
+		t.Fatalf("Locate = %q, %q, %v, want failover to b", dc, member, err)
+	}
+
+	m.Ring("b").Remove("b1")
+	if _, _, err := m.Locate("key"); err == nil {
+		t.Fatal("Locate with no members should fail")
+	}
+}
+
+func TestLocateGlobal(t *testing.T) {
+	m := New("", Global)
+	for _, dc := range []string{"a", "b", "c"} {
+		m.AddDatacenter(dc, 1)
+		m.Ring(dc).Add(dc+"1", 1)
+	}
+	seen := map[string]bool{}
+	for i := 0; i < 300; i++ {
+		dc, _, err := m.Locate(fmt.Sprint(i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		seen[dc] = true
+	}
+	if len(seen) != 3 {
+		t.Fatalf("keys reached %d datacenters, want 3", len(seen))
+	}
+}
@@ -0,0 +1,81 @@
+// Package oteltrace records OpenTelemetry spans for the lookups and
+// membership changes of a Consistent ring. It lives in its own package so
+// that the core package stays free of the OpenTelemetry dependency.
+//
+//	oteltrace.New(ring, otel.GetTracerProvider(), oteltrace.Options{})
+//	server, err := ring.GetContext(ctx, key)
+package oteltrace
+
+import (
+	"context"
+	"time"
+
+	consistent "github.com/kingreatwill/weighted-consistent-hashing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ScopeName is the instrumentation scope of the tracer.
+const ScopeName = "github.com/kingreatwill/weighted-consistent-hashing/oteltrace"
+
+// Options configures an Observer. The zero value uses the defaults.
+type Options struct {
+	// RecordKeys adds the looked up key to lookup spans. Keys may be
+	// sensitive or high-cardinality, so they are left out by default.
+	RecordKeys bool
+}
+
+// Observer is a consistent.Observer recording a span per membership change
+// and per lookup made with a context. Lookups with a context, such as
+// GetContext and GetNContext, are recorded as children of the span in that
+// context; lookups without one are not traced, since their spans would be
+// orphan roots.
+type Observer struct {
+	tracer trace.Tracer
+	opts   Options
+}
+
+// New returns an Observer using tracers of tp and registers it as an
+// observer of ring.
+func New(ring *consistent.Consistent, tp trace.TracerProvider, opts Options) *Observer {
+	o := &Observer{tracer: tp.Tracer(ScopeName), opts: opts}
+	ring.AddObserver(o)
+	return o
+}
+
+// ObserveLookup implements consistent.Observer.
+func (o *Observer) ObserveLookup(l consistent.Lookup) {
+	if l.Context == nil {
+		return
+	}
+	attrs := []attribute.KeyValue{
+		attribute.String("consistent.op", l.Op),
+		attribute.StringSlice("consistent.members", l.Members),
+		attribute.Int("consistent.ring_size", l.RingSize),
+		attribute.Bool("consistent.fallback", l.Fallback),
+	}
+	if o.opts.RecordKeys {
+		attrs = append(attrs, attribute.String("consistent.key", l.Key))
+	}
+	end := time.Now()
+	_, span := o.tracer.Start(l.Context, "consistent."+l.Op,
+		trace.WithTimestamp(end.Add(-l.Duration)), trace.WithAttributes(attrs...))
+	if l.Err != nil {
+		span.RecordError(l.Err)
+		span.SetStatus(codes.Error, l.Err.Error())
+	}
+	span.End(trace.WithTimestamp(end))
+}
+
+// ObserveChange implements consistent.Observer.
+func (o *Observer) ObserveChange(ch consistent.Change) {
+	_, span := o.tracer.Start(context.Background(), "consistent.change",
+		trace.WithAttributes(
+			attribute.String("consistent.change.kind", ch.Kind.String()),
+			attribute.String("consistent.member", ch.Member),
+			attribute.Float64("consistent.old_weight", ch.OldWeight),
+			attribute.Float64("consistent.new_weight", ch.NewWeight),
+		))
+	span.End()
+}
@@ -0,0 +1,82 @@
+package oteltrace
+
+import (
+	"context"
+	"testing"
+
+	consistent "github.com/kingreatwill/weighted-consistent-hashing"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func attrs(s sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+	m := map[attribute.Key]attribute.Value{}
+	for _, kv := range s.Attributes() {
+		m[kv.Key] = kv.Value
+	}
+	return m
+}
+
+func TestObserver(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec))
+	ring := consistent.New(20)
+	New(ring, tp, Options{RecordKeys: true})
+	ring.Add("a", 1)
+	ring.Get("key")
+
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "request")
+	if m, err := ring.GetContext(ctx, "key"); err != nil || m != "a" {
+		t.Fatalf("Get = %s, %v", m, err)
+	}
+	if _, err := ring.GetNContext(ctx, "key", 2); err == nil {
+		t.Fatal("GetN found 2 members on a ring of 1")
+	}
+	parent.End()
+
+	spans := rec.Ended()
+	if len(spans) != 4 {
+		t.Fatalf("%d spans recorded, want the change, 2 lookups and their parent", len(spans))
+	}
+	if spans[0].Name() != "consistent.change" || attrs(spans[0])["consistent.change.kind"].AsString() != "add" {
+		t.Fatalf("change span %s %v", spans[0].Name(), attrs(spans[0]))
+	}
+	a := attrs(spans[1])
+	if spans[1].Name() != "consistent.Get" || a["consistent.key"].AsString() != "key" ||
+		a["consistent.members"].AsStringSlice()[0] != "a" || a["consistent.ring_size"].AsInt64() != 20 ||
+		a["consistent.fallback"].AsBool() {
+		t.Fatalf("lookup span %s %v", spans[1].Name(), a)
+	}
+	for _, s := range spans[1:3] {
+		if s.Parent().SpanID() != parent.SpanContext().SpanID() {
+			t.Fatalf("lookup span %s is not a child of the request span", s.Name())
+		}
+	}
+	if spans[2].Status().Code.String() != "Error" {
+		t.Fatalf("failed lookup span status %v", spans[2].Status())
+	}
+}
+
+func TestObserverFallback(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec))
+	ring := consistent.New(20)
+	ring.Set(map[string]float64{"a": 1, "b": 1})
+	New(ring, tp, Options{})
+	primary, _ := ring.Get("key")
+	ring.SetHealthy(primary, false)
+
+	ring.GetContext(context.Background(), "key")
+	spans := rec.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("%d spans recorded, want 1", len(spans))
+	}
+	a := attrs(spans[0])
+	if !a["consistent.fallback"].AsBool() || a["consistent.ring_size"].AsInt64() != 40 {
+		t.Fatalf("fallback lookup span %v", a)
+	}
+	if _, ok := a["consistent.key"]; ok {
+		t.Fatal("key recorded without RecordKeys")
+	}
+}
@@ -0,0 +1,163 @@
+// Package p2c balances requests over members without key affinity, using
+// weighted power of two choices: two members are drawn at random in
+// proportion to their weight and the one with the lower expected cost, from
+// its latency average and requests in flight, is picked.
+//
+// It shares consistent.Member with the ring and can be driven by a
+// health.Monitor, so one set of members can serve both affinity routing and
+// plain load balancing.
+//
+//	b := p2c.New(members)
+//	go health.NewMonitor(b, health.TCP(time.Second), health.Options{}).Run(ctx)
+//	m, err := b.Pick()
+//	start := time.Now()
+//	... send the request to m ...
+//	b.Done(m, time.Since(start))
+package p2c
+
+import (
+	"errors"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	consistent "github.com/kingreatwill/weighted-consistent-hashing"
+)
+
+// ErrNoMember is returned by Pick when no healthy member has a positive weight.
+var ErrNoMember = errors.New("p2c: no available member")
+
+// Decay is the weight of the previous average when Done folds in a new
+// latency sample.
+const Decay = 0.9
+
+type member struct {
+	weight   float64
+	healthy  bool
+	latency  float64 // moving average in nanoseconds
+	inflight int64
+}
+
+// Balancer picks members with weighted power of two choices.
+type Balancer struct {
+	mu      sync.Mutex
+	members map[string]*member
+	names   []string
+}
+
+// New returns a Balancer over members.
+func New(members []consistent.Member) *Balancer {
+	b := &Balancer{members: make(map[string]*member)}
+	b.Update(members)
+	return b
+}
+
+// Update replaces the members, keeping the statistics and health of those
+// that stay.
+func (b *Balancer) Update(members []consistent.Member) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	next := make(map[string]*member, len(members))
+	b.names = b.names[:0]
+	for _, m := range members {
+		if _, ok := next[m.Name]; ok {
+			continue
+		}
+		s, ok := b.members[m.Name]
+		if !ok {
+			s = &member{healthy: true}
+		}
+		s.weight = m.Weight
+		next[m.Name] = s
+		b.names = append(b.names, m.Name)
+	}
+	b.members = next
+}
+
+// Members returns the names of the members.
+func (b *Balancer) Members() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]string(nil), b.names...)
+}
+
+// SetHealthy marks member as healthy or unhealthy; Pick only returns
+// healthy members.
+func (b *Balancer) SetHealthy(name string, healthy bool) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	m, ok := b.members[name]
+	if !ok {
+		return consistent.ErrMemberNotFound
+	}
+	m.healthy = healthy
+	return nil
+}
+
+// Pick returns a member for a request and counts the request as in flight
+// until Done is called for it.
+func (b *Balancer) Pick() (string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var total float64
+	for _, m := range b.members {
+		if m.healthy && m.weight > 0 {
+			total += m.weight
+		}
+	}
+	if total == 0 {
+		return "", ErrNoMember
+	}
+	first := b.draw(total)
+	second := b.draw(total)
+	for i := 0; second == first && i < 3; i++ {
+		second = b.draw(total)
+	}
+	if b.cost(second) < b.cost(first) {
+		first = second
+	}
+	b.members[first].inflight++
+	return first, nil
+}
+
+// Done reports that a request picked for name completed after latency.
+func (b *Balancer) Done(name string, latency time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	m, ok := b.members[name]
+	if !ok {
+		return
+	}
+	if m.inflight > 0 {
+		m.inflight--
+	}
+	if m.latency == 0 {
+		m.latency = float64(latency)
+	} else {
+		m.latency = Decay*m.latency + (1-Decay)*float64(latency)
+	}
+}
+
+// draw returns a healthy member at random in proportion to its weight.
+func (b *Balancer) draw(total float64) string {
+	x := rand.Float64() * total
+	var last string
+	for _, name := range b.names {
+		m := b.members[name]
+		if !m.healthy || m.weight <= 0 {
+			continue
+		}
+		last = name
+		if x < m.weight {
+			return name
+		}
+		x -= m.weight
+	}
+	return last
+}
+
+// cost estimates the cost of sending one more request to name.
+func (b *Balancer) cost(name string) float64 {
+	m := b.members[name]
+	return (m.latency + 1) * float64(m.inflight+1) / m.weight
+}
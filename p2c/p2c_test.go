@@ -0,0 +1,32 @@
+package p2c
+
+import (
+	"testing"
+	"time"
+
+	consistent "github.com/kingreatwill/weighted-consistent-hashing"
+)
+
+func TestPick(t *testing.T) {
+	b := New([]consistent.Member{{Name: "fast", Weight: 1}, {Name: "slow", Weight: 1}})
+	b.Done("fast", time.Millisecond)
+	b.Done("slow", 100*time.Millisecond)
+	counts := map[string]int{}
+	for i := 0; i < 1000; i++ {
+		m, err := b.Pick()
+		if err != nil {
+			t.Fatal(err)
+		}
+		counts[m]++
+		b.Done(m, map[string]time.Duration{"fast": time.Millisecond, "slow": 100 * time.Millisecond}[m])
+	}
+	if counts["fast"] < 2*counts["slow"] {
+		t.Fatalf("picks = %v, want mostly fast", counts)
+	}
+
+	b.SetHealthy("fast", false)
+	b.SetHealthy("slow", false)
+	if _, err := b.Pick(); err != ErrNoMember {
+		t.Fatalf("Pick with no healthy member: %v", err)
+	}
+}
@@ -0,0 +1,85 @@
+// Package promcollector exposes Prometheus metrics for a Consistent ring.
+// It lives in its own package so that the core package stays free of the
+// Prometheus dependency.
+//
+//	ring := consistent.New(200)
+//	prometheus.MustRegister(promcollector.New(ring, "cache_ring", nil))
+package promcollector
+
+import (
+	consistent "github.com/kingreatwill/weighted-consistent-hashing"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Collector is a prometheus.Collector reporting the size and membership of a
+// ring, the latency of its lookups and its membership churn.
+type Collector struct {
+	ring         *consistent.Consistent
+	ringSize     *prometheus.Desc
+	members      *prometheus.Desc
+	virtualNodes *prometheus.Desc
+	lookups      *prometheus.HistogramVec
+	churn        *prometheus.CounterVec
+}
+
+// New returns a Collector for ring and registers it as an observer of the
+// ring. Metric names are prefixed with namespace.
+func New(ring *consistent.Consistent, namespace string, constLabels prometheus.Labels) *Collector {
+	c := &Collector{
+		ring: ring,
+		ringSize: prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "virtual_nodes"),
+			"Number of virtual nodes on the ring.", nil, constLabels),
+		members: prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "members"),
+			"Number of members on the ring.", nil, constLabels),
+		virtualNodes: prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "member_virtual_nodes"),
+			"Number of virtual nodes owned by a member.", []string{"member"}, constLabels),
+		lookups: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace:   namespace,
+			Name:        "lookup_duration_seconds",
+			Help:        "Latency of ring lookups.",
+			ConstLabels: constLabels,
+			Buckets:     prometheus.ExponentialBuckets(1e-7, 4, 10),
+		}, []string{"op"}),
+		churn: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   namespace,
+			Name:        "membership_changes_total",
+			Help:        "Number of membership changes applied to the ring.",
+			ConstLabels: constLabels,
+		}, []string{"kind"}),
+	}
+	ring.AddObserver(c)
+	return c
+}
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.ringSize
+	ch <- c.members
+	ch <- c.virtualNodes
+	c.lookups.Describe(ch)
+	c.churn.Describe(ch)
+}
+
+// Collect implements prometheus.Collector.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	nodes := c.ring.VirtualNodes()
+	total := 0
+	for member, n := range nodes {
+		total += n
+		ch <- prometheus.MustNewConstMetric(c.virtualNodes, prometheus.GaugeValue, float64(n), member)
+	}
+	ch <- prometheus.MustNewConstMetric(c.ringSize, prometheus.GaugeValue, float64(total))
+	ch <- prometheus.MustNewConstMetric(c.members, prometheus.GaugeValue, float64(len(nodes)))
+	c.lookups.Collect(ch)
+	c.churn.Collect(ch)
+}
+
+// ObserveLookup implements consistent.Observer.
+func (c *Collector) ObserveLookup(l consistent.Lookup) {
+	c.lookups.WithLabelValues(l.Op).Observe(l.Duration.Seconds())
+}
+
+// ObserveChange implements consistent.Observer.
+func (c *Collector) ObserveChange(ch consistent.Change) {
+	c.churn.WithLabelValues(ch.Kind.String()).Inc()
+}
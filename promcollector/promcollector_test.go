@@ -0,0 +1,49 @@
+package promcollector
+
+import (
+	"strings"
+	"testing"
+
+	consistent "github.com/kingreatwill/weighted-consistent-hashing"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestCollector(t *testing.T) {
+	ring := consistent.New(10)
+	c := New(ring, "ring", nil)
+	ring.Set(map[string]float64{"a": 1, "b": 2})
+	ring.Remove("b")
+	ring.Get("key")
+	ring.Get("key")
+	ring.GetN("key", 1)
+
+	expected := `
+# HELP ring_members Number of members on the ring.
+# TYPE ring_members gauge
+ring_members 1
+# HELP ring_member_virtual_nodes Number of virtual nodes owned by a member.
+# TYPE ring_member_virtual_nodes gauge
+ring_member_virtual_nodes{member="a"} 10
+# HELP ring_virtual_nodes Number of virtual nodes on the ring.
+# TYPE ring_virtual_nodes gauge
+ring_virtual_nodes 10
+# HELP ring_membership_changes_total Number of membership changes applied to the ring.
+# TYPE ring_membership_changes_total counter
+ring_membership_changes_total{kind="add"} 2
+ring_membership_changes_total{kind="remove"} 1
+`
+	err := testutil.CollectAndCompare(c, strings.NewReader(expected),
+		"ring_members", "ring_member_virtual_nodes", "ring_virtual_nodes", "ring_membership_changes_total")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := testutil.CollectAndCount(c, "ring_lookup_duration_seconds"); n != 2 {
+		t.Fatalf("%d lookup histograms, want one per op", n)
+	}
+	if got := testutil.ToFloat64(c.churn.WithLabelValues("add")); got != 2 {
+		t.Fatalf("add churn = %v", got)
+	}
+	if problems, err := testutil.CollectAndLint(c); err != nil || len(problems) > 0 {
+		t.Fatalf("lint: %v %v", problems, err)
+	}
+}
@@ -0,0 +1,103 @@
+// Package redisslots maps keys to the 16384 Redis Cluster hash slots and
+// assigns the slots to the weighted members of a ring, for proxies and
+// client-side routers that must agree with Redis Cluster on which keys share
+// a slot. Keys sharing a hash tag, like "{user1000}.following" and
+// "{user1000}.followers", map to the same slot and so to the same member.
+// Each slot is placed on the ring like an integer key, so a membership
+// change only moves the slots of the arcs that change hands.
+package redisslots
+
+import (
+	"strings"
+
+	consistent "github.com/kingreatwill/weighted-consistent-hashing"
+)
+
+// NumSlots is the number of hash slots of a Redis Cluster.
+const NumSlots = 16384
+
+// Slot returns the hash slot of key as computed by Redis Cluster: the
+// CRC16 of the key, or of its hash tag if it has a non-empty one, modulo
+// NumSlots.
+func Slot(key string) int {
+	if i := strings.IndexByte(key, '{'); i >= 0 {
+		if j := strings.IndexByte(key[i+1:], '}'); j > 0 {
+			key = key[i+1 : i+1+j]
+		}
+	}
+	return int(crc16(key) % NumSlots)
+}
+
+// crc16 is CRC-16/XMODEM, the checksum used by Redis Cluster.
+func crc16(s string) uint16 {
+	var crc uint16
+	for i := 0; i < len(s); i++ {
+		crc = crc<<8 ^ crc16Table[byte(crc>>8)^s[i]]
+	}
+	return crc
+}
+
+var crc16Table = func() (t [256]uint16) {
+	for i := range t {
+		crc := uint16(i) << 8
+		for j := 0; j < 8; j++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+		t[i] = crc
+	}
+	return t
+}()
+
+// Router routes keys to the members of a Consistent through their hash
+// slots. It reads the ring on every lookup, so it follows membership and
+// health changes without being told.
+type Router struct {
+	c *consistent.Consistent
+}
+
+// New returns a Router over c.
+func New(c *consistent.Consistent) *Router {
+	return &Router{c: c}
+}
+
+// Get returns the member owning the slot of key.
+func (r *Router) Get(key string) (string, error) {
+	return r.c.GetUint64(uint64(Slot(key)))
+}
+
+// GetSlot returns the member owning slot.
+func (r *Router) GetSlot(slot int) (string, error) {
+	return r.c.GetUint64(uint64(slot))
+}
+
+// SlotRange is an inclusive range of slots owned by a member, as listed by
+// CLUSTER SLOTS.
+type SlotRange struct {
+	Start, End int
+	Member     string
+}
+
+// SlotRanges returns the ranges of slots owned by each member, in slot
+// order, merging adjacent slots with the same owner. All slots are read from
+// the same ring, without counting them as lookups. It returns nil if the ring
+// is empty.
+func (r *Router) SlotRanges() []SlotRange {
+	f := r.c.Freeze()
+	var ranges []SlotRange
+	for slot := 0; slot < NumSlots; slot++ {
+		m, err := f.GetUint64(uint64(slot))
+		if err != nil {
+			return nil
+		}
+		if n := len(ranges); n > 0 && ranges[n-1].Member == m {
+			ranges[n-1].End = slot
+			continue
+		}
+		ranges = append(ranges, SlotRange{slot, slot, m})
+	}
+	return ranges
+}
@@ -0,0 +1,55 @@
+package redisslots
+
+import (
+	"testing"
+
+	consistent "github.com/kingreatwill/weighted-consistent-hashing"
+)
+
+func TestSlot(t *testing.T) {
+	if c := crc16("123456789"); c != 0x31c3 {
+		t.Fatalf("crc16 = %#x, want 0x31c3", c)
+	}
+	for key, want := range map[string]int{
+		"foo":                  12182,
+		"bar":                  5061,
+		"{user1000}.following": Slot("user1000"),
+		"{user1000}.followers": Slot("user1000"),
+		"foo{}{bar}":           Slot("foo{}{bar}"),
+		"foo{{bar}}zap":        Slot("{bar"),
+		"foo{bar}{zap}":        Slot("bar"),
+	} {
+		if got := Slot(key); got != want {
+			t.Errorf("Slot(%q) = %d, want %d", key, got, want)
+		}
+	}
+}
+
+func TestRouter(t *testing.T) {
+	c := consistent.New(50)
+	c.Set(map[string]float64{"a:6379": 1, "b:6379": 1, "c:6379": 2})
+	r := New(c)
+	a, _ := r.Get("{user1000}.following")
+	b, _ := r.Get("{user1000}.followers")
+	if a != b {
+		t.Fatalf("keys with the same hash tag routed to %s and %s", a, b)
+	}
+	owned := map[string]int{}
+	lookups := c.Stats().Lookups
+	ranges := r.SlotRanges()
+	if c.Stats().Lookups != lookups {
+		t.Fatal("SlotRanges counted its reads as lookups")
+	}
+	if ranges[0].Start != 0 || ranges[len(ranges)-1].End != NumSlots-1 {
+		t.Fatalf("ranges cover [%d, %d]", ranges[0].Start, ranges[len(ranges)-1].End)
+	}
+	for _, sr := range ranges {
+		owned[sr.Member] += sr.End - sr.Start + 1
+	}
+	if owned["c:6379"] < NumSlots*4/10 || owned["c:6379"] > NumSlots*6/10 {
+		t.Fatalf("slots per member %v", owned)
+	}
+	if r := New(consistent.New(20)).SlotRanges(); r != nil {
+		t.Fatalf("empty ring ranges %v", r)
+	}
+}
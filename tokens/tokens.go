@@ -0,0 +1,103 @@
+// Package tokens describes the ownership of a ring as Cassandra-style token
+// ranges: contiguous ranges of key hashes, each with a primary owner and the
+// replicas that follow it on the ring. Storage systems built on the ring can
+// use them to drive streaming and repair.
+package tokens
+
+import (
+	"slices"
+	"sort"
+
+	consistent "github.com/kingreatwill/weighted-consistent-hashing"
+)
+
+// Range is a contiguous, inclusive range of key hashes and its owners.
+type Range struct {
+	consistent.HashRange
+	Primary string
+	// Replicas are the next distinct members after Primary.
+	Replicas []string
+}
+
+// Ring computes token ranges of a Consistent.
+type Ring struct {
+	c  *consistent.Consistent
+	rf int
+}
+
+// New returns a Ring over c in which every range is held by
+// replicationFactor distinct members, the primary included.
+func New(c *consistent.Consistent, replicationFactor int) *Ring {
+	return &Ring{c: c, rf: max(replicationFactor, 1)}
+}
+
+// TokenRanges returns the ranges covering the whole hash space in ascending
+// order. Adjacent ranges with the same owners are merged. The ranges reflect
+// the ring when TokenRanges is called; health, drains and pins are ignored,
+// as they do not change ownership.
+func (t *Ring) TokenRanges() []Range {
+	nodes := t.c.DumpRing()
+	n := len(nodes)
+	if n == 0 {
+		return nil
+	}
+	inclusive := t.c.KetamaMode
+	owners := func(i int) (string, []string) {
+		res := []string{nodes[i].Member}
+		for k := 1; k < n && len(res) < t.rf; k++ {
+			if m := nodes[(i+k)%n].Member; !slices.Contains(res, m) {
+				res = append(res, m)
+			}
+		}
+		return res[0], res[1:]
+	}
+	// A key maps to the first node above it, or at or above it when the ring
+	// is inclusive, so node i serves [lo(i), hi(i)].
+	lo := func(i int) uint32 {
+		if inclusive {
+			return nodes[i-1].Hash + 1
+		}
+		return nodes[i-1].Hash
+	}
+	hi := func(i int) uint32 {
+		if inclusive {
+			return nodes[i].Hash
+		}
+		return nodes[i].Hash - 1
+	}
+	var res []Range
+	add := func(start, end uint32, i int) {
+		primary, replicas := owners(i)
+		if k := len(res); k > 0 && res[k-1].Primary == primary && slices.Equal(res[k-1].Replicas, replicas) {
+			res[k-1].End = end
+			return
+		}
+		res = append(res, Range{consistent.HashRange{Start: start, End: end}, primary, replicas})
+	}
+	if inclusive || nodes[0].Hash > 0 {
+		add(0, hi(0), 0)
+	}
+	for i := 1; i < n; i++ {
+		add(lo(i), hi(i), i)
+	}
+	if last := nodes[n-1].Hash; !inclusive || last < 1<<32-1 {
+		start := last
+		if inclusive {
+			start++
+		}
+		add(start, 1<<32-1, 0)
+	}
+	return res
+}
+
+// RangeForKey returns the range key falls in. It computes all ranges, so
+// callers looking up many keys should search the result of TokenRanges.
+func (t *Ring) RangeForKey(key string) (Range, error) {
+	ranges := t.TokenRanges()
+	if len(ranges) == 0 {
+		return Range{}, consistent.ErrEmptyCircle
+	}
+	h := t.c.Hash(key)
+	i := sort.Search(len(ranges), func(i int) bool { return ranges[i].End >= h })
+	return ranges[i], nil
+}
@@ -0,0 +1,35 @@
+package tokens
+
+import (
+	"fmt"
+	"testing"
+
+	consistent "github.com/kingreatwill/weighted-consistent-hashing"
+)
+
+func TestTokenRanges(t *testing.T) {
+	c := consistent.New(20)
+	c.Set(map[string]float64{"A": 1, "B": 1, "C": 1, "D": 1})
+	tr := New(c, 3)
+	ranges := tr.TokenRanges()
+	if ranges[0].Start != 0 || ranges[len(ranges)-1].End != 1<<32-1 {
+		t.Fatalf("ranges cover [%d, %d]", ranges[0].Start, ranges[len(ranges)-1].End)
+	}
+	for i := 1; i < len(ranges); i++ {
+		if ranges[i].Start != ranges[i-1].End+1 {
+			t.Fatalf("gap between %v and %v", ranges[i-1].HashRange, ranges[i].HashRange)
+		}
+	}
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprint(i)
+		r, err := tr.RangeForKey(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, _ := c.GetN(key, 3)
+		got := append([]string{r.Primary}, r.Replicas...)
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Fatalf("owners of %q = %v, want %v", key, got, want)
+		}
+	}
+}
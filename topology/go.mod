@@ -0,0 +1,13 @@
+module github.com/kingreatwill/weighted-consistent-hashing/topology
+
+go 1.23.0
+
+require (
+	github.com/fsnotify/fsnotify v1.8.0
+	github.com/kingreatwill/weighted-consistent-hashing v0.0.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require golang.org/x/sys v0.29.0 // indirect
+
+replace github.com/kingreatwill/weighted-consistent-hashing => ../
@@ -0,0 +1,192 @@
+// Package topology loads ring members, weights, zones and tags from a YAML
+// or JSON file and keeps a ring in sync with it as the file changes.
+//
+//	members:
+//	  - name: 10.0.0.1:11211
+//	    weight: 2
+//	    zone: us-east-1a
+//	    tags: [ssd]
+//	  - name: 10.0.0.2:11211
+//	    weight: 1
+//
+// JSON files use the same structure. Errors point at the offending line.
+package topology
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	consistent "github.com/kingreatwill/weighted-consistent-hashing"
+	"gopkg.in/yaml.v3"
+)
+
+// Topology is the content of a topology file.
+type Topology struct {
+	Members []consistent.Member
+}
+
+// Error is a validation error at a line of a topology file.
+type Error struct {
+	File string
+	Line int
+	Msg  string
+}
+
+func (e *Error) Error() string {
+	if e.File == "" {
+		return fmt.Sprintf("line %d: %s", e.Line, e.Msg)
+	}
+	return fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Msg)
+}
+
+// Load reads and validates the topology file at path.
+func Load(path string) (*Topology, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	t, err := Parse(data)
+	var e *Error
+	if errors.As(err, &e) {
+		e.File = path
+	}
+	return t, err
+}
+
+// Parse parses and validates a topology in YAML or JSON.
+func Parse(data []byte) (*Topology, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	t := &Topology{}
+	if len(doc.Content) == 0 {
+		return t, nil
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, &Error{Line: root.Line, Msg: "want a mapping with a members list"}
+	}
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key, val := root.Content[i], root.Content[i+1]
+		if key.Value != "members" {
+			return nil, &Error{Line: key.Line, Msg: fmt.Sprintf("unknown field %q", key.Value)}
+		}
+		if val.Kind != yaml.SequenceNode {
+			return nil, &Error{Line: val.Line, Msg: "members must be a list"}
+		}
+		seen := make(map[string]int)
+		for _, n := range val.Content {
+			m, err := parseMember(n)
+			if err != nil {
+				return nil, err
+			}
+			if line, ok := seen[m.Name]; ok {
+				return nil, &Error{Line: n.Line, Msg: fmt.Sprintf("member %q already listed at line %d", m.Name, line)}
+			}
+			seen[m.Name] = n.Line
+			t.Members = append(t.Members, m)
+		}
+	}
+	return t, nil
+}
+
+func parseMember(n *yaml.Node) (consistent.Member, error) {
+	var m consistent.Member
+	if n.Kind != yaml.MappingNode {
+		return m, &Error{Line: n.Line, Msg: "member must be a mapping"}
+	}
+	hasWeight := false
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		key, val := n.Content[i], n.Content[i+1]
+		var err error
+		switch key.Value {
+		case "name":
+			err = val.Decode(&m.Name)
+		case "weight":
+			err = val.Decode(&m.Weight)
+			hasWeight = true
+		case "zone":
+			err = val.Decode(&m.Zone)
+		case "tags":
+			err = val.Decode(&m.Tags)
+		default:
+			return m, &Error{Line: key.Line, Msg: fmt.Sprintf("unknown member field %q", key.Value)}
+		}
+		if err != nil {
+			return m, &Error{Line: val.Line, Msg: fmt.Sprintf("invalid %s %q", key.Value, val.Value)}
+		}
+	}
+	switch {
+	case m.Name == "":
+		return m, &Error{Line: n.Line, Msg: "member without a name"}
+	case !hasWeight:
+		m.Weight = 1
+	case !(m.Weight > 0) || math.IsInf(m.Weight, 0):
+		return m, &Error{Line: n.Line, Msg: fmt.Sprintf("member %q has invalid weight %v", m.Name, m.Weight)}
+	}
+	return m, nil
+}
+
+// Apply makes the members of c those of t, with their zones and tags.
+func (t *Topology) Apply(c *consistent.Consistent) error {
+	return c.SetMembers(t.Members)
+}
+
+// Watch loads the topology file at path into c, then reloads it whenever
+// the file changes until ctx is done, and returns ctx.Err(). It watches the
+// directory of path, so files replaced by a rename, as editors and
+// Kubernetes ConfigMap volumes do, are picked up too. An invalid file is
+// reported to onError, if set, and leaves the ring as it was. Watch returns
+// the error at once if the initial load fails.
+func Watch(ctx context.Context, path string, c *consistent.Consistent, onError func(error)) error {
+	t, err := Load(path)
+	if err != nil {
+		return err
+	}
+	if err := t.Apply(c); err != nil {
+		return err
+	}
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+	if err := w.Add(filepath.Dir(path)); err != nil {
+		return err
+	}
+	report := func(err error) {
+		if err != nil && onError != nil {
+			onError(err)
+		}
+	}
+	// Editors write a file in several steps; reload once they settle.
+	const settle = 50 * time.Millisecond
+	reload := time.NewTimer(time.Hour)
+	reload.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case ev := <-w.Events:
+			// ConfigMap volumes swap a symlinked directory next to the file.
+			if filepath.Clean(ev.Name) == filepath.Clean(path) || filepath.Base(ev.Name) == "..data" {
+				reload.Reset(settle)
+			}
+		case err := <-w.Errors:
+			report(err)
+		case <-reload.C:
+			t, err := Load(path)
+			if err == nil {
+				err = t.Apply(c)
+			}
+			report(err)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package topology
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	consistent "github.com/kingreatwill/weighted-consistent-hashing"
+)
+
+func TestParse(t *testing.T) {
+	yml := `members:
+  - name: a:1
+    weight: 2
+    zone: z1
+    tags: [ssd, big]
+  - name: b:1
+`
+	json := `{"members": [{"name": "a:1", "weight": 2, "zone": "z1", "tags": ["ssd", "big"]}, {"name": "b:1"}]}`
+	for _, data := range []string{yml, json} {
+		top, err := Parse([]byte(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(top.Members) != 2 || top.Members[0].Weight != 2 || top.Members[0].Zone != "z1" ||
+			!slices.Equal(top.Members[0].Tags, []string{"ssd", "big"}) || top.Members[1].Weight != 1 {
+			t.Fatalf("parsed %+v", top.Members)
+		}
+	}
+
+	for data, want := range map[string]string{
+		"members:\n  - name: a\n    weight: -1\n": "line 2: member \"a\" has invalid weight -1",
+		"members:\n  - name: a\n    weight: x\n":  "line 3: invalid weight \"x\"",
+		"members:\n  - name: a\n  - name: a\n":    "line 3: member \"a\" already listed at line 2",
+		"members:\n  - name: a\n    color: red\n": "line 3: unknown member field \"color\"",
+		"servers: []\n":             "line 1: unknown field \"servers\"",
+		"members:\n  - weight: 1\n": "line 2: member without a name",
+		"{\"members\": [\n  {\"name\": \"a\", \"weight\": 0}]}": "line 2: member \"a\" has invalid weight 0",
+	} {
+		_, err := Parse([]byte(data))
+		var e *Error
+		if !errors.As(err, &e) || err.Error() != want {
+			t.Errorf("Parse(%q) = %v, want %s", data, err, want)
+		}
+	}
+}
+
+func TestWatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "topology.yaml")
+	write := func(s string) {
+		tmp := path + ".tmp"
+		if err := os.WriteFile(tmp, []byte(s), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Rename(tmp, path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("members:\n  - name: a\n    zone: z1\n")
+	c := consistent.New(20)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	errs := make(chan error, 10)
+	go Watch(ctx, path, c, func(err error) { errs <- err })
+
+	wait := func(want ...string) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			m := c.Members()
+			sort.Strings(m)
+			if slices.Equal(m, want) {
+				return
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("members %v, want %v", m, want)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	wait("a")
+	if z, _ := c.Zone("a"); z != "z1" {
+		t.Fatalf("zone of a = %q", z)
+	}
+
+	write("members:\n  - name: a\n  - name: b\n    tags: [ssd]\n")
+	wait("a", "b")
+	if tags := c.Tags("b"); !slices.Equal(tags, []string{"ssd"}) {
+		t.Fatalf("tags of b = %v", tags)
+	}
+	if z, _ := c.Zone("a"); z != "" {
+		t.Fatalf("zone of a = %q after it was dropped", z)
+	}
+
+	write("members:\n  - name: c\n    weight: nope\n")
+	select {
+	case err := <-errs:
+		if !strings.Contains(err.Error(), "topology.yaml:3: invalid weight") {
+			t.Fatalf("error %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("invalid file not reported")
+	}
+	wait("a", "b")
+}
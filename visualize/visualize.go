@@ -0,0 +1,107 @@
+// Package visualize renders a Consistent ring as an SVG image or a
+// standalone HTML page, to help debug skewed weight configurations.
+//
+// Each member is drawn as the arcs of the circle it owns, in its own color,
+// and the legend lists its share of the hash space. Keys can be marked on the
+// circle to see where they land.
+package visualize
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"math"
+	"sort"
+
+	consistent "github.com/kingreatwill/weighted-consistent-hashing"
+)
+
+// Options controls the rendering.
+type Options struct {
+	// Size is the width and height of the ring drawing in pixels; 0 means 480.
+	Size int
+	// Keys are marked on the circle at the position they hash to.
+	Keys []string
+}
+
+var palette = []string{
+	"#4e79a7", "#f28e2b", "#e15759", "#76b7b2", "#59a14f",
+	"#edc948", "#b07aa1", "#ff9da7", "#9c755f", "#bab0ac",
+}
+
+// SVG writes an SVG image of ring to w.
+func SVG(w io.Writer, ring *consistent.Consistent, opts Options) error {
+	size := float64(opts.Size)
+	if size <= 0 {
+		size = 480
+	}
+	members := ring.Members()
+	sort.Strings(members)
+	ownership := ring.Stats().Ownership
+	legendHeight := 20 * float64(len(members)+1)
+	cx, cy := size/2, size/2
+	outer, inner := size/2-10, size/2-50
+
+	if _, err := fmt.Fprintf(w, `<svg xmlns="http://www.w3.org/2000/svg" width="%g" height="%g" font-family="sans-serif" font-size="12">`+"\n",
+		size, size+legendHeight); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, `<circle cx="%g" cy="%g" r="%g" fill="none" stroke="#ccc"/>`+"\n", cx, cy, outer)
+	for i, m := range members {
+		color := palette[i%len(palette)]
+		for _, hr := range ring.Ranges(m) {
+			a0 := angle(float64(hr.Start))
+			a1 := angle(float64(hr.End) + 1)
+			if hr.Size() == 1<<32 {
+				// A full circle has no distinct end points; draw two halves.
+				mid := (a0 + a1) / 2
+				sector(w, cx, cy, outer, inner, a0, mid, color, m)
+				a0 = mid
+			}
+			sector(w, cx, cy, outer, inner, a0, a1, color, m)
+		}
+		y := size + 20*float64(i+1)
+		fmt.Fprintf(w, `<rect x="10" y="%g" width="12" height="12" fill="%s"/><text x="28" y="%g">%s %.2f%%</text>`+"\n",
+			y-11, color, y, html.EscapeString(m), 100*ownership[m])
+	}
+	for _, k := range opts.Keys {
+		a := angle(float64(ring.Hash(k)))
+		owner, _ := ring.Get(k)
+		fmt.Fprintf(w, `<line x1="%.2f" y1="%.2f" x2="%.2f" y2="%.2f" stroke="#000" stroke-width="2"><title>%s → %s</title></line>`+"\n",
+			cx+(inner-15)*math.Cos(a), cy+(inner-15)*math.Sin(a), cx+(outer+5)*math.Cos(a), cy+(outer+5)*math.Sin(a),
+			html.EscapeString(k), html.EscapeString(owner))
+	}
+	_, err := io.WriteString(w, "</svg>\n")
+	return err
+}
+
+// sector draws the part of the annulus between radii inner and outer from
+// angle a0 to a1, clockwise.
+func sector(w io.Writer, cx, cy, outer, inner, a0, a1 float64, color, title string) {
+	large := 0
+	if a1-a0 > math.Pi {
+		large = 1
+	}
+	fmt.Fprintf(w, `<path d="M%.2f %.2f A%g %g 0 %d 1 %.2f %.2f L%.2f %.2f A%g %g 0 %d 0 %.2f %.2f Z" fill="%s"><title>%s</title></path>`+"\n",
+		cx+outer*math.Cos(a0), cy+outer*math.Sin(a0), outer, outer, large, cx+outer*math.Cos(a1), cy+outer*math.Sin(a1),
+		cx+inner*math.Cos(a1), cy+inner*math.Sin(a1), inner, inner, large, cx+inner*math.Cos(a0), cy+inner*math.Sin(a0),
+		color, html.EscapeString(title))
+}
+
+// HTML writes a standalone HTML page embedding the SVG image of ring to w.
+func HTML(w io.Writer, ring *consistent.Consistent, opts Options) error {
+	if _, err := io.WriteString(w, "<!DOCTYPE html>\n<html><head><meta charset=\"utf-8\"><title>Consistent hash ring</title></head><body>\n"); err != nil {
+		return err
+	}
+	if err := SVG(w, ring, opts); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "</body></html>\n")
+	return err
+}
+
+// angle maps a position in the 32-bit hash space to an angle in radians,
+// starting at 12 o'clock and going clockwise.
+func angle(h float64) float64 {
+	return h/(1<<32)*2*math.Pi - math.Pi/2
+}
@@ -0,0 +1,100 @@
+package visualize
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	consistent "github.com/kingreatwill/weighted-consistent-hashing"
+)
+
+// svgDoc is the structure of the images SVG writes.
+type svgDoc struct {
+	XMLName xml.Name   `xml:"svg"`
+	Width   string     `xml:"width,attr"`
+	Circles []struct{} `xml:"circle"`
+	Paths   []struct {
+		Fill  string `xml:"fill,attr"`
+		Title string `xml:"title"`
+	} `xml:"path"`
+	Rects []struct {
+		Fill string `xml:"fill,attr"`
+	} `xml:"rect"`
+	Texts []string `xml:"text"`
+	Lines []struct {
+		Title string `xml:"title"`
+	} `xml:"line"`
+}
+
+func render(t *testing.T, ring *consistent.Consistent, opts Options) svgDoc {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := SVG(&buf, ring, opts); err != nil {
+		t.Fatal(err)
+	}
+	var doc svgDoc
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("invalid SVG: %v\n%s", err, buf.String())
+	}
+	return doc
+}
+
+func TestSVG(t *testing.T) {
+	ring := consistent.New(5)
+	ring.Set(map[string]float64{"a": 1, "b<&>": 3})
+	doc := render(t, ring, Options{Size: 200, Keys: []string{"k1", "k2"}})
+	if doc.Width != "200" || len(doc.Circles) != 1 {
+		t.Fatalf("width %q, %d circles", doc.Width, len(doc.Circles))
+	}
+	arcs := map[string]int{}
+	colors := map[string]string{}
+	for _, p := range doc.Paths {
+		arcs[p.Title]++
+		colors[p.Title] = p.Fill
+	}
+	for _, m := range []string{"a", "b<&>"} {
+		if want := len(ring.Ranges(m)); arcs[m] != want {
+			t.Fatalf("%s drawn as %d arcs, owns %d ranges", m, arcs[m], want)
+		}
+	}
+	if colors["a"] == colors["b<&>"] {
+		t.Fatal("members share a color")
+	}
+	if len(doc.Rects) != 2 || len(doc.Texts) != 2 || doc.Rects[0].Fill != colors["a"] {
+		t.Fatalf("legend %v %v", doc.Rects, doc.Texts)
+	}
+	if !strings.HasPrefix(doc.Texts[1], "b<&> ") || !strings.HasSuffix(doc.Texts[1], "%") {
+		t.Fatalf("legend entry %q", doc.Texts[1])
+	}
+	if len(doc.Lines) != 2 {
+		t.Fatalf("%d keys marked, want 2", len(doc.Lines))
+	}
+	owner, _ := ring.Get("k1")
+	if doc.Lines[0].Title != "k1 → "+owner {
+		t.Fatalf("key marker %q", doc.Lines[0].Title)
+	}
+}
+
+func TestSVGSingleMember(t *testing.T) {
+	ring := consistent.New(1)
+	ring.Add("only", 1)
+	doc := render(t, ring, Options{})
+	if doc.Width != "480" || len(doc.Paths) == 0 {
+		t.Fatalf("width %q, %d arcs", doc.Width, len(doc.Paths))
+	}
+	if doc.Texts[0] != "only 100.00%" {
+		t.Fatalf("legend entry %q", doc.Texts[0])
+	}
+}
+
+func TestHTML(t *testing.T) {
+	var buf bytes.Buffer
+	if err := HTML(&buf, consistent.New(20), Options{}); err != nil {
+		t.Fatal(err)
+	}
+	s := buf.String()
+	if !strings.HasPrefix(s, "<!DOCTYPE html>") || !strings.Contains(s, "<svg ") || !strings.HasSuffix(s, "</html>\n") {
+		t.Fatalf("page %q", s)
+	}
+}